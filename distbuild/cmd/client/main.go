@@ -0,0 +1,238 @@
+// Client runs the build on distbuild cluster and prints output of the jobs.
+//
+// The build graph is read from JSON or YAML file. Without -graph, the graph is generated for
+// the Go module in the source directory.
+//
+// Usage:
+//
+//	client [-config client.yaml] [-coordinator http://localhost:9090] [-source-dir .] [-graph graph.yaml]
+//
+// With -query, the client only prints results of the jobs found in the worker caches and lists
+// the jobs that would have to run. With -outputs, outputs of the cached jobs are downloaded into
+// the directory and their paths are printed.
+//
+// With -trace, timings of the jobs are written to the file in the Chrome trace event format.
+//
+// With -tls-ca, the client connects to the coordinator over TLS and authenticates with the certificate
+// from -tls-cert and -tls-key or with the first token from -token-file.
+//
+// With -reproduce, jobs with names matching the regular expression are run once more on a
+// different worker, and the files of the outputs that differ between the runs are printed.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"syscall"
+
+	"gitlab.com/slon/shad-go/distbuild/cmd/internal/cli"
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/client"
+	"gitlab.com/slon/shad-go/distbuild/pkg/graphgen"
+)
+
+type config struct {
+	Coordinator string `json:"coordinator"`
+	SourceDir   string `json:"source_dir"`
+	Graph       string `json:"graph"`
+	Vet         bool   `json:"vet"`
+	Test        bool   `json:"test"`
+	LogLevel    string `json:"log_level"`
+	User        string `json:"user"`
+	Priority    int    `json:"priority"`
+	ShowQueue   bool   `json:"show_queue"`
+	Query       bool   `json:"query"`
+	Outputs     string `json:"outputs"`
+	Reproduce   string `json:"reproduce"`
+	Trace       string `json:"trace"`
+
+	cli.AuthFiles
+}
+
+func main() {
+	cfg := config{
+		Coordinator: "http://localhost:9090",
+		SourceDir:   ".",
+		Vet:         true,
+		Test:        true,
+		LogLevel:    "warn",
+		User:        os.Getenv("USER"),
+	}
+
+	var configPath string
+	flag.StringVar(&configPath, "config", "", "JSON or YAML config file, flags override its values")
+	flag.StringVar(&cfg.Coordinator, "coordinator", cfg.Coordinator, "coordinator endpoint")
+	flag.StringVar(&cfg.SourceDir, "source-dir", cfg.SourceDir, "directory with the source files")
+	flag.StringVar(&cfg.Graph, "graph", cfg.Graph, "JSON or YAML file with the build graph, generated for the Go module in -source-dir by default")
+	flag.BoolVar(&cfg.Vet, "vet", cfg.Vet, "add vet jobs to the generated graph")
+	flag.BoolVar(&cfg.Test, "test", cfg.Test, "add test jobs to the generated graph")
+	flag.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "log level")
+	flag.StringVar(&cfg.User, "user", cfg.User, "user sharing the workers with other users")
+	flag.IntVar(&cfg.Priority, "priority", cfg.Priority, "build priority, jobs of the builds with higher priority run first")
+	flag.BoolVar(&cfg.ShowQueue, "show-queue", cfg.ShowQueue, "print positions of the jobs waiting for a worker")
+	flag.BoolVar(&cfg.Query, "query", cfg.Query, "print cached results without running anything, fail if some jobs are not cached")
+	flag.StringVar(&cfg.Outputs, "outputs", cfg.Outputs, "with -query, download outputs of the cached jobs into this directory")
+	flag.StringVar(&cfg.Trace, "trace", cfg.Trace, "write timings of the jobs to the file in the Chrome trace event format")
+	flag.StringVar(&cfg.Reproduce, "reproduce", cfg.Reproduce, "regexp of job names, that are run twice on different workers to check that their outputs match")
+	cfg.AuthFiles.RegisterFlags(flag.CommandLine)
+
+	if err := cli.ParseFlags(flag.CommandLine, os.Args[1:], &configPath, &cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if err := run(&cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func loadGraph(ctx context.Context, cfg *config) (*build.Graph, error) {
+	if cfg.Graph == "" {
+		return graphgen.Generate(ctx, graphgen.Config{Dir: cfg.SourceDir, Vet: cfg.Vet, Test: cfg.Test})
+	}
+
+	var graph build.Graph
+	if err := cli.LoadFile(cfg.Graph, &graph); err != nil {
+		return nil, err
+	}
+	return &graph, nil
+}
+
+func run(cfg *config) error {
+	l, err := cli.NewLogger(cfg.LogLevel)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Sync() }()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	graph, err := loadGraph(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("load graph: %w", err)
+	}
+
+	lsn := newPrinter(graph, os.Stdout, os.Stderr)
+	lsn.showQueue = cfg.ShowQueue
+
+	authConfig, err := cfg.AuthFiles.Load()
+	if err != nil {
+		return err
+	}
+
+	c := client.NewClientWithConfig(l.Named("client"), cfg.Coordinator, cfg.SourceDir, client.Config{Auth: authConfig})
+	if cfg.Query {
+		return query(ctx, c, graph, lsn, cfg.Outputs)
+	}
+
+	opts := client.BuildOptions{User: cfg.User, Priority: cfg.Priority}
+	if cfg.Reproduce != "" {
+		if opts.Reproduce, err = matchJobs(graph, cfg.Reproduce); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Trace != "" {
+		opts.Trace = client.NewTrace(*graph)
+	}
+
+	err = c.BuildWithOptions(ctx, *graph, lsn, opts)
+
+	// Trace of the failed build shows where it stopped.
+	if opts.Trace != nil {
+		if traceErr := writeTrace(cfg.Trace, opts.Trace); traceErr != nil {
+			fmt.Fprintf(os.Stderr, "write trace: %v\n", traceErr)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case lsn.failed != 0:
+		return fmt.Errorf("%d jobs failed", lsn.failed)
+	case lsn.notReproduced != 0:
+		return fmt.Errorf("%d reproducibility checks failed", lsn.notReproduced)
+	}
+	return nil
+}
+
+func writeTrace(path string, trace *client.Trace) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := trace.Write(f); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// matchJobs returns jobs of the graph with names matching the regexp.
+func matchJobs(graph *build.Graph, expr string) ([]build.ID, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -reproduce: %w", err)
+	}
+
+	var ids []build.ID
+	for _, job := range graph.Jobs {
+		if re.MatchString(job.Name) {
+			ids = append(ids, job.ID)
+		}
+	}
+	return ids, nil
+}
+
+func query(ctx context.Context, c *client.Client, graph *build.Graph, lsn *printer, outputs string) error {
+	var opts client.QueryOptions
+	if outputs != "" {
+		cache, err := artifact.NewCache(outputs)
+		if err != nil {
+			return fmt.Errorf("open outputs: %w", err)
+		}
+		opts.Outputs = cache
+	}
+
+	missing, err := c.QueryWithOptions(ctx, *graph, lsn, opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.Outputs != nil {
+		for _, job := range graph.Jobs {
+			path, unlock, err := opts.Outputs.Get(job.ID)
+			if errors.Is(err, artifact.ErrNotFound) {
+				continue
+			} else if err != nil {
+				return err
+			}
+			unlock()
+
+			fmt.Fprintf(os.Stderr, "out\t%s\t%s\n", lsn.name(job.ID), path)
+		}
+	}
+
+	for _, id := range missing {
+		fmt.Fprintf(os.Stderr, "miss\t%s\n", lsn.name(id))
+	}
+
+	switch {
+	case lsn.failed != 0:
+		return fmt.Errorf("%d jobs failed", lsn.failed)
+	case len(missing) != 0:
+		return fmt.Errorf("%d of %d jobs are not cached", len(missing), len(graph.Jobs))
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/client"
+)
+
+var (
+	_ client.BuildListener  = (*printer)(nil)
+	_ client.QueueListener  = (*printer)(nil)
+	_ client.CachedListener = (*printer)(nil)
+
+	_ client.ReproducedListener    = (*printer)(nil)
+	_ client.UnsatisfiableListener = (*printer)(nil)
+)
+
+// printer passes job output through and reports status of each finished job.
+type printer struct {
+	names          map[build.ID]string
+	stdout, stderr io.Writer
+
+	// showQueue enables reports of the jobs waiting for a worker.
+	showQueue bool
+
+	// unsatisfiable holds the reported jobs, that no registered worker can run.
+	unsatisfiable map[build.ID]struct{}
+
+	failed int
+	// notReproduced counts jobs, which outputs differ between runs or could not be compared.
+	notReproduced int
+}
+
+func newPrinter(graph *build.Graph, stdout, stderr io.Writer) *printer {
+	p := &printer{
+		names:         map[build.ID]string{},
+		stdout:        stdout,
+		stderr:        stderr,
+		unsatisfiable: map[build.ID]struct{}{},
+	}
+
+	for _, job := range graph.Jobs {
+		p.names[job.ID] = job.Name
+	}
+	return p
+}
+
+func (p *printer) name(jobID build.ID) string {
+	if name, ok := p.names[jobID]; ok {
+		return name
+	}
+	return jobID.String()
+}
+
+func (p *printer) OnJobStdout(jobID build.ID, stdout []byte) error {
+	_, err := p.stdout.Write(stdout)
+	return err
+}
+
+func (p *printer) OnJobStderr(jobID build.ID, stderr []byte) error {
+	_, err := p.stderr.Write(stderr)
+	return err
+}
+
+func (p *printer) OnJobFinished(jobID build.ID) error {
+	_, err := fmt.Fprintf(p.stderr, "ok\t%s\n", p.name(jobID))
+	return err
+}
+
+func (p *printer) OnJobCached(jobID build.ID) error {
+	_, err := fmt.Fprintf(p.stderr, "ok\t%s\t(cached)\n", p.name(jobID))
+	return err
+}
+
+func (p *printer) OnJobFailed(jobID build.ID, code int, error string) error {
+	p.failed++
+
+	if error != "" {
+		_, err := fmt.Fprintf(p.stderr, "FAIL\t%s: %s\n", p.name(jobID), error)
+		return err
+	}
+	_, err := fmt.Fprintf(p.stderr, "FAIL\t%s: exit code %d\n", p.name(jobID), code)
+	return err
+}
+
+func (p *printer) OnJobsQueued(positions map[build.ID]int) error {
+	if !p.showQueue || len(positions) == 0 {
+		return nil
+	}
+
+	first := -1
+	for _, pos := range positions {
+		if first == -1 || pos < first {
+			first = pos
+		}
+	}
+
+	_, err := fmt.Fprintf(p.stderr, "queued\t%d jobs, next at position %d\n", len(positions), first)
+	return err
+}
+
+// OnJobsUnsatisfiable warns once about every job, that no registered worker can run.
+func (p *printer) OnJobsUnsatisfiable(ids []build.ID) error {
+	for _, id := range ids {
+		if _, ok := p.unsatisfiable[id]; ok {
+			continue
+		}
+		p.unsatisfiable[id] = struct{}{}
+
+		if _, err := fmt.Fprintf(p.stderr, "WAIT\t%s: no registered worker has the labels or the resources of the job\n", p.name(id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *printer) OnJobReproduced(rep *api.JobReproduced) error {
+	switch {
+	case rep.Error != "":
+		p.notReproduced++
+		_, err := fmt.Fprintf(p.stderr, "FAIL\t%s: reproducibility check: %s\n", p.name(rep.ID), rep.Error)
+		return err
+	case len(rep.DiffFiles) != 0:
+		p.notReproduced++
+		for _, path := range rep.DiffFiles {
+			if _, err := fmt.Fprintf(p.stderr, "NONDET\t%s: %s differs between %s and %s\n", p.name(rep.ID), path, rep.Workers[0], rep.Workers[1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	_, err := fmt.Fprintf(p.stderr, "repro\t%s\n", p.name(rep.ID))
+	return err
+}
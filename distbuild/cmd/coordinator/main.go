@@ -0,0 +1,185 @@
+// Coordinator runs distbuild coordinator.
+//
+// Usage:
+//
+//	coordinator [-config coordinator.yaml] [-listen :9090] [-root dir]
+//
+// With -tls-ca, -tls-cert and -tls-key, the API is served over mutual TLS. Clients without a certificate
+// are accepted with one of the tokens from -token-file.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/cmd/internal/cli"
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/dist"
+	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+)
+
+const shutdownTimeout = 10 * time.Second
+
+type config struct {
+	Listen   string `json:"listen"`
+	Root     string `json:"root"`
+	Durable  bool   `json:"durable"`
+	LogLevel string `json:"log_level"`
+
+	FileCacheMaxBytes int64 `json:"filecache_max_bytes"`
+
+	// UserWeights sets shares of the users. It is set only in the config file.
+	UserWeights map[string]float64 `json:"user_weights"`
+
+	// Workers lists the worker ids allowed to send heartbeats.
+	Workers []string `json:"workers"`
+
+	// SpeculationFactor enables speculative execution of the jobs running longer than expected.
+	SpeculationFactor float64 `json:"speculation_factor"`
+	// SpeculationMinDelay is parsed by time.ParseDuration.
+	SpeculationMinDelay string `json:"speculation_min_delay"`
+
+	// WorkerTimeout is parsed by time.ParseDuration.
+	WorkerTimeout string `json:"worker_timeout"`
+
+	cli.AuthFiles
+}
+
+func main() {
+	cfg := config{
+		Listen:   ":9090",
+		Root:     "distbuild-coordinator",
+		Durable:  true,
+		LogLevel: "info",
+
+		SpeculationMinDelay: "10s",
+		WorkerTimeout:       "30s",
+	}
+
+	var configPath string
+	flag.StringVar(&configPath, "config", "", "JSON or YAML config file, flags override its values")
+	flag.StringVar(&cfg.Listen, "listen", cfg.Listen, "address of the HTTP API")
+	flag.StringVar(&cfg.Root, "root", cfg.Root, "directory of the file cache and the journal")
+	flag.BoolVar(&cfg.Durable, "durable", cfg.Durable, "keep the journal, so builds survive restarts")
+	flag.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "log level")
+	flag.Int64Var(&cfg.FileCacheMaxBytes, "filecache-max-bytes", cfg.FileCacheMaxBytes, "size limit of the file cache, 0 means unlimited")
+	flag.Func("workers", "comma separated ids of the workers allowed to connect, any worker by default", func(value string) error {
+		cfg.Workers = strings.Split(value, ",")
+		return nil
+	})
+	flag.Float64Var(&cfg.SpeculationFactor, "speculation-factor", cfg.SpeculationFactor, "copy jobs running longer than this many times their usual duration to a free worker, 0 disables copies")
+	flag.StringVar(&cfg.SpeculationMinDelay, "speculation-min-delay", cfg.SpeculationMinDelay, "least time the job runs before it is copied")
+	flag.StringVar(&cfg.WorkerTimeout, "worker-timeout", cfg.WorkerTimeout, "unregister workers sending no heartbeats for this long and requeue their jobs")
+	cfg.AuthFiles.RegisterFlags(flag.CommandLine)
+
+	if err := cli.ParseFlags(flag.CommandLine, os.Args[1:], &configPath, &cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if err := run(&cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(cfg *config) error {
+	l, err := cli.NewLogger(cfg.LogLevel)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Sync() }()
+
+	fileCache, err := filecache.NewWithConfig(
+		filepath.Join(cfg.Root, "filecache"),
+		artifact.Config{MaxBytes: cfg.FileCacheMaxBytes, Logger: l.Named("filecache")})
+	if err != nil {
+		return fmt.Errorf("open file cache: %w", err)
+	}
+
+	authConfig, err := cfg.AuthFiles.Load()
+	if err != nil {
+		return err
+	}
+	if err := authConfig.CheckServer(); err != nil {
+		return err
+	}
+
+	speculationMinDelay, err := time.ParseDuration(cfg.SpeculationMinDelay)
+	if err != nil {
+		return fmt.Errorf("speculation_min_delay: %w", err)
+	}
+	workerTimeout, err := time.ParseDuration(cfg.WorkerTimeout)
+	if err != nil {
+		return fmt.Errorf("worker_timeout: %w", err)
+	}
+
+	var coordinatorConfig dist.Config
+	coordinatorConfig.Scheduler.Weights = cfg.UserWeights
+	coordinatorConfig.Scheduler.SpeculationFactor = cfg.SpeculationFactor
+	coordinatorConfig.Scheduler.SpeculationMinDelay = speculationMinDelay
+	coordinatorConfig.WorkerTimeout = workerTimeout
+	coordinatorConfig.Auth = authConfig
+	for _, id := range cfg.Workers {
+		coordinatorConfig.Workers = append(coordinatorConfig.Workers, api.WorkerID(id))
+	}
+	if cfg.Durable {
+		coordinatorConfig.JournalPath = filepath.Join(cfg.Root, "journal")
+	}
+
+	c, err := dist.OpenCoordinator(l.Named("coordinator"), fileCache, coordinatorConfig)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: cfg.Listen, Handler: c, TLSConfig: authConfig.ServerTLS()}
+	serveErr := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			serveErr <- srv.ListenAndServeTLS("", "")
+		} else {
+			serveErr <- srv.ListenAndServe()
+		}
+	}()
+
+	l.Info("coordinator started", zap.String("listen", cfg.Listen), zap.String("root", cfg.Root))
+
+	select {
+	case err = <-serveErr:
+		c.Stop()
+		return err
+	case <-ctx.Done():
+	}
+
+	l.Info("stopping coordinator")
+
+	// Stopping coordinator first closes status streams, otherwise Shutdown waits for the builds.
+	c.Stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
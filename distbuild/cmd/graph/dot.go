@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// writeDOT renders the graph in the Graphviz format. Edges go from the dependency to the dependent job.
+//
+// Jobs and edges of the critical path are drawn bold, jobs with problems are drawn red.
+// Dependencies missing from the graph are drawn as dashed nodes.
+func writeDOT(w io.Writer, r *report) error {
+	onPath := map[build.ID]bool{}
+	// pathDep maps job of the critical path to the previous job of the path.
+	pathDep := map[build.ID]build.ID{}
+	for i, job := range r.CriticalPath {
+		onPath[job.ID] = true
+		if i > 0 {
+			pathDep[job.ID] = r.CriticalPath[i-1].ID
+		}
+	}
+
+	broken := map[build.ID]bool{}
+	for _, err := range r.Errors {
+		broken[err.Job] = true
+	}
+
+	known := map[build.ID]bool{}
+	for _, job := range r.graph.Jobs {
+		known[job.ID] = true
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph distbuild {\n")
+	b.WriteString("  rankdir=BT;\n")
+	b.WriteString("  node [shape=box];\n")
+
+	for _, job := range r.graph.Jobs {
+		var attrs []string
+		attrs = append(attrs, "label="+strconv.Quote(job.Name))
+		if onPath[job.ID] {
+			attrs = append(attrs, "style=bold")
+		}
+		if broken[job.ID] {
+			attrs = append(attrs, "color=red")
+		}
+		fmt.Fprintf(&b, "  %q [%s];\n", job.ID.String(), strings.Join(attrs, ", "))
+	}
+
+	missing := map[build.ID]bool{}
+	for _, job := range r.graph.Jobs {
+		for _, dep := range job.Deps {
+			if !known[dep] && !missing[dep] {
+				missing[dep] = true
+				fmt.Fprintf(&b, "  %q [label=\"missing\", style=dashed, color=red];\n", dep.String())
+			}
+
+			edge := ""
+			if prev, ok := pathDep[job.ID]; ok && prev == dep {
+				edge = " [style=bold]"
+			}
+			fmt.Fprintf(&b, "  %q -> %q%s;\n", dep.String(), job.ID.String(), edge)
+		}
+	}
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,126 @@
+// Graph validates the build graph and prints its structure.
+//
+// The graph is read from JSON or YAML file. Without the argument, the graph is generated for
+// the Go module in -dir.
+//
+// Usage:
+//
+//	graph [-dir dir] [-format text|dot|json] [-o output] [graph.yaml]
+//
+// Every problem of the graph is printed to stderr with the name of the job, and the exit code is 1.
+// The text format lists the width of every level of the graph and the critical path, the longest
+// chain of dependent jobs. The dot format renders the graph for Graphviz with the critical path
+// highlighted. The json format contains the problems, the levels and the critical path.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"gitlab.com/slon/shad-go/distbuild/cmd/internal/cli"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/graphgen"
+)
+
+var (
+	dir    = flag.String("dir", ".", "root directory of the module, used when the graph file is not given")
+	vet    = flag.Bool("vet", true, "add vet jobs to the generated graph")
+	test   = flag.Bool("test", true, "add test jobs to the generated graph")
+	format = flag.String("format", "text", "output format: text, dot or json")
+	output = flag.String("o", "", "output file, stdout by default")
+)
+
+// errInvalid is returned, when the graph has problems. The problems are already printed.
+var errInvalid = errors.New("graph is invalid")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: graph [flags] [graph.yaml]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0)); err != nil {
+		if !errors.Is(err, errInvalid) {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
+	}
+}
+
+func loadGraph(path string) (*build.Graph, error) {
+	if path == "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		return graphgen.Generate(ctx, graphgen.Config{Dir: *dir, Vet: *vet, Test: *test})
+	}
+
+	var graph build.Graph
+	if err := cli.LoadFile(path, &graph); err != nil {
+		return nil, err
+	}
+	return &graph, nil
+}
+
+func run(path string) error {
+	var write func(w io.Writer, r *report) error
+	switch *format {
+	case "text":
+		write = writeText
+	case "dot":
+		write = writeDOT
+	case "json":
+		write = writeJSON
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	graph, err := loadGraph(path)
+	if err != nil {
+		return fmt.Errorf("load graph: %w", err)
+	}
+
+	r := newReport(graph)
+	for _, err := range r.Errors {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			return err
+		}
+	}
+
+	if err := write(out, r); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	if len(r.Errors) != 0 {
+		return errInvalid
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, r *report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// report is the result of the graph inspection. It is written as is in the json format,
+// field names match the names of the graph fields.
+type report struct {
+	graph *build.Graph
+
+	Jobs   int
+	Errors []build.ValidationError
+
+	// Levels and CriticalPath are computed only for the valid graph.
+	Levels       []level
+	CriticalPath []jobRef
+}
+
+type jobRef struct {
+	ID   build.ID
+	Name string
+}
+
+type level struct {
+	Width int
+	Jobs  []jobRef
+}
+
+func refs(jobs []build.Job) []jobRef {
+	out := make([]jobRef, len(jobs))
+	for i, job := range jobs {
+		out[i] = jobRef{ID: job.ID, Name: job.Name}
+	}
+	return out
+}
+
+func newReport(graph *build.Graph) *report {
+	r := &report{
+		graph:  graph,
+		Jobs:   len(graph.Jobs),
+		Errors: build.Validate(graph),
+	}
+	if len(r.Errors) != 0 {
+		return r
+	}
+
+	for _, jobs := range build.Levels(graph.Jobs) {
+		r.Levels = append(r.Levels, level{Width: len(jobs), Jobs: refs(jobs)})
+	}
+	r.CriticalPath = refs(build.CriticalPath(graph.Jobs))
+	return r
+}
+
+func writeText(w io.Writer, r *report) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "jobs: %d\n", r.Jobs)
+	if len(r.Errors) != 0 {
+		fmt.Fprintf(&b, "problems: %d\n", len(r.Errors))
+	} else {
+		fmt.Fprintf(&b, "levels: %d\n", len(r.Levels))
+		for i, l := range r.Levels {
+			fmt.Fprintf(&b, "  %d: %d jobs\n", i, l.Width)
+		}
+
+		fmt.Fprintf(&b, "critical path: %d jobs\n", len(r.CriticalPath))
+		for _, job := range r.CriticalPath {
+			fmt.Fprintf(&b, "  %s\n", job.Name)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
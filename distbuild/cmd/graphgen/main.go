@@ -0,0 +1,63 @@
+// Graphgen prints build graph of the Go module in JSON format.
+//
+// Usage:
+//
+//	graphgen [-dir dir] [-vet] [-test] [-o graph.json] [packages]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/graphgen"
+)
+
+var (
+	dir    = flag.String("dir", ".", "root directory of the module")
+	vet    = flag.Bool("vet", true, "add vet jobs")
+	test   = flag.Bool("test", true, "add test jobs")
+	output = flag.String("o", "", "output file, stdout by default")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: graphgen [flags] [packages]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	graph, err := graphgen.Generate(ctx, graphgen.Config{
+		Dir:      *dir,
+		Patterns: flag.Args(),
+		Vet:      *vet,
+		Test:     *test,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(graph); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := out.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,167 @@
+// Package cli contains helpers shared by distbuild executables.
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v2"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+)
+
+// LoadFile decodes JSON or YAML file into v.
+//
+// YAML documents are converted to JSON first, so that v is decoded by the same rules in both
+// cases. In particular, keys are matched to the struct fields case-insensitively and build.ID
+// values are written as hex strings.
+func LoadFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch filepath.Ext(path) {
+	case ".json":
+	case ".yaml", ".yml":
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		doc, err = jsonValue(doc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		if data, err = json.Marshal(doc); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("%s: unknown file format, expected .json, .yaml or .yml", path)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// jsonValue converts YAML maps with arbitrary keys into JSON objects.
+func jsonValue(v any) (any, error) {
+	switch v := v.(type) {
+	case map[any]any:
+		obj := make(map[string]any, len(v))
+		for key, value := range v {
+			str, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("non-string key %v", key)
+			}
+
+			var err error
+			if obj[str], err = jsonValue(value); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+
+	case []any:
+		for i := range v {
+			var err error
+			if v[i], err = jsonValue(v[i]); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+
+	default:
+		return v, nil
+	}
+}
+
+// ParseFlags parses command line into config.
+//
+// Flags of fs must be bound to the fields of config. When configPath flag is set, config is
+// loaded from the file, and the flags are applied once more on top of it. So the explicitly set
+// flags take precedence over the config file, and the config file takes precedence over the
+// defaults.
+func ParseFlags(fs *flag.FlagSet, args []string, configPath *string, config any) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *configPath == "" {
+		return nil
+	}
+
+	if err := LoadFile(*configPath, config); err != nil {
+		return err
+	}
+	return fs.Parse(args)
+}
+
+// NewLogger creates human-readable logger writing messages of the given level and above to stderr.
+func NewLogger(level string) (*zap.Logger, error) {
+	lvl, err := zap.ParseAtomicLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := zap.NewProductionConfig()
+	cfg.Level = lvl
+	cfg.Encoding = "console"
+	cfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	cfg.DisableStacktrace = true
+	return cfg.Build()
+}
+
+// AuthFiles locates credentials of the component. It is embedded into the configs of the executables.
+type AuthFiles struct {
+	TLSCA     string `json:"tls_ca"`
+	TLSCert   string `json:"tls_cert"`
+	TLSKey    string `json:"tls_key"`
+	TokenFile string `json:"token_file"`
+}
+
+// RegisterFlags binds -tls-ca, -tls-cert, -tls-key and -token-file flags.
+func (f *AuthFiles) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&f.TLSCA, "tls-ca", f.TLSCA, "PEM certificate of the cluster CA, enables mutual TLS")
+	fs.StringVar(&f.TLSCert, "tls-cert", f.TLSCert, "PEM certificate signed by the cluster CA")
+	fs.StringVar(&f.TLSKey, "tls-key", f.TLSKey, "PEM key of -tls-cert")
+	fs.StringVar(&f.TokenFile, "token-file", f.TokenFile, "file with bearer tokens, one per line; servers accept all of them, the first one is sent to other components")
+}
+
+// Load reads the credentials. It returns nil config, when no files are set.
+func (f *AuthFiles) Load() (*auth.Config, error) {
+	if f.TLSCA == "" && f.TLSCert == "" && f.TLSKey == "" && f.TokenFile == "" {
+		return nil, nil
+	}
+
+	var cfg auth.Config
+	if f.TLSCA != "" || f.TLSCert != "" || f.TLSKey != "" {
+		if f.TLSCA == "" {
+			return nil, errors.New("-tls-cert and -tls-key require -tls-ca")
+		}
+
+		var err error
+		if cfg.TLS, err = auth.LoadTLS(f.TLSCA, f.TLSCert, f.TLSKey); err != nil {
+			return nil, fmt.Errorf("load TLS credentials: %w", err)
+		}
+	}
+
+	if f.TokenFile != "" {
+		tokens, err := auth.LoadTokens(f.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("load tokens: %w", err)
+		}
+		cfg.Token = tokens[0]
+		cfg.Tokens = tokens
+	}
+	return &cfg, nil
+}
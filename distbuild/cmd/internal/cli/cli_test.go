@@ -0,0 +1,86 @@
+package cli_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/cmd/internal/cli"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0666))
+	return path
+}
+
+func TestLoadGraph(t *testing.T) {
+	const yamlGraph = `
+sourcefiles:
+  "6100000000000000000000000000000000000000": a.txt
+jobs:
+  - id: "6200000000000000000000000000000000000000"
+    name: cat
+    inputs: [a.txt]
+    cmds:
+      - exec: [cat, "{{.SourceDir}}/a.txt"]
+        environ: [A=1]
+`
+
+	const jsonGraph = `{
+	"SourceFiles": {"6100000000000000000000000000000000000000": "a.txt"},
+	"Jobs": [{
+		"ID": "6200000000000000000000000000000000000000",
+		"Name": "cat",
+		"Inputs": ["a.txt"],
+		"Cmds": [{"Exec": ["cat", "{{.SourceDir}}/a.txt"], "Environ": ["A=1"]}]
+	}]
+}`
+
+	expected := build.Graph{
+		SourceFiles: map[build.ID]string{{'a'}: "a.txt"},
+		Jobs: []build.Job{
+			{
+				ID:     build.ID{'b'},
+				Name:   "cat",
+				Inputs: []string{"a.txt"},
+				Cmds:   []build.Cmd{{Exec: []string{"cat", "{{.SourceDir}}/a.txt"}, Environ: []string{"A=1"}}},
+			},
+		},
+	}
+
+	for name, content := range map[string]string{"graph.yaml": yamlGraph, "graph.json": jsonGraph} {
+		var graph build.Graph
+		require.NoError(t, cli.LoadFile(writeFile(t, name, content), &graph), name)
+		require.Equal(t, expected, graph, name)
+	}
+
+	var graph build.Graph
+	require.Error(t, cli.LoadFile(writeFile(t, "graph.txt", jsonGraph), &graph))
+}
+
+type testConfig struct {
+	Listen   string `json:"listen"`
+	Root     string `json:"root"`
+	LogLevel string `json:"log_level"`
+}
+
+func TestParseFlags(t *testing.T) {
+	configPath := writeFile(t, "config.yaml", "listen: :1234\nlog_level: debug\n")
+
+	cfg := testConfig{Listen: ":9090", Root: "default", LogLevel: "info"}
+
+	var path string
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.StringVar(&path, "config", "", "")
+	fs.StringVar(&cfg.Listen, "listen", cfg.Listen, "")
+	fs.StringVar(&cfg.Root, "root", cfg.Root, "")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "")
+
+	require.NoError(t, cli.ParseFlags(fs, []string{"-config", configPath, "-log-level", "warn"}, &path, &cfg))
+	require.Equal(t, testConfig{Listen: ":1234", Root: "default", LogLevel: "warn"}, cfg)
+}
@@ -0,0 +1,239 @@
+// Worker runs distbuild worker.
+//
+// Usage:
+//
+//	worker [-config worker.yaml] [-listen :9091] [-coordinator http://localhost:9090] [-root dir]
+//
+// With -tls-ca, -tls-cert and -tls-key, the worker connects to the coordinator and serves artifacts over
+// mutual TLS. The certificate must be valid for the host of -advertise.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/cmd/internal/cli"
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+	"gitlab.com/slon/shad-go/distbuild/pkg/worker"
+)
+
+const shutdownTimeout = 10 * time.Second
+
+type config struct {
+	Listen      string `json:"listen"`
+	Advertise   string `json:"advertise"`
+	Coordinator string `json:"coordinator"`
+	Root        string `json:"root"`
+	LogLevel    string `json:"log_level"`
+
+	ArtifactsMaxBytes int64 `json:"artifacts_max_bytes"`
+	FileCacheMaxBytes int64 `json:"filecache_max_bytes"`
+	VerifyArtifacts   bool  `json:"verify_artifacts"`
+
+	Slots       int      `json:"slots"`
+	MilliCPU    int64    `json:"milli_cpu"`
+	MemoryBytes int64    `json:"memory_bytes"`
+	Labels      []string `json:"labels"`
+
+	Sandbox       bool     `json:"sandbox"`
+	SandboxMounts []string `json:"sandbox_mounts"`
+
+	Environ []string `json:"environ"`
+
+	cli.AuthFiles
+}
+
+// resources returns the worker capacity. Zero limit of a single resource means that the resource is not limited.
+func (c *config) resources() *build.Resources {
+	if c.MilliCPU == 0 && c.MemoryBytes == 0 {
+		return nil
+	}
+
+	r := &build.Resources{MilliCPU: c.MilliCPU, Memory: c.MemoryBytes}
+	if r.MilliCPU == 0 {
+		r.MilliCPU = math.MaxInt64
+	}
+	if r.Memory == 0 {
+		r.Memory = math.MaxInt64
+	}
+	return r
+}
+
+func main() {
+	cfg := config{
+		Listen:      ":9091",
+		Coordinator: "http://localhost:9090",
+		Root:        "distbuild-worker",
+		LogLevel:    "info",
+		Slots:       1,
+		Environ:     worker.DefaultEnviron,
+	}
+
+	var configPath string
+	flag.StringVar(&configPath, "config", "", "JSON or YAML config file, flags override its values")
+	flag.StringVar(&cfg.Listen, "listen", cfg.Listen, "address of the artifact server")
+	flag.StringVar(&cfg.Advertise, "advertise", cfg.Advertise, "URL of the artifact server for other workers, derived from hostname and -listen by default")
+	flag.StringVar(&cfg.Coordinator, "coordinator", cfg.Coordinator, "coordinator endpoint")
+	flag.StringVar(&cfg.Root, "root", cfg.Root, "directory of the caches")
+	flag.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "log level")
+	flag.Int64Var(&cfg.ArtifactsMaxBytes, "artifacts-max-bytes", cfg.ArtifactsMaxBytes, "size limit of the artifact cache, 0 means unlimited")
+	flag.Int64Var(&cfg.FileCacheMaxBytes, "filecache-max-bytes", cfg.FileCacheMaxBytes, "size limit of the file cache, 0 means unlimited")
+	flag.BoolVar(&cfg.VerifyArtifacts, "verify-artifacts", cfg.VerifyArtifacts, "check artifacts against their manifests on every access")
+	flag.IntVar(&cfg.Slots, "slots", cfg.Slots, "number of jobs running concurrently")
+	flag.Int64Var(&cfg.MilliCPU, "milli-cpu", cfg.MilliCPU, "CPU shared by the jobs in thousandths of a core, 0 means unlimited")
+	flag.Int64Var(&cfg.MemoryBytes, "memory-bytes", cfg.MemoryBytes, "memory shared by the jobs, 0 means unlimited")
+	flag.Func("labels", "comma separated worker labels", func(value string) error {
+		cfg.Labels = strings.Split(value, ",")
+		return nil
+	})
+	flag.BoolVar(&cfg.Sandbox, "sandbox", cfg.Sandbox, "run jobs in a sandbox, that hides the host file system and network")
+	flag.Func("sandbox-mounts", "comma separated host paths with the tools visible inside the sandbox", func(value string) error {
+		cfg.SandboxMounts = strings.Split(value, ",")
+		return nil
+	})
+	flag.Func("environ", "comma separated variables passed to the jobs, NAME copies the variable of the worker", func(value string) error {
+		cfg.Environ = strings.Split(value, ",")
+		return nil
+	})
+	cfg.AuthFiles.RegisterFlags(flag.CommandLine)
+
+	if err := cli.ParseFlags(flag.CommandLine, os.Args[1:], &configPath, &cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if err := run(&cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// advertiseURL derives the worker URL from the scheme, the hostname and the listen port.
+func advertiseURL(scheme, listen string) (string, error) {
+	_, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return "", err
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	return scheme + "://" + net.JoinHostPort(host, port), nil
+}
+
+func run(cfg *config) error {
+	l, err := cli.NewLogger(cfg.LogLevel)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Sync() }()
+
+	authConfig, err := cfg.AuthFiles.Load()
+	if err != nil {
+		return err
+	}
+	if err := authConfig.CheckServer(); err != nil {
+		return err
+	}
+
+	if cfg.Advertise == "" {
+		scheme := "http"
+		if authConfig.ServerTLS() != nil {
+			scheme = "https"
+		}
+
+		if cfg.Advertise, err = advertiseURL(scheme, cfg.Listen); err != nil {
+			return err
+		}
+	}
+
+	fileCache, err := filecache.NewWithConfig(
+		filepath.Join(cfg.Root, "filecache"),
+		artifact.Config{MaxBytes: cfg.FileCacheMaxBytes, Logger: l.Named("filecache")})
+	if err != nil {
+		return fmt.Errorf("open file cache: %w", err)
+	}
+
+	artifacts, err := artifact.NewCacheWithConfig(
+		filepath.Join(cfg.Root, "artifacts"),
+		artifact.Config{MaxBytes: cfg.ArtifactsMaxBytes, Verify: cfg.VerifyArtifacts, Logger: l.Named("artifacts")})
+	if err != nil {
+		return fmt.Errorf("open artifact cache: %w", err)
+	}
+
+	w := worker.NewWithConfig(
+		api.WorkerID(cfg.Advertise),
+		cfg.Coordinator,
+		l.Named("worker"),
+		fileCache,
+		artifacts,
+		worker.Config{
+			Slots:         cfg.Slots,
+			Resources:     cfg.resources(),
+			Labels:        cfg.Labels,
+			Sandbox:       cfg.Sandbox,
+			SandboxMounts: cfg.SandboxMounts,
+			Environ:       cfg.Environ,
+			Auth:          authConfig,
+		})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: cfg.Listen, Handler: w, TLSConfig: authConfig.ServerTLS()}
+	serveErr := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			serveErr <- srv.ListenAndServeTLS("", "")
+		} else {
+			serveErr <- srv.ListenAndServe()
+		}
+	}()
+
+	l.Info("worker started",
+		zap.String("listen", cfg.Listen),
+		zap.String("advertise", cfg.Advertise),
+		zap.String("coordinator", cfg.Coordinator))
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- w.Run(ctx)
+	}()
+
+	select {
+	case err = <-serveErr:
+		stop()
+		<-runErr
+		return err
+	case err = <-runErr:
+		// Run returns nil, when the coordinator unregisters the drained worker.
+		if err != nil && !errors.Is(err, context.Canceled) {
+			_ = srv.Close()
+			return err
+		}
+	}
+
+	l.Info("stopping worker")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	return srv.Shutdown(shutdownCtx)
+}
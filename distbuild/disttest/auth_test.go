@@ -0,0 +1,119 @@
+package disttest
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/worker"
+)
+
+var authConfig = &Config{WorkerCount: 2, Auth: true}
+
+func TestAuthenticatedBuild(t *testing.T) {
+	// Jobs run on different workers, so the artifact is transferred between the workers.
+	env := newEnv(t, &Config{
+		WorkerCount: 2,
+		Auth:        true,
+		Workers:     []worker.Config{{Labels: []string{"first"}}, {Labels: []string{"second"}}},
+	})
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:     build.ID{'a'},
+				Name:   "write",
+				Labels: []string{"first"},
+				Cmds: []build.Cmd{
+					{CatTemplate: "OK", CatOutput: "{{.OutputDir}}/out.txt"},
+				},
+			},
+			{
+				ID:     build.ID{'b'},
+				Name:   "cat",
+				Labels: []string{"second"},
+				Cmds: []build.Cmd{
+					{Exec: []string{"cat", fmt.Sprintf("{{index .Deps %q}}/out.txt", build.ID{'a'})}},
+				},
+				Deps: []build.ID{{'a'}},
+			},
+		},
+	}
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+	assert.Equal(t, &JobResult{Stdout: "OK", Code: new(int)}, recorder.Jobs[build.ID{'b'}])
+}
+
+func TestUnauthenticatedRequests(t *testing.T) {
+	env := newEnv(t, authConfig)
+
+	post := func(c *http.Client, u string) int {
+		rsp, err := c.Post(u, "application/json", bytes.NewBufferString("{}"))
+		require.NoError(t, err)
+		defer rsp.Body.Close()
+		return rsp.StatusCode
+	}
+
+	anonymous := (&auth.Config{TLS: &tls.Config{RootCAs: env.CA.Pool()}}).HTTPClient()
+	defer anonymous.CloseIdleConnections()
+	assert.Equal(t, http.StatusUnauthorized, post(anonymous, env.CoordinatorEndpoint+"/build"))
+	assert.Equal(t, http.StatusUnauthorized, post(anonymous, env.WorkerEndpoints[0]+"/artifact"))
+
+	wrongToken := (&auth.Config{TLS: &tls.Config{RootCAs: env.CA.Pool()}, Token: "wrong"}).HTTPClient()
+	defer wrongToken.CloseIdleConnections()
+	assert.Equal(t, http.StatusUnauthorized, post(wrongToken, env.CoordinatorEndpoint+"/query"))
+
+	// Client token is valid only for the coordinator API.
+	user := (&auth.Config{TLS: &tls.Config{RootCAs: env.CA.Pool()}, Token: env.Token}).HTTPClient()
+	defer user.CloseIdleConnections()
+	assert.Equal(t, http.StatusOK, post(user, env.CoordinatorEndpoint+"/query"))
+	assert.Equal(t, http.StatusUnauthorized, post(user, env.WorkerEndpoints[0]+"/artifact"))
+
+	// Client must not pretend to be a worker.
+	heartbeat := func(c *http.Client, workerID string) error {
+		_, err := api.NewHeartbeatClientWithHTTPClient(env.Logger, env.CoordinatorEndpoint, c).
+			Heartbeat(env.Ctx, &api.HeartbeatRequest{WorkerID: api.WorkerID(workerID)})
+		return err
+	}
+
+	err := heartbeat(user, env.WorkerEndpoints[0])
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "http status 403")
+
+	// Nor take a worker out of service.
+	_, err = api.NewDrainClientWithHTTPClient(env.Logger, env.CoordinatorEndpoint, user).
+		Drain(env.Ctx, api.WorkerID(env.WorkerEndpoints[0]), &api.DrainRequest{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "http status 403")
+
+	intruderTLS, err := env.CA.TLSConfig("intruder", "127.0.0.1")
+	require.NoError(t, err)
+
+	intruder := (&auth.Config{TLS: intruderTLS}).HTTPClient()
+	defer intruder.CloseIdleConnections()
+
+	err = heartbeat(intruder, "https://127.0.0.1:1/worker/9")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "http status 403")
+
+	// Certificate of other CA is rejected during the handshake.
+	otherCA, err := auth.NewCA()
+	require.NoError(t, err)
+
+	otherTLS, err := otherCA.TLSConfig("worker0", "127.0.0.1")
+	require.NoError(t, err)
+	otherTLS.RootCAs = env.CA.Pool()
+
+	other := (&auth.Config{TLS: otherTLS}).HTTPClient()
+	defer other.CloseIdleConnections()
+	require.Error(t, heartbeat(other, env.WorkerEndpoints[0]))
+}
@@ -0,0 +1,48 @@
+package disttest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// cachedRecorder remembers jobs reported as cached.
+type cachedRecorder struct {
+	*Recorder
+
+	Cached []build.ID
+}
+
+func (r *cachedRecorder) OnJobCached(jobID build.ID) error {
+	r.Cached = append(r.Cached, jobID)
+	return r.OnJobFinished(jobID)
+}
+
+func TestCachedJobOutput(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "test",
+				Cmds: []build.Cmd{
+					{Exec: []string{"bash", "-c", "echo PASS; echo warning >&2"}},
+				},
+			},
+		},
+	}
+
+	first := &cachedRecorder{Recorder: NewRecorder()}
+	require.NoError(t, env.Client.Build(env.Ctx, graph, first))
+	assert.Empty(t, first.Cached)
+
+	second := &cachedRecorder{Recorder: NewRecorder()}
+	require.NoError(t, env.Client.Build(env.Ctx, graph, second))
+
+	assert.Equal(t, []build.ID{{'a'}}, second.Cached)
+	assert.Equal(t, first.Jobs, second.Jobs)
+}
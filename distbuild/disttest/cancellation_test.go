@@ -0,0 +1,132 @@
+package disttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// sleepGraph starts a background process, saves its pid into pidFile and waits for it.
+func sleepGraph(pidFile string) build.Graph {
+	return build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "sleep",
+				Cmds: []build.Cmd{
+					{Exec: []string{"bash", "-c", fmt.Sprintf("sleep 60 & echo $! > %s; echo started; wait", pidFile)}},
+				},
+			},
+		},
+	}
+}
+
+// cancellingRecorder cancels the build, once the job is started.
+type cancellingRecorder struct {
+	*Recorder
+
+	cancel func()
+}
+
+func (r *cancellingRecorder) OnJobStdout(jobID build.ID, stdout []byte) error {
+	r.cancel()
+	return r.Recorder.OnJobStdout(jobID, stdout)
+}
+
+// processExited checks that the process is either reaped or is a zombie.
+func processExited(t *testing.T, pid int) bool {
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if errors.Is(err, os.ErrNotExist) {
+		return true
+	}
+	require.NoError(t, err)
+
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return fields[0] == "Z"
+}
+
+func requireJobKilled(t *testing.T, env *env, pidFile string) {
+	pidBytes, err := os.ReadFile(pidFile)
+	require.NoError(t, err)
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(pidBytes)))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return processExited(t, pid)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Worker aborts the artifact after the process is killed.
+	require.Eventually(t, func() bool {
+		_, _, err := env.WorkerCache[0].Get(build.ID{'a'})
+		return errors.Is(err, artifact.ErrNotFound)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestCancelBuildOnContextDone(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("test inspects /proc")
+	}
+
+	env := newEnv(t, singleWorkerConfig)
+	pidFile := filepath.Join(env.RootDir, "pid")
+
+	ctx, cancel := context.WithCancel(env.Ctx)
+	defer cancel()
+
+	recorder := &cancellingRecorder{Recorder: NewRecorder(), cancel: cancel}
+	err := env.Client.Build(ctx, sleepGraph(pidFile), recorder)
+	require.ErrorIs(t, err, context.Canceled)
+
+	requireJobKilled(t, env, pidFile)
+}
+
+func TestCancelBuildSignal(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("test inspects /proc")
+	}
+
+	env := newEnv(t, singleWorkerConfig)
+	pidFile := filepath.Join(env.RootDir, "pid")
+
+	builds := api.NewBuildClient(env.Logger.Named("cancel"), env.CoordinatorEndpoint)
+
+	started, r, err := builds.StartBuild(env.Ctx, &api.BuildRequest{Graph: sleepGraph(pidFile)})
+	require.NoError(t, err)
+	defer r.Close()
+
+	_, err = builds.SignalBuild(env.Ctx, started.ID, &api.SignalRequest{UploadDone: &api.UploadDone{}})
+	require.NoError(t, err)
+
+	update, err := r.Next()
+	require.NoError(t, err)
+	require.NotNil(t, update.JobOutput)
+
+	_, err = builds.SignalBuild(env.Ctx, started.ID, &api.SignalRequest{CancelBuild: &api.CancelBuild{}})
+	require.NoError(t, err)
+
+	for {
+		update, err = r.Next()
+		require.NoError(t, err)
+		require.Nil(t, update.JobFinished)
+
+		if update.BuildCancelled != nil {
+			break
+		}
+	}
+
+	requireJobKilled(t, env, pidFile)
+}
@@ -0,0 +1,176 @@
+package disttest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/worker"
+)
+
+// transferGraph builds artifact of job a on the first worker and reads it from job b on the second worker.
+var transferGraph = build.Graph{
+	Jobs: []build.Job{
+		{
+			ID:     build.ID{'a'},
+			Name:   "write",
+			Labels: []string{"first"},
+			Cmds:   []build.Cmd{{CatTemplate: "OK", CatOutput: "{{.OutputDir}}/out.txt"}},
+		},
+		{
+			ID:     build.ID{'b'},
+			Name:   "cat",
+			Labels: []string{"second"},
+			Deps:   []build.ID{{'a'}},
+			Cmds:   []build.Cmd{{Exec: []string{"cat", fmt.Sprintf("{{index .Deps %q}}/out.txt", build.ID{'a'})}}},
+		},
+	},
+}
+
+func TestChaosLostHeartbeatResponses(t *testing.T) {
+	env := newEnv(t, &Config{
+		WorkerCount: 1,
+		Faults:      []Fault{{Path: "/coordinator/heartbeat", Skip: 1, Count: 3, DropResponse: true}},
+	})
+
+	var graph build.Graph
+	for i := 0; i < 3; i++ {
+		job := build.Job{
+			ID:   build.ID{'a', byte(i)},
+			Name: fmt.Sprintf("echo %d", i),
+			Cmds: []build.Cmd{{Exec: []string{"echo", fmt.Sprint(i)}}},
+		}
+		if i != 0 {
+			job.Deps = []build.ID{{'a', byte(i - 1)}}
+		}
+		graph.Jobs = append(graph.Jobs, job)
+	}
+
+	// Jobs picked in the lost responses are given to the worker again.
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	for i, job := range graph.Jobs {
+		assert.Equal(t, &JobResult{Stdout: fmt.Sprintln(i), Code: new(int)}, recorder.Jobs[job.ID])
+	}
+}
+
+func TestChaosSlowArtifactDownload(t *testing.T) {
+	env := newEnv(t, &Config{
+		WorkerCount: 2,
+		Workers:     []worker.Config{{Labels: []string{"first"}}, {Labels: []string{"second"}}},
+		Faults:      []Fault{{Path: "/worker/0/artifact", Delay: 500 * time.Millisecond}},
+	})
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, transferGraph, recorder))
+	assert.Equal(t, &JobResult{Stdout: "OK", Code: new(int)}, recorder.Jobs[build.ID{'b'}])
+}
+
+func TestChaosArtifactServerDown(t *testing.T) {
+	env := newEnv(t, &Config{
+		WorkerCount: 2,
+		Workers:     []worker.Config{{Labels: []string{"first"}}, {Labels: []string{"second"}}},
+		Faults:      []Fault{{Path: "/worker/0/artifact", Drop: true}},
+	})
+
+	// Build fails cleanly, when the dependency can not be downloaded.
+	recorder := NewRecorder()
+	require.Error(t, env.Client.Build(env.Ctx, transferGraph, recorder))
+
+	job := recorder.Jobs[build.ID{'b'}]
+	require.NotNil(t, job)
+	assert.NotEmpty(t, job.Error)
+}
+
+func TestChaosWorkerCrash(t *testing.T) {
+	job := build.ID{'a'}
+	env := newEnv(t, &Config{
+		WorkerCount: 1,
+		Kills:       []Kill{{Worker: 0, Job: job, Downtime: 100 * time.Millisecond}},
+	})
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   job,
+				Name: "slow",
+				Cmds: []build.Cmd{{Exec: []string{"bash", "-c", "sleep 0.5; echo OK"}}},
+			},
+		},
+	}
+
+	crashed := env.Workers[0]
+
+	// Restarted worker reports no running jobs, and the lost job is run again.
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+	assert.Equal(t, &JobResult{Stdout: "OK\n", Code: new(int)}, recorder.Jobs[job])
+
+	env.workersMu.Lock()
+	defer env.workersMu.Unlock()
+	assert.NotSame(t, crashed, env.Workers[0])
+}
+
+func TestChaosCoordinatorRestartDuringDownload(t *testing.T) {
+	env := newEnv(t, &Config{
+		WorkerCount:        2,
+		DurableCoordinator: true,
+		Workers:            []worker.Config{{Labels: []string{"first"}}, {Labels: []string{"second"}}},
+		Faults:             []Fault{{Path: "/worker/0/artifact", Delay: 300 * time.Millisecond}},
+	})
+
+	recorder := &restartingRecorder{
+		Recorder: NewRecorder(),
+		jobID:    build.ID{'a'},
+		restart:  func() { env.RestartCoordinator(t) },
+	}
+	require.NoError(t, env.Client.Build(env.Ctx, transferGraph, recorder))
+	assert.Equal(t, &JobResult{Stdout: "OK", Code: new(int)}, recorder.Jobs[build.ID{'b'}])
+}
+
+func TestChaosWorkerDeath(t *testing.T) {
+	job := build.ID{'a'}
+	env := newEnv(t, &Config{
+		WorkerCount:   2,
+		WorkerTimeout: time.Second,
+		Kills:         []Kill{{Worker: 0, Job: job, Permanent: true}},
+	})
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   job,
+				Name: "slow",
+				Cmds: []build.Cmd{{Exec: []string{"bash", "-c", "sleep 0.5; echo OK"}}},
+			},
+		},
+	}
+
+	// The second worker is down, until the first one dies with the job.
+	env.KillWorker(1)
+	go func() {
+		for !env.isKilled(0) {
+			select {
+			case <-time.After(10 * time.Millisecond):
+			case <-env.Ctx.Done():
+				return
+			}
+		}
+
+		if err := env.RestartWorker(1); err != nil {
+			env.Logger.Fatal("worker restart failed", zap.Error(err))
+		}
+	}()
+
+	// Dead worker never reports the job lost, coordinator requeues the job once the worker times out.
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+	assert.Equal(t, &JobResult{Stdout: "OK\n", Code: new(int)}, recorder.Jobs[job])
+	assert.True(t, env.isKilled(0))
+}
@@ -0,0 +1,104 @@
+package disttest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+var durableConfig = &Config{WorkerCount: 1, DurableCoordinator: true}
+
+// restartingRecorder restarts coordinator once the job is finished.
+type restartingRecorder struct {
+	*Recorder
+
+	jobID   build.ID
+	once    sync.Once
+	restart func()
+}
+
+func (r *restartingRecorder) OnJobFinished(jobID build.ID) error {
+	if err := r.Recorder.OnJobFinished(jobID); err != nil {
+		return err
+	}
+
+	if jobID == r.jobID {
+		r.once.Do(r.restart)
+	}
+	return nil
+}
+
+func TestCoordinatorRestartDuringBuild(t *testing.T) {
+	env := newEnv(t, durableConfig)
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "write",
+				Cmds: []build.Cmd{
+					{CatTemplate: "OK", CatOutput: "{{.OutputDir}}/out.txt"},
+					{Exec: []string{"echo", "a"}},
+				},
+			},
+			{
+				ID:   build.ID{'b'},
+				Name: "cat",
+				Cmds: []build.Cmd{
+					{Exec: []string{"cat", fmt.Sprintf("{{index .Deps %q}}/out.txt", build.ID{'a'})}},
+				},
+				Deps: []build.ID{{'a'}},
+			},
+		},
+	}
+
+	recorder := &restartingRecorder{
+		Recorder: NewRecorder(),
+		jobID:    build.ID{'a'},
+		restart:  func() { env.RestartCoordinator(t) },
+	}
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	assert.Len(t, recorder.Jobs, 2)
+	assert.Equal(t, &JobResult{Stdout: "a\n", Code: new(int)}, recorder.Jobs[build.ID{'a'}])
+	assert.Equal(t, &JobResult{Stdout: "OK", Code: new(int)}, recorder.Jobs[build.ID{'b'}])
+}
+
+func TestCoordinatorRestartKeepsArtifacts(t *testing.T) {
+	env := newEnv(t, durableConfig)
+
+	tmpFile, err := os.CreateTemp("", "")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "echo",
+				Cmds: []build.Cmd{
+					{CatTemplate: "OK\n", CatOutput: tmpFile.Name()}, // No-hermetic, for testing purposes.
+				},
+			},
+		},
+	}
+
+	require.NoError(t, env.Client.Build(env.Ctx, graph, NewRecorder()))
+	require.NoError(t, os.WriteFile(tmpFile.Name(), []byte("NOTOK\n"), 0666))
+
+	env.RestartCoordinator(t)
+
+	// Restarted coordinator must know that the artifact is stored on the worker.
+	require.NoError(t, env.Client.Build(env.Ctx, graph, NewRecorder()))
+
+	output, err := io.ReadAll(tmpFile)
+	require.NoError(t, err)
+	require.Equal(t, []byte("NOTOK\n"), output)
+}
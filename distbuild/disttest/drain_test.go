@@ -0,0 +1,136 @@
+package disttest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/worker"
+)
+
+var twoLabeledWorkersConfig = &Config{
+	WorkerCount: 2,
+	Workers:     []worker.Config{{Labels: []string{"first"}}, {Labels: []string{"second"}}},
+}
+
+// waitDrained polls the drain endpoint, until the worker is unregistered.
+func waitDrained(t *testing.T, env *env, c *api.DrainClient, workerID api.WorkerID, req *api.DrainRequest) {
+	for {
+		status, err := c.Drain(env.Ctx, workerID, req)
+		require.NoError(t, err)
+		if status.Drained {
+			return
+		}
+
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-env.Ctx.Done():
+			t.Fatal("worker is not drained")
+		}
+	}
+}
+
+func TestDrainReplicatesArtifacts(t *testing.T) {
+	env := newEnv(t, twoLabeledWorkersConfig)
+
+	echo := build.Job{
+		ID:     build.ID{'a'},
+		Name:   "echo",
+		Labels: []string{"first"},
+		Cmds:   []build.Cmd{{Exec: []string{"echo", "OK"}}},
+	}
+	graph := build.Graph{Jobs: []build.Job{echo}}
+
+	require.NoError(t, env.Client.Build(env.Ctx, graph, NewRecorder()))
+	require.Equal(t, []int{0}, workersHolding(env, echo.ID))
+
+	drain := api.NewDrainClient(env.Logger, env.CoordinatorEndpoint)
+	waitDrained(t, env, drain, api.WorkerID(env.WorkerEndpoints[0]), &api.DrainRequest{Replicate: true})
+
+	select {
+	case <-env.WorkerDrained[0]:
+	case <-env.Ctx.Done():
+		t.Fatal("drained worker is still running")
+	}
+
+	require.Equal(t, []int{0, 1}, workersHolding(env, echo.ID))
+
+	query := api.NewBuildClient(env.Logger, env.CoordinatorEndpoint)
+	rsp, err := query.QueryCache(env.Ctx, &api.QueryRequest{Jobs: []build.ID{echo.ID}})
+	require.NoError(t, err)
+	require.Equal(t, api.WorkerID(env.WorkerEndpoints[1]), rsp.Cached[echo.ID].WorkerID)
+}
+
+func TestDrainThroughWorker(t *testing.T) {
+	env := newEnv(t, twoLabeledWorkersConfig)
+
+	echo := build.Job{
+		ID:     build.ID{'a'},
+		Name:   "echo",
+		Labels: []string{"first"},
+		Cmds:   []build.Cmd{{Exec: []string{"echo", "OK"}}},
+	}
+
+	require.NoError(t, env.Client.Build(env.Ctx, build.Graph{Jobs: []build.Job{echo}}, NewRecorder()))
+
+	// Worker asks the coordinator to drain it, artifacts are not copied.
+	drain := api.NewDrainClient(env.Logger, env.WorkerEndpoints[0])
+	status, err := drain.Drain(env.Ctx, "", &api.DrainRequest{})
+	require.NoError(t, err)
+	require.True(t, status.Draining)
+
+	select {
+	case <-env.WorkerDrained[0]:
+	case <-env.Ctx.Done():
+		t.Fatal("drained worker is still running")
+	}
+
+	query := api.NewBuildClient(env.Logger, env.CoordinatorEndpoint)
+	rsp, err := query.QueryCache(env.Ctx, &api.QueryRequest{Jobs: []build.ID{echo.ID}})
+	require.NoError(t, err)
+	require.Empty(t, rsp.Cached)
+
+	// Jobs without labels run on the remaining worker.
+	cat := build.Job{
+		ID:   build.ID{'b'},
+		Name: "cat",
+		Cmds: []build.Cmd{{Exec: []string{"echo", "OK"}}},
+	}
+	require.NoError(t, env.Client.Build(env.Ctx, build.Graph{Jobs: []build.Job{cat}}, NewRecorder()))
+	require.Equal(t, []int{1}, workersHolding(env, cat.ID))
+}
+
+func TestForceDrainDeadWorker(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 2, Workers: twoLabeledWorkersConfig.Workers, DurableCoordinator: true})
+
+	echo := build.Job{
+		ID:     build.ID{'a'},
+		Name:   "echo",
+		Labels: []string{"first"},
+		Cmds:   []build.Cmd{{Exec: []string{"echo", "OK"}}},
+	}
+	require.NoError(t, env.Client.Build(env.Ctx, build.Graph{Jobs: []build.Job{echo}}, NewRecorder()))
+
+	// Dead worker never heartbeats again, it is unregistered right away.
+	env.KillWorker(0)
+
+	drain := api.NewDrainClient(env.Logger, env.CoordinatorEndpoint)
+	status, err := drain.Drain(env.Ctx, api.WorkerID(env.WorkerEndpoints[0]), &api.DrainRequest{Force: true})
+	require.NoError(t, err)
+	require.True(t, status.Drained)
+
+	query := api.NewBuildClient(env.Logger, env.CoordinatorEndpoint)
+	rsp, err := query.QueryCache(env.Ctx, &api.QueryRequest{Jobs: []build.ID{echo.ID}})
+	require.NoError(t, err)
+	require.Equal(t, []build.ID{echo.ID}, rsp.Missing)
+
+	// Removal of the artifacts is journaled, restarted coordinator does not look for them on the dead worker.
+	env.RestartCoordinator(t)
+
+	rsp, err = query.QueryCache(env.Ctx, &api.QueryRequest{Jobs: []build.ID{echo.ID}})
+	require.NoError(t, err)
+	require.Equal(t, []build.ID{echo.ID}, rsp.Missing)
+}
@@ -0,0 +1,61 @@
+package disttest
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+func TestEvictedArtifactIsRebuilt(t *testing.T) {
+	env := newEnv(t, &Config{
+		WorkerCount:     1,
+		WorkerArtifacts: artifact.Config{MaxEntries: 1},
+	})
+
+	tmpFile, err := os.CreateTemp("", "")
+	require.NoError(t, err)
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+
+	graphA := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "count",
+				Cmds: []build.Cmd{
+					{Exec: []string{"bash", "-c", "echo run >> " + tmpFile.Name()}}, // No-hermetic, for testing purposes.
+				},
+			},
+		},
+	}
+
+	graphB := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'b'},
+				Name: "echo",
+				Cmds: []build.Cmd{
+					{Exec: []string{"echo", "OK"}},
+				},
+			},
+		},
+	}
+
+	require.NoError(t, env.Client.Build(env.Ctx, graphA, NewRecorder()))
+	require.NoError(t, env.Client.Build(env.Ctx, graphA, NewRecorder()))
+
+	output, err := os.ReadFile(tmpFile.Name())
+	require.NoError(t, err)
+	require.Equal(t, "run\n", string(output))
+
+	// Artifact of the job b evicts artifact of the job a from the worker cache.
+	require.NoError(t, env.Client.Build(env.Ctx, graphB, NewRecorder()))
+	require.NoError(t, env.Client.Build(env.Ctx, graphA, NewRecorder()))
+
+	output, err = os.ReadFile(tmpFile.Name())
+	require.NoError(t, err)
+	require.Equal(t, "run\nrun\n", string(output))
+}
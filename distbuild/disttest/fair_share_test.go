@@ -0,0 +1,85 @@
+package disttest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/client"
+)
+
+// queueRecorder remembers queue positions reported to the client.
+type queueRecorder struct {
+	*Recorder
+
+	Positions []map[build.ID]int
+}
+
+func (r *queueRecorder) OnJobsQueued(positions map[build.ID]int) error {
+	r.Positions = append(r.Positions, positions)
+	return nil
+}
+
+// userGraph returns jobs appending the user name to the log file.
+func userGraph(user string, jobs int, logFile string) build.Graph {
+	var graph build.Graph
+	for i := 0; i < jobs; i++ {
+		graph.Jobs = append(graph.Jobs, build.Job{
+			ID:   build.ID{user[0], byte(i)},
+			Name: fmt.Sprintf("%s %d", user, i),
+			Cmds: []build.Cmd{
+				{Exec: []string{"bash", "-c", fmt.Sprintf("echo %s >> %s; sleep 0.2", user, logFile)}},
+			},
+		})
+	}
+	return graph
+}
+
+func TestFairShare(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	logFile := filepath.Join(env.RootDir, "log")
+
+	alice := &queueRecorder{Recorder: NewRecorder()}
+	bob := NewRecorder()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		opts := client.BuildOptions{User: "alice"}
+		assert.NoError(t, env.Client.BuildWithOptions(env.Ctx, userGraph("alice", 4, logFile), alice, opts))
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+
+	go func() {
+		defer wg.Done()
+		opts := client.BuildOptions{User: "bob"}
+		assert.NoError(t, env.Client.BuildWithOptions(env.Ctx, userGraph("bob", 2, logFile), bob, opts))
+	}()
+
+	wg.Wait()
+
+	log, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+
+	// Jobs of bob do not wait for the whole build of alice.
+	order := strings.Fields(string(log))
+	require.Len(t, order, 6)
+	assert.Equal(t, []string{"alice", "alice"}, order[4:], "%v", order)
+
+	require.NotEmpty(t, alice.Positions)
+	for id := range alice.Positions[0] {
+		assert.Equal(t, byte('a'), id[0])
+	}
+}
@@ -0,0 +1,189 @@
+package disttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// Fault is injected into the HTTP requests between the components of the env.
+type Fault struct {
+	// Path selects the requests by the path prefix, e.g. "/coordinator/heartbeat" or "/worker/1/artifact".
+	Path string
+
+	// Skip is the number of the matching requests passed without the fault.
+	Skip int
+	// Count limits the number of the faulty requests. Zero means no limit.
+	Count int
+
+	// Delay holds the request before it is handled.
+	Delay time.Duration
+	// Drop fails the request with 503 Service Unavailable without handling it.
+	Drop bool
+	// DropResponse handles the request, but the client gets 503 Service Unavailable instead of the response.
+	// Requests with the streaming responses must not be selected.
+	DropResponse bool
+}
+
+// Kill crashes the worker, once the worker reports the job running, and restarts it after Downtime.
+type Kill struct {
+	Worker   int
+	Job      build.ID
+	Downtime time.Duration
+
+	// Permanent kill never restarts the worker, Downtime is ignored.
+	Permanent bool
+}
+
+type activeFault struct {
+	Fault
+	matched  int
+	injected int
+}
+
+type activeKill struct {
+	Kill
+	done bool
+}
+
+// faultInjector is the HTTP handler of the env, injecting faults into the requests.
+type faultInjector struct {
+	env  *env
+	next http.Handler
+
+	mu     sync.Mutex
+	faults []*activeFault
+	kills  []*activeKill
+}
+
+func newFaultInjector(env *env, next http.Handler, faults []Fault, kills []Kill) *faultInjector {
+	f := &faultInjector{env: env, next: next}
+	for _, fault := range faults {
+		f.inject(fault)
+	}
+	for _, kill := range kills {
+		f.kills = append(f.kills, &activeKill{Kill: kill})
+	}
+	return f
+}
+
+// InjectFault adds the fault to the requests made from now on.
+func (e *env) InjectFault(fault Fault) {
+	e.faults.inject(fault)
+}
+
+func (f *faultInjector) inject(fault Fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.faults = append(f.faults, &activeFault{Fault: fault})
+}
+
+// match returns the first fault, that should be injected into the request.
+func (f *faultInjector) match(path string) *Fault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, fault := range f.faults {
+		if !strings.HasPrefix(path, fault.Path) || (fault.Count != 0 && fault.injected == fault.Count) {
+			continue
+		}
+
+		fault.matched++
+		if fault.matched <= fault.Skip {
+			continue
+		}
+
+		fault.injected++
+		injected := fault.Fault
+		return &injected
+	}
+	return nil
+}
+
+// kill crashes the worker sending the heartbeat, if the heartbeat matches one of the kills.
+func (f *faultInjector) kill(r *http.Request) bool {
+	if len(f.kills) == 0 {
+		return false
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return false
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var req api.HeartbeatRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return false
+	}
+
+	f.mu.Lock()
+	var kill *Kill
+	for _, k := range f.kills {
+		if !k.done && f.env.WorkerEndpoints[k.Worker] == req.WorkerID.String() && slices.Contains(req.RunningJobs, k.Job) {
+			k.done = true
+			kill = &k.Kill
+			break
+		}
+	}
+	f.mu.Unlock()
+
+	if kill == nil {
+		return false
+	}
+
+	f.env.Logger.Info("fault injected: killing worker",
+		zap.String("worker_id", req.WorkerID.String()),
+		zap.String("job_id", kill.Job.String()))
+	if kill.Permanent {
+		f.env.KillWorker(kill.Worker)
+	} else {
+		f.env.killWorkerFor(kill.Worker, kill.Downtime)
+	}
+	return true
+}
+
+func (f *faultInjector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/coordinator/heartbeat" && f.kill(r) {
+		http.Error(w, "worker is killed", http.StatusServiceUnavailable)
+		return
+	}
+
+	fault := f.match(r.URL.Path)
+	if fault == nil {
+		f.next.ServeHTTP(w, r)
+		return
+	}
+
+	f.env.Logger.Info("fault injected", zap.String("path", r.URL.Path), zap.Any("fault", fault))
+
+	if fault.Delay > 0 {
+		select {
+		case <-time.After(fault.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	switch {
+	case fault.Drop:
+		http.Error(w, "fault injected: request dropped", http.StatusServiceUnavailable)
+	case fault.DropResponse:
+		f.next.ServeHTTP(httptest.NewRecorder(), r)
+		http.Error(w, "fault injected: response dropped", http.StatusServiceUnavailable)
+	default:
+		f.next.ServeHTTP(w, r)
+	}
+}
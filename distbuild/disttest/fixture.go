@@ -2,6 +2,7 @@ package disttest
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
@@ -10,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
 	"testing"
 	"time"
 
@@ -18,6 +20,7 @@ import (
 
 	"gitlab.com/slon/shad-go/distbuild/pkg/api"
 	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
 	"gitlab.com/slon/shad-go/distbuild/pkg/client"
 	"gitlab.com/slon/shad-go/distbuild/pkg/dist"
 	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
@@ -33,12 +36,32 @@ type env struct {
 
 	Ctx context.Context
 
+	// CoordinatorEndpoint is the address of the coordinator API.
+	CoordinatorEndpoint string
+
 	Client      *client.Client
 	Coordinator *dist.Coordinator
 	Workers     []*worker.Worker
 	WorkerCache []*artifact.Cache
+	// WorkerEndpoints are the addresses of the worker handlers.
+	WorkerEndpoints []string
+	// WorkerDrained are closed, when the workers stop after being drained.
+	WorkerDrained []chan struct{}
+
+	// CA signs certificates of the components and Token authenticates the client, when Config.Auth is set.
+	CA    *auth.CA
+	Token string
 
 	HTTP *http.Server
+
+	faults *faultInjector
+
+	workersMu sync.Mutex
+	processes []*workerProcess
+
+	coordinatorMu     sync.Mutex
+	coordinatorConfig dist.Config
+	coordinatorCache  *filecache.Cache
 }
 
 const (
@@ -47,8 +70,36 @@ const (
 
 type Config struct {
 	WorkerCount int
+
+	// DurableCoordinator makes coordinator keep its journal inside RootDir.
+	DurableCoordinator bool
+
+	// WorkerArtifacts limits the size of the worker artifact caches.
+	WorkerArtifacts artifact.Config
+
+	// Workers configures workers by index. Workers without an entry get the default configuration.
+	Workers []worker.Config
+
+	// SpeculationFactor enables speculative execution of the straggling jobs, see scheduler.Config.
+	SpeculationFactor float64
+
+	// WorkerTimeout overrides the time, after which the coordinator unregisters the silent worker.
+	WorkerTimeout time.Duration
+
+	// Faults are injected into the HTTP requests from the start. More faults may be added by env.InjectFault.
+	Faults []Fault
+
+	// Kills crash the workers in the middle of the jobs.
+	Kills []Kill
+
+	// Auth serves all components over mutual TLS. Client authenticates with Token, coordinator accepts
+	// heartbeats only from the workers of the env.
+	Auth bool
 }
 
+// testToken is the bearer token of the client in the env with Config.Auth.
+const testToken = "distbuild-test-token"
+
 func newEnv(t *testing.T, config *Config) (e *env) {
 	t.Cleanup(func() {
 		goleak.VerifyNone(t)
@@ -103,66 +154,119 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 	port, err := testtool.GetFreePort()
 	require.NoError(t, err)
 	addr := "127.0.0.1:" + port
-	coordinatorEndpoint := "http://" + addr + "/coordinator"
+
+	scheme := "http"
+	var clientAuth, serverAuth *auth.Config
+	if config.Auth {
+		scheme = "https"
+		env.Token = testToken
+		env.CA, err = auth.NewCA()
+		require.NoError(t, err)
+
+		clientAuth = &auth.Config{TLS: &tls.Config{RootCAs: env.CA.Pool()}, Token: testToken}
+
+		serverTLS, err := env.CA.TLSConfig("server", "127.0.0.1")
+		require.NoError(t, err)
+		serverAuth = &auth.Config{TLS: serverTLS, Tokens: []string{testToken}}
+
+		coordinatorTLS, err := env.CA.TLSConfig("coordinator", "127.0.0.1")
+		require.NoError(t, err)
+		env.coordinatorConfig.Auth = &auth.Config{TLS: coordinatorTLS, Tokens: []string{testToken}}
+
+		for i := 0; i < config.WorkerCount; i++ {
+			workerID := api.WorkerID(fmt.Sprintf("%s://%s/worker/%d", scheme, addr, i))
+			env.coordinatorConfig.Workers = append(env.coordinatorConfig.Workers, workerID)
+		}
+	}
+	coordinatorEndpoint := scheme + "://" + addr + "/coordinator"
 
 	var cancelRootContext func()
+	env.CoordinatorEndpoint = coordinatorEndpoint
 	env.Ctx, cancelRootContext = context.WithCancel(context.Background())
 	t.Cleanup(cancelRootContext)
 
-	env.Client = client.NewClient(
+	env.Client = client.NewClientWithConfig(
 		env.Logger.Named("client"),
 		coordinatorEndpoint,
-		filepath.Join(absCWD, "testdata", t.Name()))
+		filepath.Join(absCWD, "testdata", t.Name()),
+		client.Config{Auth: clientAuth})
 
-	coordinatorCache, err := filecache.New(filepath.Join(env.RootDir, "coordinator", "filecache"))
+	env.coordinatorCache, err = filecache.New(filepath.Join(env.RootDir, "coordinator", "filecache"))
 	require.NoError(t, err)
 
-	env.Coordinator = dist.NewCoordinator(
-		env.Logger.Named("coordinator"),
-		coordinatorCache,
-	)
-	t.Cleanup(env.Coordinator.Stop)
+	if config.DurableCoordinator || config.Auth || config.SpeculationFactor != 0 || config.WorkerTimeout != 0 {
+		env.coordinatorConfig.Scheduler.SpeculationFactor = config.SpeculationFactor
+		env.coordinatorConfig.WorkerTimeout = config.WorkerTimeout
+		if config.DurableCoordinator {
+			env.coordinatorConfig.JournalPath = filepath.Join(env.RootDir, "coordinator", "journal")
+		}
+
+		env.Coordinator, err = dist.OpenCoordinator(
+			env.Logger.Named("coordinator"),
+			env.coordinatorCache,
+			env.coordinatorConfig,
+		)
+		require.NoError(t, err)
+	} else {
+		env.Coordinator = dist.NewCoordinator(
+			env.Logger.Named("coordinator"),
+			env.coordinatorCache,
+		)
+	}
+	t.Cleanup(func() {
+		env.coordinator().Stop()
+	})
 
 	router := http.NewServeMux()
-	router.Handle("/coordinator/", http.StripPrefix("/coordinator", env.Coordinator))
+	router.Handle("/coordinator/", http.StripPrefix("/coordinator", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		env.coordinator().ServeHTTP(w, r)
+	})))
 
 	for i := 0; i < config.WorkerCount; i++ {
 		workerName := fmt.Sprintf("worker%d", i)
-		workerDir := filepath.Join(env.RootDir, workerName)
+		workerPrefix := fmt.Sprintf("/worker/%d", i)
 
-		var fileCache *filecache.Cache
-		fileCache, err = filecache.New(filepath.Join(workerDir, "filecache"))
-		require.NoError(t, err)
+		p := &workerProcess{
+			name:        workerName,
+			dir:         filepath.Join(env.RootDir, workerName),
+			id:          api.WorkerID(scheme + "://" + addr + workerPrefix),
+			coordinator: coordinatorEndpoint,
+			artifacts:   config.WorkerArtifacts,
+		}
+		if i < len(config.Workers) {
+			p.config = config.Workers[i]
+		}
 
-		var artifacts *artifact.Cache
-		artifacts, err = artifact.NewCache(filepath.Join(workerDir, "artifacts"))
-		require.NoError(t, err)
+		if config.Auth {
+			var workerTLS *tls.Config
+			workerTLS, err = env.CA.TLSConfig(workerName, "127.0.0.1")
+			require.NoError(t, err)
+			p.config.Auth = &auth.Config{TLS: workerTLS}
+		}
 
-		workerPrefix := fmt.Sprintf("/worker/%d", i)
-		workerID := api.WorkerID("http://" + addr + workerPrefix)
-
-		w := worker.New(
-			workerID,
-			coordinatorEndpoint,
-			env.Logger.Named(workerName),
-			fileCache,
-			artifacts,
-		)
+		require.NoError(t, env.startWorker(p))
 
-		env.Workers = append(env.Workers, w)
-		env.WorkerCache = append(env.WorkerCache, artifacts)
+		env.processes = append(env.processes, p)
+		env.WorkerEndpoints = append(env.WorkerEndpoints, p.id.String())
+		env.WorkerDrained = append(env.WorkerDrained, make(chan struct{}))
 
-		router.Handle(workerPrefix+"/", http.StripPrefix(workerPrefix, w))
+		router.Handle(workerPrefix+"/", http.StripPrefix(workerPrefix, env.workerHandler(i)))
 	}
 
+	env.faults = newFaultInjector(env, router, config.Faults, config.Kills)
+
 	env.HTTP = &http.Server{
 		Addr:    addr,
-		Handler: router,
+		Handler: env.faults,
 	}
 
 	lsn, err := net.Listen("tcp", env.HTTP.Addr)
 	require.NoError(t, err)
 
+	if serverTLS := serverAuth.ServerTLS(); serverTLS != nil {
+		lsn = tls.NewListener(lsn, serverTLS)
+	}
+
 	go func() {
 		err := env.HTTP.Serve(lsn)
 		if err != http.ErrServerClosed {
@@ -175,15 +279,8 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 		_ = env.HTTP.Shutdown(context.Background())
 	})
 
-	for _, w := range env.Workers {
-		go func(w *worker.Worker) {
-			err := w.Run(env.Ctx)
-			if errors.Is(err, context.Canceled) {
-				return
-			}
-
-			env.Logger.Fatal("worker stopped", zap.Error(err))
-		}(w)
+	for i := range env.processes {
+		env.runWorker(i)
 	}
 
 	go func() {
@@ -198,6 +295,28 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 	return env
 }
 
+func (e *env) coordinator() *dist.Coordinator {
+	e.coordinatorMu.Lock()
+	defer e.coordinatorMu.Unlock()
+
+	return e.Coordinator
+}
+
+// RestartCoordinator stops the durable coordinator and starts a new one from its journal.
+//
+// Requests to the coordinator are blocked until the new coordinator is started.
+func (e *env) RestartCoordinator(t *testing.T) {
+	e.coordinatorMu.Lock()
+	defer e.coordinatorMu.Unlock()
+
+	e.Logger.Info("restarting coordinator")
+	e.Coordinator.Stop()
+
+	c, err := dist.OpenCoordinator(e.Logger.Named("coordinator"), e.coordinatorCache, e.coordinatorConfig)
+	require.NoError(t, err)
+	e.Coordinator = c
+}
+
 func newWinFileSink(u *url.URL) (zap.Sink, error) {
 	if len(u.Opaque) > 0 {
 		// Remove leading slash left by url.Parse()
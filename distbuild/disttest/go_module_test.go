@@ -0,0 +1,53 @@
+package disttest
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/graphgen"
+	"gitlab.com/slon/shad-go/distbuild/pkg/worker"
+)
+
+func TestGoModuleBuild(t *testing.T) {
+	// Client graph does not carry the tool locations, worker supplies them.
+	env := newEnv(t, &Config{WorkerCount: 1, Workers: []worker.Config{{Environ: worker.DefaultEnviron}}})
+
+	graph, err := graphgen.Generate(env.Ctx, graphgen.Config{
+		Dir:  filepath.Join("testdata", t.Name()),
+		Vet:  true,
+		Test: true,
+	})
+	require.NoError(t, err)
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, *graph, recorder))
+
+	require.Len(t, recorder.Jobs, len(graph.Jobs))
+	for _, job := range graph.Jobs {
+		result := recorder.Jobs[job.ID]
+		if assert.NotNil(t, result.Code, job.Name) {
+			assert.Equal(t, 0, *result.Code, "%s: %s", job.Name, result.Stderr)
+		}
+
+		if strings.HasPrefix(job.Name, "test ") {
+			assert.Equal(t, "PASS\n", result.Stdout)
+		}
+
+		if job.Name != "link example.com/hello" {
+			continue
+		}
+
+		dir, unlock, err := env.WorkerCache[0].Get(job.ID)
+		require.NoError(t, err)
+		defer unlock()
+
+		output, err := exec.Command(filepath.Join(dir, "hello")).Output()
+		require.NoError(t, err)
+		assert.Equal(t, "Hello, world!\n", string(output))
+	}
+}
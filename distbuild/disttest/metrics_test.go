@@ -0,0 +1,73 @@
+package disttest
+
+import (
+	"bufio"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/metrics"
+)
+
+// scrape returns samples served at /metrics of the endpoint.
+func scrape(t *testing.T, endpoint string) map[string]string {
+	rsp, err := http.Get(endpoint + "/metrics")
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.Equal(t, metrics.ContentType, rsp.Header.Get("Content-Type"))
+
+	samples := map[string]string{}
+	s := bufio.NewScanner(rsp.Body)
+	for s.Scan() {
+		if strings.HasPrefix(s.Text(), "#") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(s.Text(), " ")
+		require.True(t, ok, s.Text())
+		samples[name] = value
+	}
+	require.NoError(t, s.Err())
+	return samples
+}
+
+func TestMetrics(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "echo",
+				Cmds: []build.Cmd{
+					{Exec: []string{"echo", "OK"}},
+				},
+			},
+		},
+	}
+
+	require.NoError(t, env.Client.Build(env.Ctx, graph, NewRecorder()))
+	require.NoError(t, env.Client.Build(env.Ctx, graph, NewRecorder()))
+
+	coordinator := scrape(t, env.CoordinatorEndpoint)
+	assert.Equal(t, "1", coordinator["distbuild_coordinator_jobs_scheduled_total"])
+	assert.Equal(t, "1", coordinator["distbuild_coordinator_jobs_cached_total"])
+	assert.Equal(t, "1", coordinator["distbuild_coordinator_jobs_finished_total"])
+	assert.Equal(t, "0", coordinator["distbuild_coordinator_jobs_failed_total"])
+	assert.Equal(t, "0", coordinator["distbuild_scheduler_queued_jobs"])
+	assert.Equal(t, "1", coordinator["distbuild_scheduler_workers"])
+	assert.NotEqual(t, "0", coordinator["distbuild_coordinator_heartbeat_duration_seconds_count"])
+
+	worker := scrape(t, env.WorkerEndpoints[0])
+	assert.Equal(t, "1", worker["distbuild_worker_job_duration_seconds_count"])
+	assert.Equal(t, "1", worker["distbuild_worker_free_slots"])
+	assert.Equal(t, "0", worker["distbuild_worker_running_jobs"])
+	assert.Equal(t, "1", worker["distbuild_worker_artifact_cache_misses_total"])
+	assert.NotEqual(t, "0", worker["distbuild_worker_heartbeat_duration_seconds_count"])
+}
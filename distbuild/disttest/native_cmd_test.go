@@ -0,0 +1,137 @@
+package disttest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/worker"
+)
+
+func TestNativeCmds(t *testing.T) {
+	env := newEnv(t, twoLabeledWorkersConfig)
+
+	lib := build.ID{'a'}
+	libDir := fmt.Sprintf("{{index .Deps %q}}", lib)
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:     lib,
+				Name:   "layout",
+				Labels: []string{"first"},
+				Cmds: []build.Cmd{
+					{Mkdir: "{{.OutputDir}}/lib"},
+					{CatTemplate: "A", CatOutput: "{{.OutputDir}}/lib/liba.so.1"},
+					{SymlinkTarget: "{{.OutputDir}}/lib/liba.so.1", SymlinkOutput: "{{.OutputDir}}/lib/liba.so"},
+					{ArchiveSource: "{{.OutputDir}}/lib", ArchiveOutput: "{{.OutputDir}}/lib.tar.gz"},
+				},
+			},
+			{
+				// Runs on the other worker, so the symlink is transferred with the artifact.
+				ID:     build.ID{'b'},
+				Name:   "consume",
+				Labels: []string{"second"},
+				Deps:   []build.ID{lib},
+				Cmds: []build.Cmd{
+					{CopySource: libDir + "/lib", CopyOutput: "{{.OutputDir}}/copy"},
+					{UnarchiveSource: libDir + "/lib.tar.gz", UnarchiveOutput: "{{.OutputDir}}/unpacked"},
+					{Exec: []string{"cat", "{{.OutputDir}}/copy/liba.so", "{{.OutputDir}}/unpacked/liba.so"}},
+					{Exec: []string{"readlink", "{{.OutputDir}}/unpacked/liba.so"}},
+				},
+			},
+		},
+	}
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	consume := recorder.Jobs[build.ID{'b'}]
+	require.NotNil(t, consume)
+	assert.Equal(t, "AAliba.so.1\n", consume.Stdout)
+}
+
+func TestNativeCmdsStayInsideOutputDir(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	escape := filepath.Join(env.RootDir, "escape")
+	for i, cmd := range []build.Cmd{
+		{Mkdir: escape},
+		{CopySource: "{{.OutputDir}}", CopyOutput: escape},
+		{CopySource: "{{.SourceDir}}", CopyOutput: "{{.OutputDir}}/../escape"},
+		{SymlinkTarget: "../escape", SymlinkOutput: "{{.OutputDir}}/link"},
+		{SymlinkTarget: escape, SymlinkOutput: "{{.OutputDir}}/link"},
+		{ArchiveSource: "{{.OutputDir}}", ArchiveOutput: escape + ".tar"},
+	} {
+		job := build.Job{
+			ID:   build.ID{'a', byte(i)},
+			Name: "escape",
+			Cmds: []build.Cmd{{Mkdir: "{{.OutputDir}}/dir"}, cmd},
+		}
+
+		recorder := NewRecorder()
+		require.Error(t, env.Client.Build(env.Ctx, build.Graph{Jobs: []build.Job{job}}, recorder), "%+v", cmd)
+		assert.Contains(t, recorder.Jobs[job.ID].Error, "outside of the output directory", "%+v", cmd)
+	}
+
+	_, err := os.Lstat(escape)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Lstat(escape + ".tar")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestSandboxCatStaysInsideOutputDir(t *testing.T) {
+	requireUserNamespaces(t)
+
+	env := newEnv(t, &Config{
+		WorkerCount: 1,
+		Workers:     []worker.Config{{Sandbox: true}},
+	})
+
+	escape := filepath.Join(env.RootDir, "escape")
+	for i, cmds := range [][]build.Cmd{
+		{{CatTemplate: "KO", CatOutput: escape}},
+		{{CatTemplate: "KO", CatOutput: "{{.OutputDir}}/../escape"}},
+		{
+			// Cat runs in the worker process, so the symlink planted by the job must not redirect it.
+			{Exec: []string{"ln", "-s", env.RootDir, "{{.OutputDir}}/link"}},
+			{CatTemplate: "KO", CatOutput: "{{.OutputDir}}/link/escape"},
+		},
+	} {
+		job := build.Job{
+			ID:   build.ID{'c', byte(i)},
+			Name: "escape",
+			Cmds: cmds,
+		}
+
+		recorder := NewRecorder()
+		require.Error(t, env.Client.Build(env.Ctx, build.Graph{Jobs: []build.Job{job}}, recorder), "%+v", cmds)
+		assert.Contains(t, recorder.Jobs[job.ID].Error, "outside of the output directory", "%+v", cmds)
+	}
+
+	_, err := os.Lstat(escape)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestSymlinkChainStaysInsideOutputDir(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	// Each link is local by the path as written, but a/l is really created in the output directory itself.
+	job := build.Job{
+		ID:   build.ID{'s'},
+		Name: "escape",
+		Cmds: []build.Cmd{
+			{SymlinkTarget: ".", SymlinkOutput: "{{.OutputDir}}/a"},
+			{SymlinkTarget: "../escape", SymlinkOutput: "{{.OutputDir}}/a/l"},
+		},
+	}
+
+	recorder := NewRecorder()
+	require.Error(t, env.Client.Build(env.Ctx, build.Graph{Jobs: []build.Job{job}}, recorder))
+	assert.Contains(t, recorder.Jobs[job.ID].Error, "points outside of the output directory")
+}
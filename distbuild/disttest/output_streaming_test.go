@@ -0,0 +1,65 @@
+package disttest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// streamingRecorder unblocks the job, once the first line of its output is received.
+type streamingRecorder struct {
+	*Recorder
+
+	signalFile string
+	streamed   bool
+}
+
+func (r *streamingRecorder) OnJobStdout(jobID build.ID, stdout []byte) error {
+	if !r.streamed {
+		r.streamed = true
+		if err := os.WriteFile(r.signalFile, nil, 0666); err != nil {
+			return err
+		}
+	}
+	return r.Recorder.OnJobStdout(jobID, stdout)
+}
+
+func TestJobOutputStreaming(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	signalFile := filepath.Join(env.RootDir, "signal")
+	script := fmt.Sprintf(`
+echo first
+echo warning >&2
+for i in $(seq 50); do
+	[ -f %[1]s ] && break
+	sleep 0.1
+done
+[ -f %[1]s ] || exit 1
+echo second
+`, signalFile)
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "tail",
+				Cmds: []build.Cmd{
+					{Exec: []string{"bash", "-c", script}},
+				},
+			},
+		},
+	}
+
+	recorder := &streamingRecorder{Recorder: NewRecorder(), signalFile: signalFile}
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	assert.True(t, recorder.streamed)
+	assert.Equal(t, &JobResult{Stdout: "first\nsecond\n", Stderr: "warning\n", Code: new(int)}, recorder.Jobs[build.ID{'a'}])
+}
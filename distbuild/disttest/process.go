@@ -0,0 +1,170 @@
+package disttest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+	"gitlab.com/slon/shad-go/distbuild/pkg/worker"
+)
+
+// workerProcess is the worker of the env. Killed worker is started again with the same id and caches,
+// as if its process was restarted.
+type workerProcess struct {
+	name        string
+	dir         string
+	id          api.WorkerID
+	coordinator string
+	config      worker.Config
+	artifacts   artifact.Config
+
+	w       *worker.Worker
+	cache   *artifact.Cache
+	cancel  context.CancelFunc
+	stopped chan struct{}
+	killed  bool
+}
+
+// startWorker opens the caches of the worker and creates the worker.
+func (e *env) startWorker(p *workerProcess) error {
+	fileCache, err := filecache.New(filepath.Join(p.dir, "filecache"))
+	if err != nil {
+		return err
+	}
+
+	artifacts, err := artifact.NewCacheWithConfig(filepath.Join(p.dir, "artifacts"), p.artifacts)
+	if err != nil {
+		return err
+	}
+
+	p.cache = artifacts
+	p.w = worker.NewWithConfig(
+		p.id,
+		p.coordinator,
+		e.Logger.Named(p.name),
+		fileCache,
+		artifacts,
+		p.config,
+	)
+	return nil
+}
+
+// runWorker runs the i-th worker until it is killed or the env is stopped.
+func (e *env) runWorker(i int) {
+	e.workersMu.Lock()
+	defer e.workersMu.Unlock()
+
+	p := e.processes[i]
+	if i < len(e.Workers) {
+		e.Workers[i], e.WorkerCache[i] = p.w, p.cache
+	} else {
+		e.Workers = append(e.Workers, p.w)
+		e.WorkerCache = append(e.WorkerCache, p.cache)
+	}
+
+	var ctx context.Context
+	ctx, p.cancel = context.WithCancel(e.Ctx)
+	p.stopped = make(chan struct{})
+	p.killed = false
+
+	go func(w *worker.Worker, stopped chan struct{}, drained chan struct{}) {
+		defer close(stopped)
+
+		err := w.Run(ctx)
+		if err == nil {
+			close(drained)
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+
+		e.Logger.Fatal("worker stopped", zap.Error(err))
+	}(p.w, p.stopped, e.WorkerDrained[i])
+}
+
+// KillWorker crashes the i-th worker. Jobs of the worker are interrupted, and the worker no longer
+// serves its artifacts. KillWorker returns once the worker is stopped.
+func (e *env) KillWorker(i int) {
+	e.workersMu.Lock()
+	p := e.processes[i]
+	if p.killed {
+		e.workersMu.Unlock()
+		return
+	}
+	p.killed = true
+	p.cancel()
+	stopped := p.stopped
+	e.workersMu.Unlock()
+
+	e.Logger.Info("worker killed", zap.String("worker_id", p.id.String()))
+	<-stopped
+}
+
+// isKilled reports whether the i-th worker is killed.
+func (e *env) isKilled(i int) bool {
+	e.workersMu.Lock()
+	defer e.workersMu.Unlock()
+
+	return e.processes[i].killed
+}
+
+// RestartWorker starts the killed worker again with the same id and caches.
+func (e *env) RestartWorker(i int) error {
+	e.workersMu.Lock()
+	p := e.processes[i]
+	killed := p.killed
+	e.workersMu.Unlock()
+
+	if !killed {
+		return errors.New("worker is running")
+	}
+
+	if err := e.startWorker(p); err != nil {
+		return err
+	}
+
+	e.Logger.Info("worker restarted", zap.String("worker_id", p.id.String()))
+	e.runWorker(i)
+	return nil
+}
+
+// killWorkerFor kills the i-th worker and restarts it after downtime in background.
+func (e *env) killWorkerFor(i int, downtime time.Duration) {
+	e.KillWorker(i)
+
+	go func() {
+		select {
+		case <-time.After(downtime):
+		case <-e.Ctx.Done():
+			return
+		}
+
+		if err := e.RestartWorker(i); err != nil {
+			e.Logger.Fatal("worker restart failed", zap.Error(err))
+		}
+	}()
+}
+
+// workerHandler serves the requests to the i-th worker. Requests to the killed worker fail.
+func (e *env) workerHandler(i int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.workersMu.Lock()
+		p := e.processes[i]
+		killed, handler := p.killed, p.w
+		e.workersMu.Unlock()
+
+		if killed {
+			http.Error(w, "worker is killed", http.StatusServiceUnavailable)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
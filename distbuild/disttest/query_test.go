@@ -0,0 +1,78 @@
+package disttest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/client"
+)
+
+func TestQueryCache(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	echo := build.Job{
+		ID:   build.ID{'a'},
+		Name: "echo",
+		Cmds: []build.Cmd{{Exec: []string{"echo", "OK"}}},
+	}
+	graph := build.Graph{Jobs: []build.Job{echo}}
+
+	missing, err := env.Client.Query(env.Ctx, graph, NewRecorder())
+	require.NoError(t, err)
+	require.Equal(t, []build.ID{echo.ID}, missing)
+
+	require.NoError(t, env.Client.Build(env.Ctx, graph, NewRecorder()))
+
+	// Query does not run the jobs missing from the cache.
+	graph.Jobs = append(graph.Jobs, build.Job{
+		ID:   build.ID{'b'},
+		Name: "fail",
+		Deps: []build.ID{echo.ID},
+		Cmds: []build.Cmd{{Exec: []string{"false"}}},
+	})
+
+	recorder := NewRecorder()
+	missing, err = env.Client.Query(env.Ctx, graph, recorder)
+	require.NoError(t, err)
+	require.Equal(t, []build.ID{{'b'}}, missing)
+
+	assert.Equal(t, map[build.ID]*JobResult{
+		echo.ID: {Stdout: "OK\n", Code: new(int)},
+	}, recorder.Jobs)
+}
+
+func TestQueryDownloadsOutputs(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	job := build.Job{
+		ID:   build.ID{'a'},
+		Name: "write",
+		Cmds: []build.Cmd{{CatTemplate: "OK", CatOutput: "{{.OutputDir}}/out.txt"}},
+	}
+	graph := build.Graph{Jobs: []build.Job{job}}
+	require.NoError(t, env.Client.Build(env.Ctx, graph, NewRecorder()))
+
+	outputs, err := artifact.NewCache(filepath.Join(env.RootDir, "outputs"))
+	require.NoError(t, err)
+
+	// Repeated query keeps the outputs downloaded by the first one.
+	for range 2 {
+		missing, err := env.Client.QueryWithOptions(env.Ctx, graph, NewRecorder(), client.QueryOptions{Outputs: outputs})
+		require.NoError(t, err)
+		require.Empty(t, missing)
+	}
+
+	path, unlock, err := outputs.Get(job.ID)
+	require.NoError(t, err)
+	defer unlock()
+
+	content, err := os.ReadFile(filepath.Join(path, "out.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "OK", string(content))
+}
@@ -0,0 +1,60 @@
+package disttest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/worker"
+)
+
+var threeLabeledWorkersConfig = &Config{
+	WorkerCount: 3,
+	Workers: []worker.Config{
+		{Labels: []string{"first"}},
+		{Labels: []string{"second"}},
+		{Labels: []string{"third"}},
+	},
+}
+
+func TestCorruptedReplicaIsSkipped(t *testing.T) {
+	env := newEnv(t, threeLabeledWorkersConfig)
+
+	produce := build.Job{
+		ID:     build.ID{'a'},
+		Name:   "produce",
+		Labels: []string{"first"},
+		Cmds:   []build.Cmd{{CatTemplate: "OK", CatOutput: "{{.OutputDir}}/out.txt"}},
+	}
+	consume := func(id byte, label string) build.Job {
+		return build.Job{
+			ID:     build.ID{id},
+			Name:   "consume " + label,
+			Labels: []string{label},
+			Deps:   []build.ID{produce.ID},
+			Cmds:   []build.Cmd{{Exec: []string{"cat", fmt.Sprintf("{{index .Deps %q}}/out.txt", produce.ID)}}},
+		}
+	}
+
+	// The second worker copies the artifact, so that both the first and the second worker hold it.
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, build.Graph{Jobs: []build.Job{produce, consume('b', "second")}}, recorder))
+
+	dir, unlock, err := env.WorkerCache[0].Get(produce.ID)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "out.txt"), []byte("KO"), 0666))
+	unlock()
+
+	// Locations are sorted, the third worker tries the corrupted replica of the first worker first.
+	recorder = NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, build.Graph{Jobs: []build.Job{produce, consume('c', "third")}}, recorder))
+
+	result := recorder.Jobs[build.ID{'c'}]
+	require.NotNil(t, result)
+	assert.Equal(t, "OK", result.Stdout)
+}
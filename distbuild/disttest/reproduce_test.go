@@ -0,0 +1,123 @@
+package disttest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/client"
+)
+
+// reproducedRecorder remembers results of the reproducibility checks.
+type reproducedRecorder struct {
+	*Recorder
+
+	Reproduced map[build.ID]*api.JobReproduced
+}
+
+func (r *reproducedRecorder) OnJobReproduced(rep *api.JobReproduced) error {
+	r.Reproduced[rep.ID] = rep
+	return nil
+}
+
+func reproduceGraph() build.Graph {
+	return build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "hermetic",
+				Cmds: []build.Cmd{
+					{CatTemplate: "OK", CatOutput: "{{.OutputDir}}/out.txt"},
+				},
+			},
+			{
+				ID:   build.ID{'b'},
+				Name: "stamp",
+				Cmds: []build.Cmd{
+					{CatTemplate: "OK", CatOutput: "{{.OutputDir}}/out.txt"},
+					{Exec: []string{"bash", "-c", "od -An -x -N16 /dev/urandom > {{.OutputDir}}/stamp.txt"}},
+				},
+				Deps: []build.ID{{'a'}},
+			},
+		},
+	}
+}
+
+func TestReproducibilityCheck(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 2})
+
+	recorder := &reproducedRecorder{Recorder: NewRecorder(), Reproduced: map[build.ID]*api.JobReproduced{}}
+	opts := client.BuildOptions{Reproduce: []build.ID{{'a'}, {'b'}}}
+	require.NoError(t, env.Client.BuildWithOptions(env.Ctx, reproduceGraph(), recorder, opts))
+
+	require.Len(t, recorder.Reproduced, 2)
+
+	hermetic := recorder.Reproduced[build.ID{'a'}]
+	require.NotNil(t, hermetic)
+	assert.Empty(t, hermetic.Error)
+	assert.Empty(t, hermetic.DiffFiles)
+	assert.NotEqual(t, hermetic.Workers[0], hermetic.Workers[1])
+
+	stamp := recorder.Reproduced[build.ID{'b'}]
+	require.NotNil(t, stamp)
+	assert.Empty(t, stamp.Error)
+	assert.Equal(t, []string{"stamp.txt"}, stamp.DiffFiles)
+	assert.NotEqual(t, stamp.Workers[0], stamp.Workers[1])
+
+	requireGraphArtifactsOnly(t, env)
+}
+
+// requireGraphArtifactsOnly waits until artifacts of the second runs are removed, and only the artifacts
+// of the graph jobs stay in the caches.
+func requireGraphArtifactsOnly(t *testing.T, env *env) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		for _, cache := range env.WorkerCache {
+			err := cache.Range(func(id build.ID) error {
+				if id != (build.ID{'a'}) && id != (build.ID{'b'}) {
+					return fmt.Errorf("unexpected artifact %v", id)
+				}
+				return nil
+			})
+			if err != nil {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestReproducibilityCheckRemovalSurvivesRestart(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 2, DurableCoordinator: true})
+
+	recorder := &reproducedRecorder{Recorder: NewRecorder(), Reproduced: map[build.ID]*api.JobReproduced{}}
+	opts := client.BuildOptions{Reproduce: []build.ID{{'a'}}}
+	require.NoError(t, env.Client.BuildWithOptions(env.Ctx, reproduceGraph(), recorder, opts))
+	require.Contains(t, recorder.Reproduced, build.ID{'a'})
+
+	// Workers stop before the heartbeat asking to remove the artifact of the second run.
+	env.KillWorker(0)
+	env.KillWorker(1)
+	env.RestartCoordinator(t)
+	require.NoError(t, env.RestartWorker(0))
+	require.NoError(t, env.RestartWorker(1))
+
+	requireGraphArtifactsOnly(t, env)
+}
+
+func TestReproducibilityCheckSingleWorker(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	recorder := &reproducedRecorder{Recorder: NewRecorder(), Reproduced: map[build.ID]*api.JobReproduced{}}
+	opts := client.BuildOptions{Reproduce: []build.ID{{'a'}}}
+	require.NoError(t, env.Client.BuildWithOptions(env.Ctx, reproduceGraph(), recorder, opts))
+
+	require.Contains(t, recorder.Reproduced, build.ID{'a'})
+	assert.NotEmpty(t, recorder.Reproduced[build.ID{'a'}].Error)
+}
@@ -0,0 +1,107 @@
+package disttest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/worker"
+)
+
+const gigabyte = 1 << 30
+
+// workersHolding returns indices of the workers, which caches contain the artifact.
+func workersHolding(env *env, id build.ID) []int {
+	var workers []int
+	for i, cache := range env.WorkerCache {
+		if _, unlock, err := cache.Get(id); err == nil {
+			unlock()
+			workers = append(workers, i)
+		}
+	}
+	return workers
+}
+
+func TestJobPlacement(t *testing.T) {
+	env := newEnv(t, &Config{
+		WorkerCount: 3,
+		Workers: []worker.Config{
+			{Resources: &build.Resources{MilliCPU: 4000, Memory: gigabyte}},
+			{Resources: &build.Resources{MilliCPU: 4000, Memory: 8 * gigabyte}},
+			{Resources: &build.Resources{MilliCPU: 4000, Memory: gigabyte}, Labels: []string{"has-docker"}},
+		},
+	})
+
+	var graph build.Graph
+	for i := 0; i < 3; i++ {
+		graph.Jobs = append(graph.Jobs,
+			build.Job{
+				ID:        build.ID{'m', byte(i)},
+				Name:      fmt.Sprintf("link %d", i),
+				Resources: build.Resources{MilliCPU: 1000, Memory: 4 * gigabyte},
+				Cmds:      []build.Cmd{{Exec: []string{"true"}}},
+			},
+			build.Job{
+				ID:     build.ID{'d', byte(i)},
+				Name:   fmt.Sprintf("docker %d", i),
+				Labels: []string{"has-docker"},
+				Cmds:   []build.Cmd{{Exec: []string{"true"}}},
+			},
+		)
+	}
+
+	require.NoError(t, env.Client.Build(env.Ctx, graph, NewRecorder()))
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, []int{1}, workersHolding(env, build.ID{'m', byte(i)}))
+		assert.Equal(t, []int{2}, workersHolding(env, build.ID{'d', byte(i)}))
+	}
+}
+
+func TestJobBinPacking(t *testing.T) {
+	env := newEnv(t, &Config{
+		WorkerCount: 1,
+		Workers: []worker.Config{
+			{Slots: 4, Resources: &build.Resources{MilliCPU: 2000, Memory: gigabyte}},
+		},
+	})
+
+	runningDir := filepath.Join(env.RootDir, "running")
+	require.NoError(t, os.Mkdir(runningDir, 0777))
+	logFile := filepath.Join(env.RootDir, "log")
+
+	var graph build.Graph
+	for i := 0; i < 3; i++ {
+		marker := filepath.Join(runningDir, strconv.Itoa(i))
+		script := fmt.Sprintf("touch %s; ls %s | wc -l >> %s; sleep 1; rm %s", marker, runningDir, logFile, marker)
+
+		graph.Jobs = append(graph.Jobs, build.Job{
+			ID:        build.ID{'a', byte(i)},
+			Name:      fmt.Sprintf("sleep %d", i),
+			Resources: build.Resources{MilliCPU: 1000, Memory: 100 << 20},
+			Cmds:      []build.Cmd{{Exec: []string{"bash", "-c", script}}},
+		})
+	}
+
+	require.NoError(t, env.Client.Build(env.Ctx, graph, NewRecorder()))
+
+	log, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+
+	maxRunning := 0
+	for _, line := range strings.Fields(string(log)) {
+		n, err := strconv.Atoi(line)
+		require.NoError(t, err)
+		maxRunning = max(maxRunning, n)
+	}
+
+	// Two jobs fill the worker CPU, the third one waits for a free core.
+	assert.Equal(t, 2, maxRunning, "%s", log)
+}
@@ -0,0 +1,129 @@
+package disttest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// flakyGraph runs the job failing with exit code 1 until it is started the given number of times.
+//
+// Every start appends a line to the counter file.
+func flakyGraph(counterFile string, failures int, job build.Job) build.Graph {
+	script := fmt.Sprintf(`echo run >> %[1]s; n=$(wc -l < %[1]s); echo "attempt $n"; [ "$n" -gt %[2]d ]`, counterFile, failures)
+
+	job.ID = build.ID{'a'}
+	job.Name = "flaky"
+	job.Cmds = []build.Cmd{{Exec: []string{"bash", "-c", script}}}
+	return build.Graph{Jobs: []build.Job{job}}
+}
+
+func readRuns(t *testing.T, counterFile string) int {
+	content, err := os.ReadFile(counterFile)
+	require.NoError(t, err)
+	return strings.Count(string(content), "\n")
+}
+
+func TestJobTimeout(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:      build.ID{'a'},
+				Name:    "sleep",
+				Timeout: 200 * time.Millisecond,
+				Cmds: []build.Cmd{
+					{Exec: []string{"sleep", "10"}},
+				},
+			},
+		},
+	}
+
+	start := time.Now()
+	recorder := NewRecorder()
+	require.Error(t, env.Client.Build(env.Ctx, graph, recorder))
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	job := recorder.Jobs[build.ID{'a'}]
+	require.NotNil(t, job)
+	assert.Contains(t, job.Error, "timed out")
+}
+
+func TestJobRetryOnExitCode(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	counterFile := filepath.Join(env.RootDir, "counter")
+	graph := flakyGraph(counterFile, 2, build.Job{Retries: 2, RetryOnExitCode: true})
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	assert.Equal(t, 3, readRuns(t, counterFile))
+
+	job := recorder.Jobs[build.ID{'a'}]
+	assert.Equal(t, 0, *job.Code)
+	assert.True(t, strings.HasSuffix(job.Stdout, "attempt 3\n"), job.Stdout)
+}
+
+func TestJobRetriesExhausted(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 3})
+
+	counterFile := filepath.Join(env.RootDir, "counter")
+	graph := flakyGraph(counterFile, 5, build.Job{Retries: 1, RetryOnExitCode: true})
+
+	recorder := NewRecorder()
+	require.Error(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	assert.Equal(t, 2, readRuns(t, counterFile))
+	assert.Equal(t, 1, *recorder.Jobs[build.ID{'a'}].Code)
+}
+
+func TestJobExitCodeNotRetriedByDefault(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	counterFile := filepath.Join(env.RootDir, "counter")
+	graph := flakyGraph(counterFile, 1, build.Job{Retries: 2})
+
+	recorder := NewRecorder()
+	require.Error(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	assert.Equal(t, 1, readRuns(t, counterFile))
+	assert.Equal(t, 1, *recorder.Jobs[build.ID{'a'}].Code)
+}
+
+func TestJobTimeoutRetried(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 3})
+
+	// The first attempt hangs, the second one finishes right away.
+	counterFile := filepath.Join(env.RootDir, "counter")
+	script := fmt.Sprintf(`echo run >> %[1]s; [ "$(wc -l < %[1]s)" -gt 1 ] || sleep 10`, counterFile)
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:      build.ID{'a'},
+				Name:    "hang once",
+				Timeout: 200 * time.Millisecond,
+				Retries: 1,
+				Cmds: []build.Cmd{
+					{Exec: []string{"bash", "-c", script}},
+				},
+			},
+		},
+	}
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	assert.Equal(t, 2, readRuns(t, counterFile))
+	assert.Equal(t, &JobResult{Code: new(int)}, recorder.Jobs[build.ID{'a'}])
+}
@@ -0,0 +1,125 @@
+package disttest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/worker"
+)
+
+func requireUserNamespaces(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("sandbox is supported only on linux")
+	}
+
+	limit, err := os.ReadFile("/proc/sys/user/max_user_namespaces")
+	if err != nil || strings.TrimSpace(string(limit)) == "0" {
+		t.Skip("user namespaces are disabled")
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	requireUserNamespaces(t)
+
+	env := newEnv(t, &Config{
+		WorkerCount: 1,
+		Workers:     []worker.Config{{Sandbox: true}},
+	})
+
+	secret := filepath.Join(env.RootDir, "secret")
+	require.NoError(t, os.WriteFile(secret, []byte("secret"), 0666))
+
+	script := fmt.Sprintf(`
+		echo OK > {{.OutputDir}}/out.txt
+		touch {{.SourceDir}}/x 2>/dev/null && echo sources are writable
+		cat %s 2>/dev/null && echo host is visible
+		env`, secret)
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "inspect",
+				Cmds: []build.Cmd{
+					{Exec: []string{"bash", "-c", script}, Environ: []string{"FOO=bar"}},
+				},
+			},
+			{
+				ID:   build.ID{'b'},
+				Name: "read dep",
+				Deps: []build.ID{{'a'}},
+				Cmds: []build.Cmd{
+					{Exec: []string{"cat", fmt.Sprintf("{{index .Deps %q}}/out.txt", build.ID{'a'})}},
+					{Exec: []string{"bash", "-c", fmt.Sprintf("echo > {{index .Deps %q}}/out.txt", build.ID{'a'})}},
+				},
+			},
+		},
+	}
+
+	recorder := NewRecorder()
+	require.Error(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	inspect := recorder.Jobs[build.ID{'a'}]
+	require.NotNil(t, inspect)
+	assert.Equal(t, 0, *inspect.Code, inspect.Stderr)
+
+	// bash adds its own variables to the environment.
+	assert.Contains(t, inspect.Stdout, "FOO=bar\n")
+	assert.NotContains(t, inspect.Stdout, "sources are writable")
+	assert.NotContains(t, inspect.Stdout, "host is visible")
+	assert.NotContains(t, inspect.Stdout, "HOME=")
+
+	// Dependency is readable, but not writable.
+	readDep := recorder.Jobs[build.ID{'b'}]
+	require.NotNil(t, readDep)
+	assert.Equal(t, "OK\n", readDep.Stdout)
+	assert.NotEqual(t, 0, *readDep.Code)
+}
+
+func TestSandboxNativeCmdInputs(t *testing.T) {
+	requireUserNamespaces(t)
+
+	env := newEnv(t, &Config{
+		WorkerCount: 1,
+		Workers:     []worker.Config{{Sandbox: true}},
+	})
+
+	secret := filepath.Join(env.RootDir, "secret")
+	require.NoError(t, os.WriteFile(secret, []byte("secret"), 0666))
+
+	// Commands executed by the worker itself see only the inputs of the job, as the sandboxed processes do.
+	// Symlinks planted by the job are resolved by the worker, so they must not widen the inputs.
+	for i, cmds := range [][]build.Cmd{
+		{{CopySource: secret, CopyOutput: "{{.OutputDir}}/secret"}},
+		{
+			{Exec: []string{"ln", "-s", "/", "{{.OutputDir}}/link"}},
+			{CopySource: "{{.OutputDir}}/link" + secret, CopyOutput: "{{.OutputDir}}/secret"},
+		},
+		{
+			{Exec: []string{"ln", "-s", "/", "{{.OutputDir}}/link"}},
+			{ArchiveSource: "{{.OutputDir}}/link" + env.RootDir, ArchiveOutput: "{{.OutputDir}}/root.tar"},
+		},
+		{
+			{Exec: []string{"ln", "-s", secret, "{{.OutputDir}}/secret.tar"}},
+			{UnarchiveSource: "{{.OutputDir}}/secret.tar", UnarchiveOutput: "{{.OutputDir}}/secret"},
+		},
+	} {
+		job := build.Job{
+			ID:   build.ID{'a', byte(i)},
+			Name: "copy secret",
+			Cmds: cmds,
+		}
+
+		recorder := NewRecorder()
+		require.Error(t, env.Client.Build(env.Ctx, build.Graph{Jobs: []build.Job{job}}, recorder), "%+v", cmds)
+		assert.Contains(t, recorder.Jobs[job.ID].Error, "is not an input of the job", "%+v", cmds)
+	}
+}
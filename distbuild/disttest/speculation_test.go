@@ -0,0 +1,42 @@
+package disttest
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+func TestSpeculativeExecution(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 2, SpeculationFactor: 2})
+
+	// First build records the usual duration of the compile job.
+	fast := build.Job{
+		ID:   build.ID{'a'},
+		Name: "compile",
+		Cmds: []build.Cmd{{Exec: []string{"true"}}},
+	}
+	require.NoError(t, env.Client.Build(env.Ctx, build.Graph{Jobs: []build.Job{fast}}, NewRecorder()))
+
+	// The copy started first hangs, the speculative copy finishes immediately.
+	lock := filepath.Join(t.TempDir(), "lock")
+	slow := build.Job{
+		ID:   build.ID{'b'},
+		Name: "compile",
+		Cmds: []build.Cmd{
+			{Exec: []string{"bash", "-c", fmt.Sprintf("mkdir %s 2>/dev/null && sleep 5; echo OK", lock)}}, // No-hermetic, for testing purposes.
+		},
+	}
+
+	start := time.Now()
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, build.Graph{Jobs: []build.Job{slow}}, recorder))
+	require.Less(t, time.Since(start), 4*time.Second)
+
+	require.Equal(t, &JobResult{Stdout: "OK\n", Code: new(int)}, recorder.Jobs[slow.ID])
+	require.Len(t, workersHolding(env, slow.ID), 1)
+}
@@ -0,0 +1,12 @@
+package greet_test
+
+import (
+	"fmt"
+
+	"example.com/hello/greet"
+)
+
+func ExampleHello() {
+	fmt.Println(greet.Hello("example"))
+	// Output: Hello, example!
+}
@@ -0,0 +1,91 @@
+package disttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/client"
+	"gitlab.com/slon/shad-go/distbuild/pkg/worker"
+)
+
+type traceEvent struct {
+	Name  string         `json:"name"`
+	Cat   string         `json:"cat"`
+	Ph    string         `json:"ph"`
+	Ts    float64        `json:"ts"`
+	Dur   float64        `json:"dur"`
+	Pid   int            `json:"pid"`
+	Tid   int            `json:"tid"`
+	Color string         `json:"cname"`
+	Args  map[string]any `json:"args"`
+}
+
+func TestBuildTrace(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 1, Workers: []worker.Config{{Slots: 2}}})
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "compile",
+				Cmds: []build.Cmd{{Exec: []string{"sleep", "0.5"}}},
+			},
+			{
+				ID:   build.ID{'b'},
+				Name: "link",
+				Deps: []build.ID{{'a'}},
+				Cmds: []build.Cmd{{Exec: []string{"sleep", "0.2"}}},
+			},
+			{
+				ID:   build.ID{'c'},
+				Name: "vet",
+				// Sleeps as long as compile, so that the independent jobs overlap even on a slow machine.
+				Cmds: []build.Cmd{{Exec: []string{"sleep", "0.5"}}},
+			},
+		},
+	}
+
+	trace := client.NewTrace(graph)
+	require.NoError(t, env.Client.BuildWithOptions(env.Ctx, graph, NewRecorder(), client.BuildOptions{Trace: trace}))
+
+	var buf bytes.Buffer
+	require.NoError(t, trace.Write(&buf))
+
+	var file struct {
+		TraceEvents []traceEvent `json:"traceEvents"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &file))
+
+	jobs := map[string]traceEvent{}
+	processes := map[int]string{}
+	slots := map[int]bool{}
+	queued := 0
+	for _, e := range file.TraceEvents {
+		switch {
+		case e.Ph == "M" && e.Name == "process_name":
+			processes[e.Pid] = e.Args["name"].(string)
+		case e.Ph == "X" && e.Cat == "job":
+			jobs[e.Name] = e
+			slots[e.Tid] = true
+		case e.Ph == "b" && e.Cat == "queue":
+			queued++
+		}
+	}
+
+	require.Len(t, jobs, 3)
+	assert.Equal(t, 3, queued)
+	assert.Len(t, slots, 2, "independent jobs run in different slots")
+
+	for _, name := range []string{"compile", "link"} {
+		assert.Equal(t, env.WorkerEndpoints[0], processes[jobs[name].Pid])
+		assert.GreaterOrEqual(t, jobs[name].Dur, 200e3, name)
+		assert.Equal(t, "terrible", jobs[name].Color, "%s is on the critical path", name)
+	}
+	assert.Empty(t, jobs["vet"].Color)
+	assert.GreaterOrEqual(t, jobs["link"].Ts, jobs["compile"].Ts+jobs["compile"].Dur)
+}
@@ -8,6 +8,24 @@ import (
 
 type BuildRequest struct {
 	Graph build.Graph
+
+	// BuildID, если задан, подключает клиента к уже существующему билду вместо запуска нового.
+	//
+	// Результаты джобов, завершившихся до подключения клиента, присылаются повторно.
+	BuildID *build.ID
+
+	// User задаёт пользователя, от имени которого запущен билд. Координатор делит воркеры
+	// между пользователями поровну или в соответствии с их весами.
+	User string
+
+	// Priority задаёт приоритет билда. Джобы билдов с большим приоритетом выполняются раньше.
+	Priority int
+
+	// Reproduce перечисляет джобы, которые нужно проверить на воспроизводимость.
+	//
+	// После успешного завершения такой джоб запускается ещё раз на другом воркере, и артефакты
+	// двух запусков сравниваются пофайлово. Результат проверки присылается в JobReproduced.
+	Reproduce []build.ID
 }
 
 type BuildStarted struct {
@@ -16,9 +34,44 @@ type BuildStarted struct {
 }
 
 type StatusUpdate struct {
-	JobFinished   *JobResult
-	BuildFailed   *BuildFailed
-	BuildFinished *BuildFinished
+	JobOutput      *JobOutput
+	JobFinished    *JobResult
+	BuildFailed    *BuildFailed
+	BuildFinished  *BuildFinished
+	BuildCancelled *BuildCancelled
+	JobsQueued     *JobsQueued
+	JobReproduced  *JobReproduced
+}
+
+// JobReproduced сообщает результат проверки джоба на воспроизводимость.
+//
+// Билд завершается только после того, как проверены все джобы из BuildRequest.Reproduce.
+// Невоспроизводимый джоб не считается ошибкой билда.
+type JobReproduced struct {
+	ID build.ID
+
+	// Workers содержит воркеры, на которых выполнялись первый и второй запуски джоба.
+	Workers [2]WorkerID
+
+	// DiffFiles перечисляет файлы артефакта, которые различаются между запусками.
+	// Пустой список означает, что джоб воспроизводим.
+	DiffFiles []string
+
+	// Error описывает, почему проверку не удалось выполнить. Например, второй запуск завершился ошибкой.
+	Error string
+}
+
+// JobsQueued сообщает позиции джобов билда, ожидающих свободного воркера.
+//
+// Позиция равна числу ожидающих джобов всех билдов, которые будут выданы воркерам раньше. Позиции
+// приблизительны: воркеры предпочитают джобы, артефакты которых уже есть у них в кеше.
+type JobsQueued struct {
+	Positions map[build.ID]int
+
+	// Unsatisfiable перечисляет ожидающие джобы билда, которые не может выполнить ни один из
+	// зарегистрированных воркеров: ни у одного нет всех меток джоба или достаточно ресурсов.
+	// Такие джобы ждут, пока не подключится подходящий воркер.
+	Unsatisfiable []build.ID
 }
 
 type BuildFailed struct {
@@ -28,15 +81,43 @@ type BuildFailed struct {
 type BuildFinished struct {
 }
 
+// BuildCancelled сообщает, что билд был остановлен сигналом CancelBuild.
+type BuildCancelled struct {
+}
+
 type UploadDone struct{}
 
+// CancelBuild останавливает билд. Джобы билда, которые уже выполняются, прерываются на воркерах.
+type CancelBuild struct{}
+
 type SignalRequest struct {
-	UploadDone *UploadDone
+	UploadDone  *UploadDone
+	CancelBuild *CancelBuild
 }
 
 type SignalResponse struct {
 }
 
+// QueryRequest спрашивает, какие из джобов уже есть в кеше. Джобы не запускаются.
+type QueryRequest struct {
+	Jobs []build.ID
+}
+
+// CachedJob описывает джоб, артефакт которого есть в кеше воркера.
+type CachedJob struct {
+	WorkerID WorkerID
+
+	// Result содержит сохранённый результат джоба. Result == nil, если координатор не знает вывода джоба.
+	Result *JobResult
+}
+
+type QueryResponse struct {
+	Cached map[build.ID]CachedJob
+
+	// Missing перечисляет джобы, которые нужно запустить.
+	Missing []build.ID
+}
+
 type StatusWriter interface {
 	Started(rsp *BuildStarted) error
 	Updated(update *StatusUpdate) error
@@ -45,6 +126,7 @@ type StatusWriter interface {
 type Service interface {
 	StartBuild(ctx context.Context, request *BuildRequest, w StatusWriter) error
 	SignalBuild(ctx context.Context, buildID build.ID, signal *SignalRequest) (*SignalResponse, error)
+	QueryCache(ctx context.Context, request *QueryRequest) (*QueryResponse, error)
 }
 
 type StatusReader interface {
@@ -3,7 +3,14 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
 
 	"go.uber.org/zap"
 
@@ -11,16 +18,143 @@ import (
 )
 
 type BuildClient struct {
+	l        *zap.Logger
+	endpoint string
+	client   *http.Client
 }
 
 func NewBuildClient(l *zap.Logger, endpoint string) *BuildClient {
-	panic("implement me")
+	return NewBuildClientWithHTTPClient(l, endpoint, http.DefaultClient)
+}
+
+// NewBuildClientWithHTTPClient creates client sending requests through c, for example to present credentials.
+func NewBuildClientWithHTTPClient(l *zap.Logger, endpoint string, c *http.Client) *BuildClient {
+	return &BuildClient{l: l, endpoint: endpoint, client: c}
+}
+
+// readError converts non-200 response into error.
+func readError(rsp *http.Response) error {
+	body, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return fmt.Errorf("http status %d: %w", rsp.StatusCode, err)
+	}
+	return fmt.Errorf("http status %d: %s", rsp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+type statusReader struct {
+	body io.ReadCloser
+	dec  *json.Decoder
+}
+
+func (r *statusReader) Close() error {
+	return r.body.Close()
+}
+
+func (r *statusReader) Next() (*StatusUpdate, error) {
+	var update StatusUpdate
+	if err := r.dec.Decode(&update); err != nil {
+		return nil, err
+	}
+	return &update, nil
 }
 
 func (c *BuildClient) StartBuild(ctx context.Context, request *BuildRequest) (*BuildStarted, StatusReader, error) {
-	panic("implement me")
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/build", bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	c.l.Debug("starting build", zap.Int("jobs", len(request.Graph.Jobs)))
+
+	rsp, err := c.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if rsp.StatusCode != http.StatusOK {
+		defer rsp.Body.Close()
+		err = readError(rsp)
+		c.l.Error("start build failed", zap.Error(err))
+		return nil, nil, err
+	}
+
+	r := &statusReader{body: rsp.Body, dec: json.NewDecoder(rsp.Body)}
+
+	var started BuildStarted
+	if err := r.dec.Decode(&started); err != nil {
+		_ = r.Close()
+		return nil, nil, fmt.Errorf("failed to read build started message: %w", err)
+	}
+
+	c.l.Debug("build started", zap.String("build_id", started.ID.String()))
+	return &started, r, nil
 }
 
 func (c *BuildClient) SignalBuild(ctx context.Context, buildID build.ID, signal *SignalRequest) (*SignalResponse, error) {
-	panic("implement me")
+	body, err := json.Marshal(signal)
+	if err != nil {
+		return nil, err
+	}
+
+	u := c.endpoint + "/signal?build_id=" + url.QueryEscape(buildID.String())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		err = readError(rsp)
+		c.l.Error("signal build failed", zap.String("build_id", buildID.String()), zap.Error(err))
+		return nil, err
+	}
+
+	var signalRsp SignalResponse
+	if err := json.NewDecoder(rsp.Body).Decode(&signalRsp); err != nil {
+		return nil, err
+	}
+	return &signalRsp, nil
+}
+
+func (c *BuildClient) QueryCache(ctx context.Context, request *QueryRequest) (*QueryResponse, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/query", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		err = readError(rsp)
+		c.l.Error("cache query failed", zap.Error(err))
+		return nil, err
+	}
+
+	var queryRsp QueryResponse
+	if err := json.NewDecoder(rsp.Body).Decode(&queryRsp); err != nil {
+		return nil, err
+	}
+	return &queryRsp, nil
 }
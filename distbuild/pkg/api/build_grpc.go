@@ -0,0 +1,216 @@
+//go:build !solution
+
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api/apipb"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// BuildGRPCServer serves Service over gRPC. It is the gRPC counterpart of BuildHandler.
+type BuildGRPCServer struct {
+	apipb.UnimplementedBuildServer
+
+	l *zap.Logger
+	s Service
+}
+
+func NewBuildGRPCServer(l *zap.Logger, s Service) *BuildGRPCServer {
+	return &BuildGRPCServer{l: l, s: s}
+}
+
+func (h *BuildGRPCServer) Register(s grpc.ServiceRegistrar) {
+	apipb.RegisterBuildServer(s, h)
+}
+
+type grpcStatusWriter struct {
+	stream  apipb.Build_StartBuildServer
+	started bool
+}
+
+func (w *grpcStatusWriter) Started(rsp *BuildStarted) error {
+	w.started = true
+	return w.stream.Send(&apipb.BuildEvent{
+		Event: &apipb.BuildEvent_Started{Started: buildStartedToProto(rsp)},
+	})
+}
+
+func (w *grpcStatusWriter) Updated(update *StatusUpdate) error {
+	if !w.started {
+		return fmt.Errorf("status update before build started")
+	}
+
+	return w.stream.Send(&apipb.BuildEvent{
+		Event: &apipb.BuildEvent_Update{Update: statusUpdateToProto(update)},
+	})
+}
+
+func (h *BuildGRPCServer) StartBuild(pb *apipb.BuildRequest, stream apipb.Build_StartBuildServer) error {
+	var d protoDecoder
+	req := d.buildRequest(pb)
+	if d.err != nil {
+		h.l.Error("failed to decode build request", zap.Error(d.err))
+		return status.Error(codes.InvalidArgument, d.err.Error())
+	}
+
+	h.l.Debug("build request received", zap.Int("jobs", len(req.Graph.Jobs)))
+
+	sw := &grpcStatusWriter{stream: stream}
+	err := h.s.StartBuild(stream.Context(), req, sw)
+	if err == nil {
+		return nil
+	}
+
+	h.l.Error("build failed", zap.Error(err))
+	if !sw.started {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	if err := sw.Updated(&StatusUpdate{BuildFailed: &BuildFailed{Error: err.Error()}}); err != nil {
+		h.l.Warn("failed to send build error", zap.Error(err))
+	}
+	return nil
+}
+
+func (h *BuildGRPCServer) SignalBuild(ctx context.Context, pb *apipb.SignalBuildRequest) (*apipb.SignalResponse, error) {
+	var d protoDecoder
+	buildID := d.id(pb.GetBuildId())
+	signal := d.signal(pb.GetSignal())
+	if d.err != nil {
+		return nil, status.Error(codes.InvalidArgument, d.err.Error())
+	}
+
+	h.l.Debug("signal received", zap.String("build_id", buildID.String()))
+
+	if _, err := h.s.SignalBuild(ctx, buildID, signal); err != nil {
+		h.l.Error("signal failed", zap.String("build_id", buildID.String()), zap.Error(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &apipb.SignalResponse{}, nil
+}
+
+func (h *BuildGRPCServer) QueryCache(ctx context.Context, pb *apipb.QueryRequest) (*apipb.QueryResponse, error) {
+	var d protoDecoder
+	req := &QueryRequest{Jobs: d.ids(pb.GetJobs())}
+	if d.err != nil {
+		return nil, status.Error(codes.InvalidArgument, d.err.Error())
+	}
+
+	h.l.Debug("cache query received", zap.Int("jobs", len(req.Jobs)))
+
+	rsp, err := h.s.QueryCache(ctx, req)
+	if err != nil {
+		h.l.Error("cache query failed", zap.Error(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return queryResponseToProto(rsp), nil
+}
+
+// BuildGRPCClient is the gRPC counterpart of BuildClient.
+type BuildGRPCClient struct {
+	l      *zap.Logger
+	client apipb.BuildClient
+}
+
+// NewBuildGRPCClient creates client of the coordinator. Credentials and the endpoint are configured on cc.
+func NewBuildGRPCClient(l *zap.Logger, cc grpc.ClientConnInterface) *BuildGRPCClient {
+	return &BuildGRPCClient{l: l, client: apipb.NewBuildClient(cc)}
+}
+
+type grpcStatusReader struct {
+	stream apipb.Build_StartBuildClient
+	cancel context.CancelFunc
+}
+
+func (r *grpcStatusReader) Close() error {
+	r.cancel()
+	return nil
+}
+
+func (r *grpcStatusReader) Next() (*StatusUpdate, error) {
+	event, err := r.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+
+	if event.GetUpdate() == nil {
+		return nil, fmt.Errorf("unexpected build event %T", event.GetEvent())
+	}
+
+	var d protoDecoder
+	update := d.statusUpdate(event.GetUpdate())
+	if d.err != nil {
+		return nil, d.err
+	}
+	return update, nil
+}
+
+func (c *BuildGRPCClient) StartBuild(ctx context.Context, request *BuildRequest) (*BuildStarted, StatusReader, error) {
+	c.l.Debug("starting build", zap.Int("jobs", len(request.Graph.Jobs)))
+
+	// Stream lives until the reader is closed.
+	ctx, cancel := context.WithCancel(ctx)
+
+	stream, err := c.client.StartBuild(ctx, buildRequestToProto(request))
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	event, err := stream.Recv()
+	if err != nil {
+		cancel()
+		c.l.Error("start build failed", zap.Error(err))
+		return nil, nil, err
+	}
+
+	if event.GetStarted() == nil {
+		cancel()
+		return nil, nil, fmt.Errorf("failed to read build started message: unexpected build event %T", event.GetEvent())
+	}
+
+	var d protoDecoder
+	started := d.buildStarted(event.GetStarted())
+	if d.err != nil {
+		cancel()
+		return nil, nil, fmt.Errorf("failed to read build started message: %w", d.err)
+	}
+
+	c.l.Debug("build started", zap.String("build_id", started.ID.String()))
+	return started, &grpcStatusReader{stream: stream, cancel: cancel}, nil
+}
+
+func (c *BuildGRPCClient) SignalBuild(ctx context.Context, buildID build.ID, signal *SignalRequest) (*SignalResponse, error) {
+	_, err := c.client.SignalBuild(ctx, &apipb.SignalBuildRequest{
+		BuildId: buildID[:],
+		Signal:  signalToProto(signal),
+	})
+	if err != nil {
+		c.l.Error("signal build failed", zap.String("build_id", buildID.String()), zap.Error(err))
+		return nil, err
+	}
+	return &SignalResponse{}, nil
+}
+
+func (c *BuildGRPCClient) QueryCache(ctx context.Context, request *QueryRequest) (*QueryResponse, error) {
+	pb, err := c.client.QueryCache(ctx, &apipb.QueryRequest{Jobs: idsToProto(request.Jobs)})
+	if err != nil {
+		c.l.Error("cache query failed", zap.Error(err))
+		return nil, err
+	}
+
+	var d protoDecoder
+	rsp := d.queryResponse(pb)
+	if d.err != nil {
+		return nil, d.err
+	}
+	return rsp, nil
+}
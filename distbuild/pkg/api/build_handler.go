@@ -3,18 +3,146 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 )
 
 func NewBuildService(l *zap.Logger, s Service) *BuildHandler {
-	panic("implement me")
+	return &BuildHandler{l: l, s: s}
 }
 
 type BuildHandler struct {
+	l *zap.Logger
+	s Service
 }
 
 func (h *BuildHandler) Register(mux *http.ServeMux) {
-	panic("implement me")
+	mux.HandleFunc("/build", h.build)
+	mux.HandleFunc("/signal", h.signal)
+	mux.HandleFunc("/query", h.query)
+}
+
+type statusWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	enc     *json.Encoder
+	started bool
+}
+
+func (w *statusWriter) write(msg any) error {
+	if err := w.enc.Encode(msg); err != nil {
+		return err
+	}
+	return w.rc.Flush()
+}
+
+func (w *statusWriter) Started(rsp *BuildStarted) error {
+	w.w.Header().Set("Content-Type", "application/json")
+	w.w.WriteHeader(http.StatusOK)
+	w.started = true
+	return w.write(rsp)
+}
+
+func (w *statusWriter) Updated(update *StatusUpdate) error {
+	if !w.started {
+		return fmt.Errorf("status update before build started")
+	}
+	return w.write(update)
+}
+
+func (h *BuildHandler) build(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req BuildRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.l.Error("failed to decode build request", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.l.Debug("build request received", zap.Int("jobs", len(req.Graph.Jobs)))
+
+	sw := &statusWriter{w: w, rc: http.NewResponseController(w), enc: json.NewEncoder(w)}
+	err := h.s.StartBuild(r.Context(), &req, sw)
+	if err == nil {
+		return
+	}
+
+	h.l.Error("build failed", zap.Error(err))
+	if !sw.started {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := sw.Updated(&StatusUpdate{BuildFailed: &BuildFailed{Error: err.Error()}}); err != nil {
+		h.l.Warn("failed to send build error", zap.Error(err))
+	}
+}
+
+func (h *BuildHandler) signal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var buildID build.ID
+	if err := buildID.UnmarshalText([]byte(r.URL.Query().Get("build_id"))); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req SignalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.l.Debug("signal received", zap.String("build_id", buildID.String()))
+
+	rsp, err := h.s.SignalBuild(r.Context(), buildID, &req)
+	if err != nil {
+		h.l.Error("signal failed", zap.String("build_id", buildID.String()), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rsp); err != nil {
+		h.l.Warn("failed to write signal response", zap.Error(err))
+	}
+}
+
+func (h *BuildHandler) query(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req QueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.l.Debug("cache query received", zap.Int("jobs", len(req.Jobs)))
+
+	rsp, err := h.s.QueryCache(r.Context(), &req)
+	if err != nil {
+		h.l.Error("cache query failed", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rsp); err != nil {
+		h.l.Warn("failed to write query response", zap.Error(err))
+	}
 }
@@ -4,13 +4,17 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 
 	"gitlab.com/slon/shad-go/distbuild/pkg/api"
 	mock "gitlab.com/slon/shad-go/distbuild/pkg/api/mock"
@@ -19,143 +23,298 @@ import (
 
 //go:generate mockgen -package mock -destination mock/mock.go . Service
 
+// buildClient is implemented by the clients of all transports.
+type buildClient interface {
+	StartBuild(ctx context.Context, request *api.BuildRequest) (*api.BuildStarted, api.StatusReader, error)
+	SignalBuild(ctx context.Context, buildID build.ID, signal *api.SignalRequest) (*api.SignalResponse, error)
+	QueryCache(ctx context.Context, request *api.QueryRequest) (*api.QueryResponse, error)
+}
+
 type env struct {
 	ctrl   *gomock.Controller
 	mock   *mock.MockService
-	server *httptest.Server
-	client *api.BuildClient
+	client buildClient
 }
 
-func (e *env) stop() {
-	e.server.Close()
+// transports lists the api implementations. Every test runs against each of them.
+var transports = []string{"http", "grpc"}
+
+func forEachTransport(t *testing.T, test func(t *testing.T, transport string)) {
+	for _, transport := range transports {
+		t.Run(transport, func(t *testing.T) {
+			test(t, transport)
+		})
+	}
+}
+
+// newGRPCConn starts gRPC server with services registered by register and connects to it.
+func newGRPCConn(t *testing.T, register func(s *grpc.Server)) *grpc.ClientConn {
+	lsn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	register(server)
+	go func() { _ = server.Serve(lsn) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial(lsn.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
 }
 
-func newEnv(t *testing.T) (*env, func()) {
+func newEnv(t *testing.T, transport string) *env {
 	env := &env{}
 	env.ctrl = gomock.NewController(t)
 	env.mock = mock.NewMockService(env.ctrl)
 
 	log := zaptest.NewLogger(t)
 
-	mux := http.NewServeMux()
+	switch transport {
+	case "http":
+		mux := http.NewServeMux()
+		api.NewBuildService(log, env.mock).Register(mux)
 
-	handler := api.NewBuildService(log, env.mock)
-	handler.Register(mux)
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
 
-	env.server = httptest.NewServer(mux)
+		env.client = api.NewBuildClient(log, server.URL)
 
-	env.client = api.NewBuildClient(log, env.server.URL)
+	case "grpc":
+		conn := newGRPCConn(t, func(s *grpc.Server) {
+			api.NewBuildGRPCServer(log, env.mock).Register(s)
+		})
+		env.client = api.NewBuildGRPCClient(log, conn)
+
+	default:
+		t.Fatalf("unknown transport %q", transport)
+	}
 
-	return env, env.stop
+	return env
 }
 
 func TestBuildSignal(t *testing.T) {
-	env, stop := newEnv(t)
-	defer stop()
+	forEachTransport(t, func(t *testing.T, transport string) {
+		env := newEnv(t, transport)
 
-	ctx := context.Background()
+		ctx := context.Background()
 
-	buildIDa := build.ID{01}
-	buildIDb := build.ID{02}
-	req := &api.SignalRequest{}
-	rsp := &api.SignalResponse{}
+		buildIDa := build.ID{01}
+		buildIDb := build.ID{02}
+		req := &api.SignalRequest{}
+		rsp := &api.SignalResponse{}
 
-	env.mock.EXPECT().SignalBuild(gomock.Any(), buildIDa, req).Return(rsp, nil)
-	env.mock.EXPECT().SignalBuild(gomock.Any(), buildIDb, req).Return(nil, fmt.Errorf("foo bar error"))
+		env.mock.EXPECT().SignalBuild(gomock.Any(), buildIDa, req).Return(rsp, nil)
+		env.mock.EXPECT().SignalBuild(gomock.Any(), buildIDb, req).Return(nil, fmt.Errorf("foo bar error"))
 
-	_, err := env.client.SignalBuild(ctx, buildIDa, req)
-	require.NoError(t, err)
+		_, err := env.client.SignalBuild(ctx, buildIDa, req)
+		require.NoError(t, err)
 
-	_, err = env.client.SignalBuild(ctx, buildIDb, req)
-	require.Error(t, err)
-	require.Contains(t, err.Error(), "foo bar error")
+		_, err = env.client.SignalBuild(ctx, buildIDb, req)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "foo bar error")
+	})
 }
 
 func TestBuildStartError(t *testing.T) {
-	env, stop := newEnv(t)
-	defer stop()
+	forEachTransport(t, func(t *testing.T, transport string) {
+		env := newEnv(t, transport)
 
-	ctx := context.Background()
+		ctx := context.Background()
 
-	env.mock.EXPECT().StartBuild(gomock.Any(), gomock.Any(), gomock.Any()).Return(fmt.Errorf("foo bar error"))
+		env.mock.EXPECT().StartBuild(gomock.Any(), gomock.Any(), gomock.Any()).Return(fmt.Errorf("foo bar error"))
 
-	_, _, err := env.client.StartBuild(ctx, &api.BuildRequest{})
-	require.Error(t, err)
-	require.Contains(t, err.Error(), "foo bar error")
+		_, _, err := env.client.StartBuild(ctx, &api.BuildRequest{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "foo bar error")
+	})
 }
 
 func TestBuildRunning(t *testing.T) {
-	env, stop := newEnv(t)
-	defer stop()
+	forEachTransport(t, func(t *testing.T, transport string) {
+		env := newEnv(t, transport)
 
-	ctx := context.Background()
+		ctx := context.Background()
 
-	buildID := build.ID{02}
+		buildID := build.ID{02}
 
-	req := &api.BuildRequest{
-		Graph: build.Graph{SourceFiles: map[build.ID]string{{01}: "a.txt"}},
-	}
+		req := &api.BuildRequest{
+			Graph: build.Graph{SourceFiles: map[build.ID]string{{01}: "a.txt"}},
+		}
 
-	started := &api.BuildStarted{ID: buildID}
-	finished := &api.StatusUpdate{BuildFinished: &api.BuildFinished{}}
+		started := &api.BuildStarted{ID: buildID}
+		finished := &api.StatusUpdate{BuildFinished: &api.BuildFinished{}}
 
-	env.mock.EXPECT().StartBuild(gomock.Any(), gomock.Any(), gomock.Any()).
-		DoAndReturn(func(_ context.Context, req *api.BuildRequest, w api.StatusWriter) error {
-			if err := w.Started(started); err != nil {
-				return err
-			}
+		env.mock.EXPECT().StartBuild(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, req *api.BuildRequest, w api.StatusWriter) error {
+				if err := w.Started(started); err != nil {
+					return err
+				}
 
-			if err := w.Updated(finished); err != nil {
-				return err
-			}
+				if err := w.Updated(finished); err != nil {
+					return err
+				}
 
-			return fmt.Errorf("foo bar error")
-		})
+				return fmt.Errorf("foo bar error")
+			})
 
-	rsp, r, err := env.client.StartBuild(ctx, req)
-	require.NoError(t, err)
-	defer r.Close()
+		rsp, r, err := env.client.StartBuild(ctx, req)
+		require.NoError(t, err)
+		defer r.Close()
 
-	require.Equal(t, started, rsp)
+		require.Equal(t, started, rsp)
 
-	u, err := r.Next()
-	require.NoError(t, err)
-	require.Equal(t, finished, u)
+		u, err := r.Next()
+		require.NoError(t, err)
+		require.Equal(t, finished, u)
 
-	u, err = r.Next()
-	require.NoError(t, err)
-	require.Contains(t, u.BuildFailed.Error, "foo bar error")
+		u, err = r.Next()
+		require.NoError(t, err)
+		require.Contains(t, u.BuildFailed.Error, "foo bar error")
 
-	_, err = r.Next()
-	require.Equal(t, io.EOF, err)
+		_, err = r.Next()
+		require.Equal(t, io.EOF, err)
+	})
 }
 
 func TestBuildResultsStreaming(t *testing.T) {
-	// Test is hanging?
-	// See https://golang.org/pkg/net/http/#Flusher
+	forEachTransport(t, func(t *testing.T, transport string) {
+		// Test is hanging?
+		// See https://golang.org/pkg/net/http/#Flusher
+
+		env := newEnv(t, transport)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		buildID := build.ID{02}
+		req := &api.BuildRequest{}
+		started := &api.BuildStarted{ID: buildID}
+
+		env.mock.EXPECT().StartBuild(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, req *api.BuildRequest, w api.StatusWriter) error {
+				if err := w.Started(started); err != nil {
+					return err
+				}
+
+				<-ctx.Done()
+				return ctx.Err()
+			})
+
+		rsp, r, err := env.client.StartBuild(ctx, req)
+		require.NoError(t, err)
+		defer r.Close()
+		require.Equal(t, started, rsp)
+	})
+}
 
-	env, stop := newEnv(t)
-	defer stop()
+func TestBuildMessagesRoundTrip(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 0, 0, 42, time.UTC)
+	errorMsg := "exit status 1"
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	req := &api.BuildRequest{
+		Graph: build.Graph{
+			SourceFiles: map[build.ID]string{{01}: "a.txt"},
+			Jobs: []build.Job{
+				{
+					ID:     build.ID{03},
+					Name:   "cat",
+					Inputs: []string{"a.txt"},
+					Deps:   []build.ID{{04}},
+					Cmds: []build.Cmd{
+						{Exec: []string{"cat", "a.txt"}, Environ: []string{"A=B"}, WorkingDirectory: "/tmp"},
+						{CatTemplate: "OK", CatOutput: "out.txt"},
+						{CopySource: "a", CopyOutput: "b"},
+						{SymlinkTarget: "b", SymlinkOutput: "c"},
+						{Mkdir: "d"},
+						{ArchiveSource: "d", ArchiveOutput: "d.tar"},
+						{UnarchiveSource: "d.tar", UnarchiveOutput: "e"},
+					},
+					Timeout:         time.Minute,
+					Retries:         2,
+					RetryOnExitCode: true,
+					Resources:       build.Resources{MilliCPU: 500, Memory: 1 << 20},
+					Labels:          []string{"linux"},
+				},
+			},
+		},
+		BuildID:   &build.ID{02},
+		User:      "alice",
+		Priority:  3,
+		Reproduce: []build.ID{{03}},
+	}
 
-	buildID := build.ID{02}
-	req := &api.BuildRequest{}
-	started := &api.BuildStarted{ID: buildID}
+	started := &api.BuildStarted{ID: build.ID{02}, MissingFiles: []build.ID{{01}}}
+	updates := []*api.StatusUpdate{
+		{JobOutput: &api.JobOutput{ID: build.ID{03}, Stderr: true, Attempt: 1, Offset: 10, Data: []byte("out")}},
+		{JobFinished: &api.JobResult{
+			ID:       build.ID{03},
+			Stdout:   []byte("stdout"),
+			Stderr:   []byte("stderr"),
+			ExitCode: 1,
+			Error:    &errorMsg,
+			Attempts: 2,
+			Trace: &api.JobTrace{
+				WorkerID: "worker0",
+				Slot:     1,
+				Queued:   at,
+				Picked:   at,
+				Started:  at,
+				Prepared: at,
+				Executed: at,
+			},
+		}},
+		{JobsQueued: &api.JobsQueued{Positions: map[build.ID]int{{03}: 5}, Unsatisfiable: []build.ID{{04}}}},
+		{JobReproduced: &api.JobReproduced{ID: build.ID{03}, Workers: [2]api.WorkerID{"worker0", "worker1"}, DiffFiles: []string{"out.txt"}}},
+		{BuildCancelled: &api.BuildCancelled{}},
+	}
 
-	env.mock.EXPECT().StartBuild(gomock.Any(), gomock.Any(), gomock.Any()).
-		DoAndReturn(func(ctx context.Context, req *api.BuildRequest, w api.StatusWriter) error {
-			if err := w.Started(started); err != nil {
-				return err
-			}
+	query := &api.QueryRequest{Jobs: []build.ID{{03}, {04}}}
+	cached := &api.QueryResponse{
+		Cached: map[build.ID]api.CachedJob{
+			{03}: {WorkerID: "worker0", Result: &api.JobResult{ID: build.ID{03}, Cached: true}},
+			{04}: {WorkerID: "worker1"},
+		},
+	}
 
-			<-ctx.Done()
-			return ctx.Err()
-		})
+	forEachTransport(t, func(t *testing.T, transport string) {
+		env := newEnv(t, transport)
 
-	rsp, r, err := env.client.StartBuild(ctx, req)
-	require.NoError(t, err)
-	defer r.Close()
-	require.Equal(t, started, rsp)
+		ctx := context.Background()
+
+		env.mock.EXPECT().StartBuild(gomock.Any(), gomock.Eq(req), gomock.Any()).
+			DoAndReturn(func(_ context.Context, req *api.BuildRequest, w api.StatusWriter) error {
+				if err := w.Started(started); err != nil {
+					return err
+				}
+
+				for _, update := range updates {
+					if err := w.Updated(update); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+
+		env.mock.EXPECT().QueryCache(gomock.Any(), gomock.Eq(query)).Return(cached, nil)
+
+		rsp, r, err := env.client.StartBuild(ctx, req)
+		require.NoError(t, err)
+		defer r.Close()
+
+		require.Equal(t, started, rsp)
+
+		for _, update := range updates {
+			u, err := r.Next()
+			require.NoError(t, err)
+			require.Equal(t, update, u)
+		}
+
+		_, err = r.Next()
+		require.Equal(t, io.EOF, err)
+
+		queryRsp, err := env.client.QueryCache(ctx, query)
+		require.NoError(t, err)
+		require.Equal(t, cached, queryRsp)
+	})
 }
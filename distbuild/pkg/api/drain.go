@@ -0,0 +1,42 @@
+package api
+
+import (
+	"context"
+)
+
+// DrainRequest выводит воркер из работы, например, на время обслуживания машины.
+//
+// Выводимый воркер не получает новых джобов и дожидается завершения выполняющихся. Затем координатор
+// снимает воркер с учёта: LocateArtifact больше не указывает на него, а воркер прекращает работу.
+type DrainRequest struct {
+	// Replicate просит перед снятием с учёта скопировать на другие воркеры артефакты, которых
+	// нет больше ни на одном воркере.
+	Replicate bool
+
+	// Force снимает воркер с учёта сразу, не дожидаясь его хартбита. Так выводится из работы воркер,
+	// машина которого умерла. Выполнявшиеся на нём джобы ставятся в очередь заново, а артефакты,
+	// которых нет на других воркерах, теряются. Replicate вместе с Force не копирует ничего.
+	Force bool
+}
+
+// DrainStatus описывает состояние выводимого воркера.
+type DrainStatus struct {
+	// Draining сообщает, что воркер не получает новых джобов.
+	Draining bool
+
+	// RunningJobs - число джобов, выполняющихся на воркере.
+	RunningJobs int
+
+	// PendingArtifacts - число уникальных артефактов воркера, которые ещё не скопированы на другие воркеры.
+	PendingArtifacts int
+
+	// Drained сообщает, что воркер снят с учёта.
+	Drained bool
+}
+
+// DrainService выводит воркер из работы. Его реализуют и координатор, и сам воркер.
+//
+// Повторный вызов ничего не меняет и возвращает текущее состояние воркера.
+type DrainService interface {
+	Drain(ctx context.Context, workerID WorkerID, req *DrainRequest) (*DrainStatus, error)
+}
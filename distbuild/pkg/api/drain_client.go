@@ -0,0 +1,64 @@
+//go:build !solution
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"go.uber.org/zap"
+)
+
+type DrainClient struct {
+	l        *zap.Logger
+	endpoint string
+	client   *http.Client
+}
+
+func NewDrainClient(l *zap.Logger, endpoint string) *DrainClient {
+	return NewDrainClientWithHTTPClient(l, endpoint, http.DefaultClient)
+}
+
+// NewDrainClientWithHTTPClient creates client sending requests through c, for example to present credentials.
+func NewDrainClientWithHTTPClient(l *zap.Logger, endpoint string, c *http.Client) *DrainClient {
+	return &DrainClient{l: l, endpoint: endpoint, client: c}
+}
+
+func (c *DrainClient) Drain(ctx context.Context, workerID WorkerID, req *DrainRequest) (*DrainStatus, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	u := c.endpoint + "/drain"
+	if workerID != "" {
+		u += "?worker_id=" + url.QueryEscape(workerID.String())
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	rsp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		err = readError(rsp)
+		c.l.Error("drain failed", zap.String("worker_id", workerID.String()), zap.Error(err))
+		return nil, err
+	}
+
+	var status DrainStatus
+	if err := json.NewDecoder(rsp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
@@ -0,0 +1,58 @@
+//go:build !solution
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+type DrainHandler struct {
+	l *zap.Logger
+	s DrainService
+}
+
+func NewDrainHandler(l *zap.Logger, s DrainService) *DrainHandler {
+	return &DrainHandler{l: l, s: s}
+}
+
+func (h *DrainHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/drain", h.drain)
+}
+
+func (h *DrainHandler) drain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	workerID := WorkerID(r.URL.Query().Get("worker_id"))
+
+	var req DrainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.l.Debug("drain request received", zap.String("worker_id", workerID.String()), zap.Bool("replicate", req.Replicate))
+
+	status, err := h.s.Drain(r.Context(), workerID, &req)
+	if err != nil {
+		h.l.Error("drain failed", zap.String("worker_id", workerID.String()), zap.Error(err))
+
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrUnknownWorker) {
+			code = http.StatusForbidden
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		h.l.Warn("failed to write drain status", zap.Error(err))
+	}
+}
@@ -0,0 +1,52 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+)
+
+type fakeDrainService struct {
+	workerID api.WorkerID
+	req      *api.DrainRequest
+}
+
+func (s *fakeDrainService) Drain(ctx context.Context, workerID api.WorkerID, req *api.DrainRequest) (*api.DrainStatus, error) {
+	if workerID == "unknown" {
+		return nil, fmt.Errorf("worker %s is not registered", workerID)
+	}
+
+	s.workerID = workerID
+	s.req = req
+	return &api.DrainStatus{Draining: true, RunningJobs: 1, PendingArtifacts: 2}, nil
+}
+
+func TestDrain(t *testing.T) {
+	l := zaptest.NewLogger(t)
+	s := &fakeDrainService{}
+
+	mux := http.NewServeMux()
+	api.NewDrainHandler(l, s).Register(mux)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := api.NewDrainClient(l, server.URL)
+
+	status, err := client.Drain(context.Background(), "http://worker0", &api.DrainRequest{Replicate: true})
+	require.NoError(t, err)
+	require.Equal(t, &api.DrainStatus{Draining: true, RunningJobs: 1, PendingArtifacts: 2}, status)
+	require.Equal(t, api.WorkerID("http://worker0"), s.workerID)
+	require.Equal(t, &api.DrainRequest{Replicate: true}, s.req)
+
+	_, err = client.Drain(context.Background(), "unknown", &api.DrainRequest{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is not registered")
+}
@@ -2,6 +2,8 @@ package api
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 )
@@ -18,6 +20,58 @@ type JobResult struct {
 	//
 	// Если Error == nil, значит джоб завершился успешно.
 	Error *string
+
+	// Attempts сообщает, сколько попыток потребовалось, включая последнюю.
+	Attempts int
+
+	// Cached сообщает, что джоб не запускался: его артефакт уже был в кеше, а вывод взят из
+	// результата, сохранённого рядом с артефактом.
+	Cached bool `json:",omitempty"`
+
+	// Trace содержит моменты прохождения этапов выполнения. Trace == nil для джобов, взятых из кеша координатором.
+	Trace *JobTrace `json:",omitempty"`
+}
+
+// JobTrace описывает, когда джоб проходил этапы выполнения. По нему клиент строит трассировку билда.
+//
+// Queued и Picked проставляет координатор, остальные моменты — воркер. Часы машин не синхронизируются,
+// поэтому моменты с разных машин могут немного расходиться. Нулевой момент означает, что этап не был пройден.
+type JobTrace struct {
+	// WorkerID и Slot задают воркер и номер слота воркера, в котором выполнялся джоб.
+	WorkerID WorkerID
+	Slot     int
+
+	// Queued - координатор поставил джоб в очередь.
+	Queued time.Time
+	// Picked - координатор отдал джоб воркеру.
+	Picked time.Time
+	// Started - воркер начал выполнение.
+	Started time.Time
+	// Prepared - исходные файлы и артефакты зависимостей скачаны.
+	Prepared time.Time
+	// Executed - команды джоба выполнены.
+	Executed time.Time
+	// Committed - артефакт сохранён в кеш воркера.
+	Committed time.Time
+}
+
+// JobOutput содержит очередной кусок вывода работающего джоба.
+//
+// Куски одного потока передаются в порядке возрастания Offset. Часть кусков может быть потеряна
+// при переполнении буферов, полный вывод джоба всегда передаётся в JobResult.
+type JobOutput struct {
+	ID build.ID
+
+	// Stderr отличает кусок stderr от куска stdout.
+	Stderr bool
+
+	// Attempt задаёт номер попытки, которая породила вывод. Offset считается от начала вывода попытки.
+	Attempt int
+
+	// Offset задаёт позицию начала куска в полном выводе джоба.
+	Offset int
+
+	Data []byte
 }
 
 type WorkerID string
@@ -41,12 +95,37 @@ type HeartbeatRequest struct {
 	// FreeSlots сообщает, сколько еще процессов можно запустить на этом воркере.
 	FreeSlots int
 
+	// FreeResources сообщает, сколько ресурсов осталось свободно с учётом работающих джобов.
+	//
+	// nil означает, что ресурсы воркера не ограничены и число джобов ограничивает только FreeSlots.
+	FreeResources *build.Resources
+
+	// Resources сообщает полную ёмкость воркера. Координатор сообщает клиенту о джобах, которые
+	// не помещаются ни в один воркер.
+	//
+	// nil означает, что ресурсы воркера не ограничены.
+	Resources *build.Resources
+
+	// Labels перечисляет метки воркера. Воркер может выполнять только джобы, все метки которых
+	// перечислены в Labels.
+	Labels []string
+
+	// JobOutput передаёт вывод джобов, который появился на этой итерации цикла.
+	JobOutput []JobOutput
+
 	// JobResult сообщает координатору, какие джобы завершили исполнение на этом воркере
 	// на этой итерации цикла.
 	FinishedJob []JobResult
 
 	// AddedArtifacts говорит, какие артефакты появились в кеше на этой итерации цикла.
 	AddedArtifacts []build.ID
+
+	// RemovedArtifacts говорит, какие артефакты были вытеснены из кеша на этой итерации цикла.
+	RemovedArtifacts []build.ID
+
+	// Drain просит координатора вывести воркер из работы. Воркер, которого вывели через DrainService,
+	// передаёт Drain в каждом хартбите, пока координатор не ответит Drained.
+	Drain *DrainRequest
 }
 
 // JobSpec описывает джоб, который нужно запустить.
@@ -55,15 +134,46 @@ type JobSpec struct {
 	SourceFiles map[build.ID]string
 
 	// Artifacts задаёт воркеров, с которых можно скачать артефакты необходимые этому джобу.
-	Artifacts map[build.ID]WorkerID
+	// Если артефакт на одном воркере испорчен или пропал, воркер скачивает его со следующего.
+	Artifacts map[build.ID][]WorkerID
+
+	// Attempt задаёт номер попытки, начиная с 1.
+	Attempt int
+
+	// Queued и Picked - моменты, когда координатор поставил джоб в очередь и отдал его воркеру.
+	// Воркер возвращает их в JobResult.Trace.
+	Queued, Picked time.Time
 
 	build.Job
 }
 
 type HeartbeatResponse struct {
 	JobsToRun map[build.ID]JobSpec
+
+	// JobsToCancel перечисляет джобы, которые воркер должен прервать.
+	//
+	// Результат прерванного джоба координатору не передаётся.
+	JobsToCancel []build.ID
+
+	// Draining сообщает, что воркер выводится из работы. Новых джобов воркер не получит.
+	Draining bool
+
+	// ArtifactsToFetch перечисляет артефакты, которые воркер должен скачать к себе в кеш с указанных воркеров.
+	// Так координатор копирует уникальные артефакты выводимого воркера.
+	ArtifactsToFetch map[build.ID]WorkerID
+
+	// ArtifactsToRemove перечисляет артефакты, которые воркер должен удалить из своего кеша,
+	// например артефакт повторного запуска джоба после проверки воспроизводимости.
+	ArtifactsToRemove []build.ID
+
+	// Drained сообщает выводимому воркеру, что он снят с учёта и должен прекратить работу.
+	Drained bool
 }
 
+// ErrUnknownWorker возвращается HeartbeatService, если воркер не входит в список разрешённых
+// или его сертификат выписан для другого адреса. Хендлер отвечает на такой хартбит кодом 403.
+var ErrUnknownWorker = errors.New("unknown worker")
+
 type HeartbeatService interface {
 	Heartbeat(ctx context.Context, req *HeartbeatRequest) (*HeartbeatResponse, error)
 }
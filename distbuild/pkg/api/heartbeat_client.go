@@ -3,18 +3,56 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"net/http"
 
 	"go.uber.org/zap"
 )
 
 type HeartbeatClient struct {
+	l        *zap.Logger
+	endpoint string
+	client   *http.Client
 }
 
 func NewHeartbeatClient(l *zap.Logger, endpoint string) *HeartbeatClient {
-	panic("implement me")
+	return NewHeartbeatClientWithHTTPClient(l, endpoint, http.DefaultClient)
+}
+
+// NewHeartbeatClientWithHTTPClient creates client sending requests through c, for example to present credentials.
+func NewHeartbeatClientWithHTTPClient(l *zap.Logger, endpoint string, c *http.Client) *HeartbeatClient {
+	return &HeartbeatClient{l: l, endpoint: endpoint, client: c}
 }
 
 func (c *HeartbeatClient) Heartbeat(ctx context.Context, req *HeartbeatRequest) (*HeartbeatResponse, error) {
-	panic("implement me")
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/heartbeat", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	rsp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		err = readError(rsp)
+		c.l.Error("heartbeat failed", zap.String("worker_id", req.WorkerID.String()), zap.Error(err))
+		return nil, err
+	}
+
+	var hbRsp HeartbeatResponse
+	if err := json.NewDecoder(rsp.Body).Decode(&hbRsp); err != nil {
+		return nil, err
+	}
+	return &hbRsp, nil
 }
@@ -0,0 +1,77 @@
+//go:build !solution
+
+package api
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api/apipb"
+)
+
+// HeartbeatGRPCServer serves HeartbeatService over gRPC. It is the gRPC counterpart of HeartbeatHandler.
+type HeartbeatGRPCServer struct {
+	apipb.UnimplementedHeartbeatServer
+
+	l *zap.Logger
+	s HeartbeatService
+}
+
+func NewHeartbeatGRPCServer(l *zap.Logger, s HeartbeatService) *HeartbeatGRPCServer {
+	return &HeartbeatGRPCServer{l: l, s: s}
+}
+
+func (h *HeartbeatGRPCServer) Register(s grpc.ServiceRegistrar) {
+	apipb.RegisterHeartbeatServer(s, h)
+}
+
+func (h *HeartbeatGRPCServer) Heartbeat(ctx context.Context, pb *apipb.HeartbeatRequest) (*apipb.HeartbeatResponse, error) {
+	var d protoDecoder
+	req := d.heartbeatRequest(pb)
+	if d.err != nil {
+		h.l.Error("failed to decode heartbeat", zap.Error(d.err))
+		return nil, status.Error(codes.InvalidArgument, d.err.Error())
+	}
+
+	rsp, err := h.s.Heartbeat(ctx, req)
+	if err != nil {
+		h.l.Error("heartbeat failed", zap.String("worker_id", req.WorkerID.String()), zap.Error(err))
+
+		code := codes.Internal
+		if errors.Is(err, ErrUnknownWorker) {
+			code = codes.PermissionDenied
+		}
+		return nil, status.Error(code, err.Error())
+	}
+	return heartbeatResponseToProto(rsp), nil
+}
+
+// HeartbeatGRPCClient is the gRPC counterpart of HeartbeatClient.
+type HeartbeatGRPCClient struct {
+	l      *zap.Logger
+	client apipb.HeartbeatClient
+}
+
+func NewHeartbeatGRPCClient(l *zap.Logger, cc grpc.ClientConnInterface) *HeartbeatGRPCClient {
+	return &HeartbeatGRPCClient{l: l, client: apipb.NewHeartbeatClient(cc)}
+}
+
+func (c *HeartbeatGRPCClient) Heartbeat(ctx context.Context, req *HeartbeatRequest) (*HeartbeatResponse, error) {
+	pb, err := c.client.Heartbeat(ctx, heartbeatRequestToProto(req))
+	if err != nil {
+		c.l.Error("heartbeat failed", zap.String("worker_id", req.WorkerID.String()), zap.Error(err))
+		return nil, err
+	}
+
+	var d protoDecoder
+	rsp := d.heartbeatResponse(pb)
+	if d.err != nil {
+		return nil, d.err
+	}
+	return rsp, nil
+}
@@ -3,18 +3,53 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 
 	"go.uber.org/zap"
 )
 
 type HeartbeatHandler struct {
+	l *zap.Logger
+	s HeartbeatService
 }
 
 func NewHeartbeatHandler(l *zap.Logger, s HeartbeatService) *HeartbeatHandler {
-	panic("implement me")
+	return &HeartbeatHandler{l: l, s: s}
 }
 
 func (h *HeartbeatHandler) Register(mux *http.ServeMux) {
-	panic("implement me")
+	mux.HandleFunc("/heartbeat", h.heartbeat)
+}
+
+func (h *HeartbeatHandler) heartbeat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req HeartbeatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.l.Error("failed to decode heartbeat", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rsp, err := h.s.Heartbeat(r.Context(), &req)
+	if err != nil {
+		h.l.Error("heartbeat failed", zap.String("worker_id", req.WorkerID.String()), zap.Error(err))
+
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrUnknownWorker) {
+			code = http.StatusForbidden
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rsp); err != nil {
+		h.l.Warn("failed to write heartbeat response", zap.Error(err))
+	}
 }
@@ -10,6 +10,9 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"gitlab.com/slon/shad-go/distbuild/pkg/api"
 	"gitlab.com/slon/shad-go/distbuild/pkg/api/mock"
@@ -18,38 +21,121 @@ import (
 
 //go:generate mockgen -package mock -destination mock/heartbeat.go . HeartbeatService
 
+func newHeartbeatClient(t *testing.T, transport string, s api.HeartbeatService) api.HeartbeatService {
+	l := zaptest.NewLogger(t)
+
+	switch transport {
+	case "http":
+		mux := http.NewServeMux()
+		api.NewHeartbeatHandler(l, s).Register(mux)
+
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+
+		return api.NewHeartbeatClient(l, server.URL)
+
+	case "grpc":
+		conn := newGRPCConn(t, func(server *grpc.Server) {
+			api.NewHeartbeatGRPCServer(l, s).Register(server)
+		})
+		return api.NewHeartbeatGRPCClient(l, conn)
+
+	default:
+		t.Fatalf("unknown transport %q", transport)
+		return nil
+	}
+}
+
 func TestHeartbeat(t *testing.T) {
-	ctrl := gomock.NewController(t)
+	forEachTransport(t, func(t *testing.T, transport string) {
+		ctrl := gomock.NewController(t)
 
-	l := zaptest.NewLogger(t)
-	m := mock.NewMockHeartbeatService(ctrl)
-	mux := http.NewServeMux()
-	api.NewHeartbeatHandler(l, m).Register(mux)
+		m := mock.NewMockHeartbeatService(ctrl)
+		client := newHeartbeatClient(t, transport, m)
+
+		req := &api.HeartbeatRequest{
+			WorkerID: "worker0",
+		}
+		rsp := &api.HeartbeatResponse{
+			JobsToRun: map[build.ID]api.JobSpec{
+				{0x01}: {Job: build.Job{Name: "cc a.c"}},
+			},
+		}
 
-	server := httptest.NewServer(mux)
-	defer server.Close()
+		gomock.InOrder(
+			m.EXPECT().Heartbeat(gomock.Any(), gomock.Eq(req)).Times(1).Return(rsp, nil),
+			m.EXPECT().Heartbeat(gomock.Any(), gomock.Eq(req)).Times(1).Return(nil, fmt.Errorf("build error: foo bar")),
+		)
 
-	client := api.NewHeartbeatClient(l, server.URL)
+		clientRsp, err := client.Heartbeat(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, rsp, clientRsp)
+
+		_, err = client.Heartbeat(context.Background(), req)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "build error: foo bar")
+	})
+}
+
+func TestHeartbeatMessagesRoundTrip(t *testing.T) {
+	errorMsg := "killed"
 
 	req := &api.HeartbeatRequest{
-		WorkerID: "worker0",
+		WorkerID:      "worker0",
+		RunningJobs:   []build.ID{{01}},
+		FreeSlots:     2,
+		FreeResources: &build.Resources{MilliCPU: 1000},
+		Resources:     &build.Resources{MilliCPU: 2000, Memory: 1 << 30},
+		Labels:        []string{"linux"},
+		JobOutput: []api.JobOutput{
+			{ID: build.ID{01}, Data: []byte("out")},
+		},
+		FinishedJob: []api.JobResult{
+			{ID: build.ID{02}, ExitCode: 137, Error: &errorMsg, Attempts: 1},
+		},
+		AddedArtifacts:   []build.ID{{02}},
+		RemovedArtifacts: []build.ID{{03}},
+		Drain:            &api.DrainRequest{Replicate: true, Force: true},
 	}
 	rsp := &api.HeartbeatResponse{
 		JobsToRun: map[build.ID]api.JobSpec{
-			{0x01}: {Job: build.Job{Name: "cc a.c"}},
+			{04}: {
+				SourceFiles: map[build.ID]string{{05}: "a.c"},
+				Artifacts:   map[build.ID][]api.WorkerID{{02}: {"worker1", "worker2"}},
+				Attempt:     2,
+				Job:         build.Job{ID: build.ID{04}, Name: "cc a.c", Deps: []build.ID{{02}}},
+			},
 		},
+		JobsToCancel:      []build.ID{{01}},
+		Draining:          true,
+		ArtifactsToFetch:  map[build.ID]api.WorkerID{{03}: "worker2"},
+		ArtifactsToRemove: []build.ID{{06}},
+		Drained:           true,
 	}
 
-	gomock.InOrder(
-		m.EXPECT().Heartbeat(gomock.Any(), gomock.Eq(req)).Times(1).Return(rsp, nil),
-		m.EXPECT().Heartbeat(gomock.Any(), gomock.Eq(req)).Times(1).Return(nil, fmt.Errorf("build error: foo bar")),
-	)
+	forEachTransport(t, func(t *testing.T, transport string) {
+		ctrl := gomock.NewController(t)
+
+		m := mock.NewMockHeartbeatService(ctrl)
+		client := newHeartbeatClient(t, transport, m)
+
+		m.EXPECT().Heartbeat(gomock.Any(), gomock.Eq(req)).Return(rsp, nil)
+
+		clientRsp, err := client.Heartbeat(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, rsp, clientRsp)
+	})
+}
+
+func TestHeartbeatUnknownWorker(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	m := mock.NewMockHeartbeatService(ctrl)
+	client := newHeartbeatClient(t, "grpc", m)
 
-	clientRsp, err := client.Heartbeat(context.Background(), req)
-	require.NoError(t, err)
-	require.Equal(t, rsp, clientRsp)
+	m.EXPECT().Heartbeat(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("worker0: %w", api.ErrUnknownWorker))
 
-	_, err = client.Heartbeat(context.Background(), req)
+	_, err := client.Heartbeat(context.Background(), &api.HeartbeatRequest{WorkerID: "worker0"})
 	require.Error(t, err)
-	require.Contains(t, err.Error(), "build error: foo bar")
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
 }
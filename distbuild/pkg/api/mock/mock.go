@@ -35,6 +35,21 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 	return m.recorder
 }
 
+// QueryCache mocks base method
+func (m *MockService) QueryCache(arg0 context.Context, arg1 *api.QueryRequest) (*api.QueryResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryCache", arg0, arg1)
+	ret0, _ := ret[0].(*api.QueryResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryCache indicates an expected call of QueryCache
+func (mr *MockServiceMockRecorder) QueryCache(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryCache", reflect.TypeOf((*MockService)(nil).QueryCache), arg0, arg1)
+}
+
 // SignalBuild mocks base method
 func (m *MockService) SignalBuild(arg0 context.Context, arg1 build.ID, arg2 *api.SignalRequest) (*api.SignalResponse, error) {
 	m.ctrl.T.Helper()
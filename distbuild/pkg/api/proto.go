@@ -0,0 +1,586 @@
+//go:build !solution
+
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api/apipb"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// Conversion between api types and protobuf messages of the gRPC transport.
+//
+// Empty collections are decoded as nil and zero times and durations are not sent,
+// so a value survives the round trip unchanged, as it does with the JSON transport.
+
+func idsToProto(ids []build.ID) [][]byte {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	out := make([][]byte, len(ids))
+	for i := range ids {
+		out[i] = ids[i][:]
+	}
+	return out
+}
+
+func timeToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func timeFromProto(t *timestamppb.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.AsTime()
+}
+
+func resourcesToProto(r build.Resources) *apipb.Resources {
+	return &apipb.Resources{MilliCpu: r.MilliCPU, Memory: r.Memory}
+}
+
+func resourcesFromProto(r *apipb.Resources) build.Resources {
+	return build.Resources{MilliCPU: r.GetMilliCpu(), Memory: r.GetMemory()}
+}
+
+func jobToProto(job *build.Job) *apipb.Job {
+	pb := &apipb.Job{
+		Id:              job.ID[:],
+		Name:            job.Name,
+		Inputs:          job.Inputs,
+		Deps:            idsToProto(job.Deps),
+		Retries:         int64(job.Retries),
+		RetryOnExitCode: job.RetryOnExitCode,
+		Labels:          job.Labels,
+	}
+
+	for _, cmd := range job.Cmds {
+		pb.Cmds = append(pb.Cmds, &apipb.Cmd{
+			Exec:             cmd.Exec,
+			Environ:          cmd.Environ,
+			WorkingDirectory: cmd.WorkingDirectory,
+			CatTemplate:      cmd.CatTemplate,
+			CatOutput:        cmd.CatOutput,
+			CopySource:       cmd.CopySource,
+			CopyOutput:       cmd.CopyOutput,
+			SymlinkTarget:    cmd.SymlinkTarget,
+			SymlinkOutput:    cmd.SymlinkOutput,
+			Mkdir:            cmd.Mkdir,
+			ArchiveSource:    cmd.ArchiveSource,
+			ArchiveOutput:    cmd.ArchiveOutput,
+			UnarchiveSource:  cmd.UnarchiveSource,
+			UnarchiveOutput:  cmd.UnarchiveOutput,
+		})
+	}
+
+	if job.Timeout != 0 {
+		pb.Timeout = durationpb.New(job.Timeout)
+	}
+	if job.Resources != (build.Resources{}) {
+		pb.Resources = resourcesToProto(job.Resources)
+	}
+	return pb
+}
+
+func graphToProto(g *build.Graph) *apipb.Graph {
+	pb := &apipb.Graph{}
+	if g.SourceFiles != nil {
+		pb.SourceFiles = make(map[string]string, len(g.SourceFiles))
+		for id, path := range g.SourceFiles {
+			pb.SourceFiles[id.String()] = path
+		}
+	}
+
+	for i := range g.Jobs {
+		pb.Jobs = append(pb.Jobs, jobToProto(&g.Jobs[i]))
+	}
+	return pb
+}
+
+func buildRequestToProto(req *BuildRequest) *apipb.BuildRequest {
+	pb := &apipb.BuildRequest{
+		Graph:     graphToProto(&req.Graph),
+		User:      req.User,
+		Priority:  int64(req.Priority),
+		Reproduce: idsToProto(req.Reproduce),
+	}
+	if req.BuildID != nil {
+		pb.BuildId = req.BuildID[:]
+	}
+	return pb
+}
+
+func buildStartedToProto(started *BuildStarted) *apipb.BuildStarted {
+	return &apipb.BuildStarted{
+		Id:           started.ID[:],
+		MissingFiles: idsToProto(started.MissingFiles),
+	}
+}
+
+func jobResultToProto(res *JobResult) *apipb.JobResult {
+	pb := &apipb.JobResult{
+		Id:       res.ID[:],
+		Stdout:   res.Stdout,
+		Stderr:   res.Stderr,
+		ExitCode: int64(res.ExitCode),
+		Error:    res.Error,
+		Attempts: int64(res.Attempts),
+		Cached:   res.Cached,
+	}
+
+	if t := res.Trace; t != nil {
+		pb.Trace = &apipb.JobTrace{
+			WorkerId:  t.WorkerID.String(),
+			Slot:      int64(t.Slot),
+			Queued:    timeToProto(t.Queued),
+			Picked:    timeToProto(t.Picked),
+			Started:   timeToProto(t.Started),
+			Prepared:  timeToProto(t.Prepared),
+			Executed:  timeToProto(t.Executed),
+			Committed: timeToProto(t.Committed),
+		}
+	}
+	return pb
+}
+
+func jobOutputToProto(out *JobOutput) *apipb.JobOutput {
+	return &apipb.JobOutput{
+		Id:      out.ID[:],
+		Stderr:  out.Stderr,
+		Attempt: int64(out.Attempt),
+		Offset:  int64(out.Offset),
+		Data:    out.Data,
+	}
+}
+
+func statusUpdateToProto(update *StatusUpdate) *apipb.StatusUpdate {
+	pb := &apipb.StatusUpdate{}
+	if update.JobOutput != nil {
+		pb.JobOutput = jobOutputToProto(update.JobOutput)
+	}
+	if update.JobFinished != nil {
+		pb.JobFinished = jobResultToProto(update.JobFinished)
+	}
+	if update.BuildFailed != nil {
+		pb.BuildFailed = &apipb.BuildFailed{Error: update.BuildFailed.Error}
+	}
+	if update.BuildFinished != nil {
+		pb.BuildFinished = &apipb.BuildFinished{}
+	}
+	if update.BuildCancelled != nil {
+		pb.BuildCancelled = &apipb.BuildCancelled{}
+	}
+	if q := update.JobsQueued; q != nil {
+		pb.JobsQueued = &apipb.JobsQueued{Unsatisfiable: idsToProto(q.Unsatisfiable)}
+		if q.Positions != nil {
+			pb.JobsQueued.Positions = make(map[string]int64, len(q.Positions))
+			for id, pos := range q.Positions {
+				pb.JobsQueued.Positions[id.String()] = int64(pos)
+			}
+		}
+	}
+	if r := update.JobReproduced; r != nil {
+		pb.JobReproduced = &apipb.JobReproduced{
+			Id:        r.ID[:],
+			Workers:   []string{r.Workers[0].String(), r.Workers[1].String()},
+			DiffFiles: r.DiffFiles,
+			Error:     r.Error,
+		}
+	}
+	return pb
+}
+
+func signalToProto(signal *SignalRequest) *apipb.SignalRequest {
+	pb := &apipb.SignalRequest{}
+	if signal.UploadDone != nil {
+		pb.UploadDone = &apipb.UploadDone{}
+	}
+	if signal.CancelBuild != nil {
+		pb.CancelBuild = &apipb.CancelBuild{}
+	}
+	return pb
+}
+
+func queryResponseToProto(rsp *QueryResponse) *apipb.QueryResponse {
+	pb := &apipb.QueryResponse{Missing: idsToProto(rsp.Missing)}
+	if rsp.Cached != nil {
+		pb.Cached = make(map[string]*apipb.CachedJob, len(rsp.Cached))
+		for id, cached := range rsp.Cached {
+			c := &apipb.CachedJob{WorkerId: cached.WorkerID.String()}
+			if cached.Result != nil {
+				c.Result = jobResultToProto(cached.Result)
+			}
+			pb.Cached[id.String()] = c
+		}
+	}
+	return pb
+}
+
+func heartbeatRequestToProto(req *HeartbeatRequest) *apipb.HeartbeatRequest {
+	pb := &apipb.HeartbeatRequest{
+		WorkerId:         req.WorkerID.String(),
+		RunningJobs:      idsToProto(req.RunningJobs),
+		FreeSlots:        int64(req.FreeSlots),
+		Labels:           req.Labels,
+		AddedArtifacts:   idsToProto(req.AddedArtifacts),
+		RemovedArtifacts: idsToProto(req.RemovedArtifacts),
+	}
+	if req.FreeResources != nil {
+		pb.FreeResources = resourcesToProto(*req.FreeResources)
+	}
+	if req.Resources != nil {
+		pb.Resources = resourcesToProto(*req.Resources)
+	}
+	if req.Drain != nil {
+		pb.Drain = &apipb.DrainRequest{Replicate: req.Drain.Replicate, Force: req.Drain.Force}
+	}
+	for i := range req.JobOutput {
+		pb.JobOutput = append(pb.JobOutput, jobOutputToProto(&req.JobOutput[i]))
+	}
+	for i := range req.FinishedJob {
+		pb.FinishedJob = append(pb.FinishedJob, jobResultToProto(&req.FinishedJob[i]))
+	}
+	return pb
+}
+
+func heartbeatResponseToProto(rsp *HeartbeatResponse) *apipb.HeartbeatResponse {
+	pb := &apipb.HeartbeatResponse{
+		JobsToCancel:      idsToProto(rsp.JobsToCancel),
+		Draining:          rsp.Draining,
+		Drained:           rsp.Drained,
+		ArtifactsToRemove: idsToProto(rsp.ArtifactsToRemove),
+	}
+	if rsp.ArtifactsToFetch != nil {
+		pb.ArtifactsToFetch = make(map[string]string, len(rsp.ArtifactsToFetch))
+		for id, workerID := range rsp.ArtifactsToFetch {
+			pb.ArtifactsToFetch[id.String()] = workerID.String()
+		}
+	}
+	if rsp.JobsToRun != nil {
+		pb.JobsToRun = make(map[string]*apipb.JobSpec, len(rsp.JobsToRun))
+		for id, spec := range rsp.JobsToRun {
+			s := &apipb.JobSpec{
+				Job:     jobToProto(&spec.Job),
+				Attempt: int64(spec.Attempt),
+				Queued:  timeToProto(spec.Queued),
+				Picked:  timeToProto(spec.Picked),
+			}
+			if spec.SourceFiles != nil {
+				s.SourceFiles = make(map[string]string, len(spec.SourceFiles))
+				for file, path := range spec.SourceFiles {
+					s.SourceFiles[file.String()] = path
+				}
+			}
+			if spec.Artifacts != nil {
+				s.Artifacts = make(map[string]*apipb.WorkerIDs, len(spec.Artifacts))
+				for dep, workerIDs := range spec.Artifacts {
+					locations := &apipb.WorkerIDs{}
+					for _, workerID := range workerIDs {
+						locations.WorkerIds = append(locations.WorkerIds, workerID.String())
+					}
+					s.Artifacts[dep.String()] = locations
+				}
+			}
+			pb.JobsToRun[id.String()] = s
+		}
+	}
+	return pb
+}
+
+// protoDecoder converts protobuf messages into api types. The first malformed id is kept in err,
+// so the caller checks the error once after the whole message is decoded.
+type protoDecoder struct {
+	err error
+}
+
+func (d *protoDecoder) id(b []byte) build.ID {
+	var id build.ID
+	if len(b) != len(id) {
+		if d.err == nil {
+			d.err = fmt.Errorf("invalid id length %d", len(b))
+		}
+		return id
+	}
+
+	copy(id[:], b)
+	return id
+}
+
+func (d *protoDecoder) ids(bs [][]byte) []build.ID {
+	if len(bs) == 0 {
+		return nil
+	}
+
+	ids := make([]build.ID, len(bs))
+	for i, b := range bs {
+		ids[i] = d.id(b)
+	}
+	return ids
+}
+
+// key decodes hex id used as a key of protobuf map.
+func (d *protoDecoder) key(s string) build.ID {
+	var id build.ID
+	if err := id.UnmarshalText([]byte(s)); err != nil && d.err == nil {
+		d.err = err
+	}
+	return id
+}
+
+func (d *protoDecoder) job(pb *apipb.Job) build.Job {
+	job := build.Job{
+		ID:              d.id(pb.GetId()),
+		Name:            pb.GetName(),
+		Inputs:          pb.GetInputs(),
+		Deps:            d.ids(pb.GetDeps()),
+		Timeout:         pb.GetTimeout().AsDuration(),
+		Retries:         int(pb.GetRetries()),
+		RetryOnExitCode: pb.GetRetryOnExitCode(),
+		Resources:       resourcesFromProto(pb.GetResources()),
+		Labels:          pb.GetLabels(),
+	}
+
+	for _, cmd := range pb.GetCmds() {
+		job.Cmds = append(job.Cmds, build.Cmd{
+			Exec:             cmd.GetExec(),
+			Environ:          cmd.GetEnviron(),
+			WorkingDirectory: cmd.GetWorkingDirectory(),
+			CatTemplate:      cmd.GetCatTemplate(),
+			CatOutput:        cmd.GetCatOutput(),
+			CopySource:       cmd.GetCopySource(),
+			CopyOutput:       cmd.GetCopyOutput(),
+			SymlinkTarget:    cmd.GetSymlinkTarget(),
+			SymlinkOutput:    cmd.GetSymlinkOutput(),
+			Mkdir:            cmd.GetMkdir(),
+			ArchiveSource:    cmd.GetArchiveSource(),
+			ArchiveOutput:    cmd.GetArchiveOutput(),
+			UnarchiveSource:  cmd.GetUnarchiveSource(),
+			UnarchiveOutput:  cmd.GetUnarchiveOutput(),
+		})
+	}
+	return job
+}
+
+func (d *protoDecoder) sourceFiles(pb map[string]string) map[build.ID]string {
+	if len(pb) == 0 {
+		return nil
+	}
+
+	files := make(map[build.ID]string, len(pb))
+	for id, path := range pb {
+		files[d.key(id)] = path
+	}
+	return files
+}
+
+func (d *protoDecoder) buildRequest(pb *apipb.BuildRequest) *BuildRequest {
+	req := &BuildRequest{
+		Graph: build.Graph{
+			SourceFiles: d.sourceFiles(pb.GetGraph().GetSourceFiles()),
+		},
+		User:      pb.GetUser(),
+		Priority:  int(pb.GetPriority()),
+		Reproduce: d.ids(pb.GetReproduce()),
+	}
+
+	for _, job := range pb.GetGraph().GetJobs() {
+		req.Graph.Jobs = append(req.Graph.Jobs, d.job(job))
+	}
+
+	if len(pb.GetBuildId()) != 0 {
+		buildID := d.id(pb.GetBuildId())
+		req.BuildID = &buildID
+	}
+	return req
+}
+
+func (d *protoDecoder) buildStarted(pb *apipb.BuildStarted) *BuildStarted {
+	return &BuildStarted{
+		ID:           d.id(pb.GetId()),
+		MissingFiles: d.ids(pb.GetMissingFiles()),
+	}
+}
+
+func (d *protoDecoder) jobResult(pb *apipb.JobResult) JobResult {
+	res := JobResult{
+		ID:       d.id(pb.GetId()),
+		Stdout:   pb.GetStdout(),
+		Stderr:   pb.GetStderr(),
+		ExitCode: int(pb.GetExitCode()),
+		Error:    pb.Error,
+		Attempts: int(pb.GetAttempts()),
+		Cached:   pb.GetCached(),
+	}
+
+	if t := pb.GetTrace(); t != nil {
+		res.Trace = &JobTrace{
+			WorkerID:  WorkerID(t.GetWorkerId()),
+			Slot:      int(t.GetSlot()),
+			Queued:    timeFromProto(t.GetQueued()),
+			Picked:    timeFromProto(t.GetPicked()),
+			Started:   timeFromProto(t.GetStarted()),
+			Prepared:  timeFromProto(t.GetPrepared()),
+			Executed:  timeFromProto(t.GetExecuted()),
+			Committed: timeFromProto(t.GetCommitted()),
+		}
+	}
+	return res
+}
+
+func (d *protoDecoder) jobOutput(pb *apipb.JobOutput) JobOutput {
+	return JobOutput{
+		ID:      d.id(pb.GetId()),
+		Stderr:  pb.GetStderr(),
+		Attempt: int(pb.GetAttempt()),
+		Offset:  int(pb.GetOffset()),
+		Data:    pb.GetData(),
+	}
+}
+
+func (d *protoDecoder) statusUpdate(pb *apipb.StatusUpdate) *StatusUpdate {
+	update := &StatusUpdate{}
+	if pb.JobOutput != nil {
+		out := d.jobOutput(pb.JobOutput)
+		update.JobOutput = &out
+	}
+	if pb.JobFinished != nil {
+		res := d.jobResult(pb.JobFinished)
+		update.JobFinished = &res
+	}
+	if pb.BuildFailed != nil {
+		update.BuildFailed = &BuildFailed{Error: pb.BuildFailed.GetError()}
+	}
+	if pb.BuildFinished != nil {
+		update.BuildFinished = &BuildFinished{}
+	}
+	if pb.BuildCancelled != nil {
+		update.BuildCancelled = &BuildCancelled{}
+	}
+	if q := pb.JobsQueued; q != nil {
+		update.JobsQueued = &JobsQueued{Unsatisfiable: d.ids(q.GetUnsatisfiable())}
+		if len(q.Positions) != 0 {
+			update.JobsQueued.Positions = make(map[build.ID]int, len(q.Positions))
+			for id, pos := range q.Positions {
+				update.JobsQueued.Positions[d.key(id)] = int(pos)
+			}
+		}
+	}
+	if r := pb.JobReproduced; r != nil {
+		update.JobReproduced = &JobReproduced{
+			ID:        d.id(r.GetId()),
+			DiffFiles: r.GetDiffFiles(),
+			Error:     r.GetError(),
+		}
+		if len(r.Workers) > len(update.JobReproduced.Workers) && d.err == nil {
+			d.err = fmt.Errorf("invalid number of workers %d", len(r.Workers))
+		}
+		for i := 0; i < len(r.Workers) && i < len(update.JobReproduced.Workers); i++ {
+			update.JobReproduced.Workers[i] = WorkerID(r.Workers[i])
+		}
+	}
+	return update
+}
+
+func (d *protoDecoder) signal(pb *apipb.SignalRequest) *SignalRequest {
+	signal := &SignalRequest{}
+	if pb.GetUploadDone() != nil {
+		signal.UploadDone = &UploadDone{}
+	}
+	if pb.GetCancelBuild() != nil {
+		signal.CancelBuild = &CancelBuild{}
+	}
+	return signal
+}
+
+func (d *protoDecoder) queryResponse(pb *apipb.QueryResponse) *QueryResponse {
+	rsp := &QueryResponse{Missing: d.ids(pb.GetMissing())}
+	if len(pb.GetCached()) != 0 {
+		rsp.Cached = make(map[build.ID]CachedJob, len(pb.Cached))
+		for id, cached := range pb.Cached {
+			c := CachedJob{WorkerID: WorkerID(cached.GetWorkerId())}
+			if cached.GetResult() != nil {
+				res := d.jobResult(cached.Result)
+				c.Result = &res
+			}
+			rsp.Cached[d.key(id)] = c
+		}
+	}
+	return rsp
+}
+
+func (d *protoDecoder) heartbeatRequest(pb *apipb.HeartbeatRequest) *HeartbeatRequest {
+	req := &HeartbeatRequest{
+		WorkerID:         WorkerID(pb.GetWorkerId()),
+		RunningJobs:      d.ids(pb.GetRunningJobs()),
+		FreeSlots:        int(pb.GetFreeSlots()),
+		Labels:           pb.GetLabels(),
+		AddedArtifacts:   d.ids(pb.GetAddedArtifacts()),
+		RemovedArtifacts: d.ids(pb.GetRemovedArtifacts()),
+	}
+	if pb.GetFreeResources() != nil {
+		free := resourcesFromProto(pb.FreeResources)
+		req.FreeResources = &free
+	}
+	if pb.GetResources() != nil {
+		resources := resourcesFromProto(pb.Resources)
+		req.Resources = &resources
+	}
+	if pb.GetDrain() != nil {
+		req.Drain = &DrainRequest{Replicate: pb.Drain.GetReplicate(), Force: pb.Drain.GetForce()}
+	}
+	for _, out := range pb.GetJobOutput() {
+		req.JobOutput = append(req.JobOutput, d.jobOutput(out))
+	}
+	for _, res := range pb.GetFinishedJob() {
+		req.FinishedJob = append(req.FinishedJob, d.jobResult(res))
+	}
+	return req
+}
+
+func (d *protoDecoder) heartbeatResponse(pb *apipb.HeartbeatResponse) *HeartbeatResponse {
+	rsp := &HeartbeatResponse{
+		JobsToCancel:      d.ids(pb.GetJobsToCancel()),
+		Draining:          pb.GetDraining(),
+		Drained:           pb.GetDrained(),
+		ArtifactsToRemove: d.ids(pb.GetArtifactsToRemove()),
+	}
+	if len(pb.GetArtifactsToFetch()) != 0 {
+		rsp.ArtifactsToFetch = make(map[build.ID]WorkerID, len(pb.ArtifactsToFetch))
+		for id, workerID := range pb.ArtifactsToFetch {
+			rsp.ArtifactsToFetch[d.key(id)] = WorkerID(workerID)
+		}
+	}
+	if len(pb.GetJobsToRun()) != 0 {
+		rsp.JobsToRun = make(map[build.ID]JobSpec, len(pb.JobsToRun))
+		for id, s := range pb.JobsToRun {
+			spec := JobSpec{
+				SourceFiles: d.sourceFiles(s.GetSourceFiles()),
+				Attempt:     int(s.GetAttempt()),
+				Queued:      timeFromProto(s.GetQueued()),
+				Picked:      timeFromProto(s.GetPicked()),
+				Job:         d.job(s.GetJob()),
+			}
+			if len(s.GetArtifacts()) != 0 {
+				spec.Artifacts = make(map[build.ID][]WorkerID, len(s.Artifacts))
+				for dep, locations := range s.Artifacts {
+					var workerIDs []WorkerID
+					for _, workerID := range locations.GetWorkerIds() {
+						workerIDs = append(workerIDs, WorkerID(workerID))
+					}
+					spec.Artifacts[d.key(dep)] = workerIDs
+				}
+			}
+			rsp.JobsToRun[d.key(id)] = spec
+		}
+	}
+	return rsp
+}
@@ -7,6 +7,10 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
 
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 )
@@ -19,15 +23,42 @@ var (
 )
 
 type Cache struct {
-	tmpDir   string
-	cacheDir string
+	tmpDir        string
+	cacheDir      string
+	manifestDir   string
+	resultDir     string
+	quarantineDir string
+	config        Config
 
 	mu          sync.Mutex
 	writeLocked map[build.ID]struct{}
 	readLocked  map[build.ID]int
+	index       *index
+	onEvict     func(artifact build.ID)
+
+	hits, misses atomic.Uint64
+}
+
+// Stats counts lookups of the artifacts by Get.
+type Stats struct {
+	Hits   uint64
+	Misses uint64
 }
 
 func NewCache(root string) (*Cache, error) {
+	return NewCacheWithConfig(root, Config{})
+}
+
+func NewCacheWithConfig(root string, config Config) (*Cache, error) {
+	// Commands of the jobs expect absolute paths of the artifacts.
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if config.Logger == nil {
+		config.Logger = zap.NewNop()
+	}
+
 	tmpDir := filepath.Join(root, "tmp")
 
 	if err := os.RemoveAll(tmpDir); err != nil {
@@ -38,23 +69,38 @@ func NewCache(root string) (*Cache, error) {
 	}
 
 	cacheDir := filepath.Join(root, "c")
-	if err := os.MkdirAll(cacheDir, 0777); err != nil {
+	manifestDir := filepath.Join(root, "m")
+	resultDir := filepath.Join(root, "r")
+	for _, dir := range []string{cacheDir, manifestDir, resultDir} {
+		for i := 0; i < 256; i++ {
+			d := hex.EncodeToString([]byte{uint8(i)})
+			if err := os.MkdirAll(filepath.Join(dir, d), 0777); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	quarantineDir := filepath.Join(root, "quarantine")
+	if err := os.MkdirAll(quarantineDir, 0777); err != nil {
 		return nil, err
 	}
 
-	for i := 0; i < 256; i++ {
-		d := hex.EncodeToString([]byte{uint8(i)})
-		if err := os.MkdirAll(filepath.Join(cacheDir, d), 0777); err != nil {
-			return nil, err
-		}
+	c := &Cache{
+		tmpDir:        tmpDir,
+		cacheDir:      cacheDir,
+		manifestDir:   manifestDir,
+		resultDir:     resultDir,
+		quarantineDir: quarantineDir,
+		config:        config,
+		writeLocked:   make(map[build.ID]struct{}),
+		readLocked:    make(map[build.ID]int),
+		index:         newIndex(),
 	}
 
-	return &Cache{
-		tmpDir:      tmpDir,
-		cacheDir:    cacheDir,
-		writeLocked: make(map[build.ID]struct{}),
-		readLocked:  make(map[build.ID]int),
-	}, nil
+	if err := c.loadIndex(); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
 func (c *Cache) readLock(id build.ID) error {
@@ -141,7 +187,14 @@ func (c *Cache) Remove(artifact build.ID) error {
 	}
 	defer c.writeUnlock(artifact)
 
-	return os.RemoveAll(filepath.Join(c.cacheDir, artifact.Path()))
+	c.mu.Lock()
+	c.index.remove(artifact)
+	c.mu.Unlock()
+
+	if err := os.RemoveAll(filepath.Join(c.cacheDir, artifact.Path())); err != nil {
+		return err
+	}
+	return c.removeSidecars(artifact)
 }
 
 func (c *Cache) Create(artifact build.ID) (path string, commit, abort func() error, err error) {
@@ -161,13 +214,52 @@ func (c *Cache) Create(artifact build.ID) (path string, commit, abort func() err
 	}
 
 	commit = func() error {
-		defer c.writeUnlock(artifact)
-		return os.Rename(path, filepath.Join(c.cacheDir, artifact.Path()))
+		manifest, err := BuildManifest(path)
+		if err != nil {
+			c.writeUnlock(artifact)
+			return err
+		}
+
+		// Manifest goes first, so that committed artifact always has one.
+		if err := c.writeManifest(artifact, manifest); err != nil {
+			c.writeUnlock(artifact)
+			return err
+		}
+
+		cachePath := filepath.Join(c.cacheDir, artifact.Path())
+		if err := os.Rename(path, cachePath); err != nil {
+			c.writeUnlock(artifact)
+			return err
+		}
+
+		now := time.Now()
+		_ = os.Chtimes(cachePath, now, now)
+
+		c.mu.Lock()
+		c.index.add(artifact, manifest.Size(), now)
+		c.mu.Unlock()
+
+		c.writeUnlock(artifact)
+
+		// Artifact is committed regardless of the eviction result. Eviction is repeated after the next commit.
+		if err := c.evict(&artifact); err != nil {
+			c.config.Logger.Warn("artifact eviction failed", zap.String("id", artifact.String()), zap.Error(err))
+		}
+		return nil
 	}
 
 	return
 }
 
+// Stats returns the number of Get calls, that found and did not find the artifact, since the cache was opened.
+func (c *Cache) Stats() Stats {
+	return Stats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+// Get locks the artifact for read.
+//
+// When Config.Verify is set, the artifact is checked against its manifest. Corrupted artifact is
+// moved to the quarantine directory and ErrCorrupted is returned.
 func (c *Cache) Get(artifact build.ID) (path string, unlock func(), err error) {
 	if err = c.readLock(artifact); err != nil {
 		return
@@ -178,11 +270,33 @@ func (c *Cache) Get(artifact build.ID) (path string, unlock func(), err error) {
 		c.readUnlock(artifact)
 
 		if os.IsNotExist(err) {
+			c.misses.Add(1)
 			err = ErrNotFound
 		}
 		return
 	}
 
+	if c.config.Verify {
+		if err = c.verify(artifact); err != nil {
+			c.readUnlock(artifact)
+
+			if errors.Is(err, ErrCorrupted) {
+				c.misses.Add(1)
+				_ = c.quarantine(artifact)
+			}
+			return
+		}
+	}
+
+	c.hits.Add(1)
+
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+
+	c.mu.Lock()
+	c.index.touch(artifact, now)
+	c.mu.Unlock()
+
 	unlock = func() {
 		c.readUnlock(artifact)
 	}
@@ -5,9 +5,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
 	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 )
@@ -22,10 +24,14 @@ func (c *testCache) cleanup() {
 }
 
 func newTestCache(t *testing.T) *testCache {
+	return newTestCacheWithConfig(t, artifact.Config{})
+}
+
+func newTestCacheWithConfig(t *testing.T, config artifact.Config) *testCache {
 	tmpDir, err := os.MkdirTemp("", "")
 	require.NoError(t, err)
 
-	cache, err := artifact.NewCache(tmpDir)
+	cache, err := artifact.NewCacheWithConfig(tmpDir, config)
 	if err != nil {
 		_ = os.RemoveAll(tmpDir)
 	}
@@ -96,3 +102,229 @@ func TestArtifactExists(t *testing.T) {
 	_, _, _, err = c.Create(idA)
 	require.Truef(t, errors.Is(err, artifact.ErrExists), "%v", err)
 }
+
+func (c *testCache) put(t *testing.T, id build.ID, size int) {
+	path, commit, _, err := c.Create(id)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(path, "a.txt"), make([]byte, size), 0666))
+	require.NoError(t, commit())
+}
+
+func (c *testCache) contains(t *testing.T, id build.ID) bool {
+	_, unlock, err := c.Get(id)
+	if errors.Is(err, artifact.ErrNotFound) {
+		return false
+	}
+	require.NoError(t, err)
+
+	unlock()
+	return true
+}
+
+func TestEvictLeastRecentlyUsed(t *testing.T) {
+	c := newTestCacheWithConfig(t, artifact.Config{MaxEntries: 2})
+
+	var evicted []build.ID
+	c.SetEvictHandler(func(id build.ID) {
+		evicted = append(evicted, id)
+	})
+
+	idA, idB, idC := build.ID{'a'}, build.ID{'b'}, build.ID{'c'}
+
+	c.put(t, idA, 1)
+	c.put(t, idB, 1)
+	require.True(t, c.contains(t, idA))
+
+	c.put(t, idC, 1)
+	require.Equal(t, []build.ID{idB}, evicted)
+
+	require.True(t, c.contains(t, idA))
+	require.False(t, c.contains(t, idB))
+	require.True(t, c.contains(t, idC))
+}
+
+func TestEvictBySize(t *testing.T) {
+	c := newTestCacheWithConfig(t, artifact.Config{MaxBytes: 100})
+
+	idA, idB, idC := build.ID{'a'}, build.ID{'b'}, build.ID{'c'}
+
+	c.put(t, idA, 40)
+	c.put(t, idB, 40)
+	c.put(t, idC, 40)
+
+	require.False(t, c.contains(t, idA))
+	require.True(t, c.contains(t, idB))
+	require.True(t, c.contains(t, idC))
+}
+
+func TestEvictSkipsLockedArtifacts(t *testing.T) {
+	c := newTestCacheWithConfig(t, artifact.Config{MaxEntries: 1})
+
+	idA, idB := build.ID{'a'}, build.ID{'b'}
+
+	c.put(t, idA, 1)
+
+	_, unlock, err := c.Get(idA)
+	require.NoError(t, err)
+
+	c.put(t, idB, 1)
+	require.True(t, c.contains(t, idA))
+	require.True(t, c.contains(t, idB))
+
+	unlock()
+	require.NoError(t, c.Evict())
+
+	require.False(t, c.contains(t, idA))
+	require.True(t, c.contains(t, idB))
+}
+
+func TestEvictAfterReopen(t *testing.T) {
+	c := newTestCache(t)
+
+	idA, idB, idC := build.ID{'a'}, build.ID{'b'}, build.ID{'c'}
+
+	c.put(t, idA, 1)
+	c.put(t, idB, 1)
+	c.put(t, idC, 1)
+
+	// Artifacts are committed too fast to have distinct modification times.
+	old := time.Now().Add(-time.Hour)
+	for i, id := range []build.ID{idB, idA, idC} {
+		mtime := old.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, os.Chtimes(filepath.Join(c.tmpDir, "c", id.Path()), mtime, mtime))
+	}
+
+	reopened, err := artifact.NewCacheWithConfig(c.tmpDir, artifact.Config{MaxEntries: 2})
+	require.NoError(t, err)
+
+	var evicted []build.ID
+	reopened.SetEvictHandler(func(id build.ID) {
+		evicted = append(evicted, id)
+	})
+
+	require.NoError(t, reopened.Evict())
+	require.Equal(t, []build.ID{idB}, evicted)
+}
+
+func TestManifestWrittenOnCommit(t *testing.T) {
+	c := newTestCache(t)
+
+	id := build.ID{'a'}
+	path, commit, _, err := c.Create(id)
+	require.NoError(t, err)
+
+	require.NoError(t, os.Mkdir(filepath.Join(path, "d"), 0777))
+	require.NoError(t, os.WriteFile(filepath.Join(path, "d", "x.txt"), []byte("x"), 0644))
+	require.NoError(t, os.Symlink("x.txt", filepath.Join(path, "d", "y.txt")))
+	require.NoError(t, commit())
+
+	m, err := c.Manifest(id)
+	require.NoError(t, err)
+	require.Equal(t, []artifact.ManifestEntry{
+		{Path: "d", Dir: true},
+		{
+			Path:   "d/x.txt",
+			Mode:   0644,
+			Size:   1,
+			SHA256: "2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881",
+		},
+		{Path: "d/y.txt", Link: "x.txt"},
+	}, m.Files)
+
+	require.NoError(t, c.Remove(id))
+	_, err = c.Manifest(id)
+	require.ErrorIs(t, err, artifact.ErrNotFound)
+}
+
+func TestVerifyQuarantinesCorruptedArtifact(t *testing.T) {
+	c := newTestCacheWithConfig(t, artifact.Config{Verify: true})
+
+	var evicted []build.ID
+	c.SetEvictHandler(func(id build.ID) {
+		evicted = append(evicted, id)
+	})
+
+	idA, idB := build.ID{'a'}, build.ID{'b'}
+	c.put(t, idA, 10)
+	c.put(t, idB, 10)
+	require.True(t, c.contains(t, idA))
+
+	require.NoError(t, os.WriteFile(filepath.Join(c.tmpDir, "c", idA.Path(), "a.txt"), []byte("corrupted"), 0666))
+
+	_, _, err := c.Get(idA)
+	require.ErrorIs(t, err, artifact.ErrCorrupted)
+	require.ErrorIs(t, err, artifact.ErrNotFound)
+	require.Equal(t, []build.ID{idA}, evicted)
+
+	quarantined, err := os.ReadDir(filepath.Join(c.tmpDir, "quarantine"))
+	require.NoError(t, err)
+	require.Len(t, quarantined, 1)
+
+	require.False(t, c.contains(t, idA))
+	require.True(t, c.contains(t, idB))
+
+	// Quarantined artifact is fetched again.
+	c.put(t, idA, 10)
+	require.True(t, c.contains(t, idA))
+}
+
+func TestManifestCreatedAfterReopen(t *testing.T) {
+	c := newTestCache(t)
+
+	id := build.ID{'a'}
+	c.put(t, id, 10)
+
+	// Artifact committed before manifests were introduced.
+	require.NoError(t, os.Remove(filepath.Join(c.tmpDir, "m", id.Path()+".json")))
+
+	reopened, err := artifact.NewCacheWithConfig(c.tmpDir, artifact.Config{Verify: true})
+	require.NoError(t, err)
+
+	_, unlock, err := reopened.Get(id)
+	require.NoError(t, err)
+	unlock()
+}
+
+func TestResultRemovedWithArtifact(t *testing.T) {
+	c := newTestCache(t)
+
+	id := build.ID{0x01}
+	res := &api.JobResult{ID: id, Stdout: []byte("PASS\n")}
+
+	require.ErrorIs(t, c.WriteResult(res), artifact.ErrNotFound)
+
+	_, commit, _, err := c.Create(id)
+	require.NoError(t, err)
+	require.NoError(t, commit())
+
+	require.NoError(t, c.WriteResult(res))
+
+	stored, err := c.Result(id)
+	require.NoError(t, err)
+	require.Equal(t, res, stored)
+
+	require.NoError(t, c.Remove(id))
+
+	_, err = c.Result(id)
+	require.ErrorIs(t, err, artifact.ErrNotFound)
+}
+
+func TestManifestDiff(t *testing.T) {
+	a := &artifact.Manifest{Files: []artifact.ManifestEntry{
+		{Path: "bin", Dir: true},
+		{Path: "bin/tool", Mode: 0755, Size: 3, SHA256: "aa"},
+		{Path: "stamp", Mode: 0644, Size: 8, SHA256: "bb"},
+		{Path: "old.txt", Mode: 0644, Size: 1, SHA256: "cc"},
+	}}
+	b := &artifact.Manifest{Files: []artifact.ManifestEntry{
+		{Path: "bin", Dir: true},
+		{Path: "bin/tool", Mode: 0644, Size: 3, SHA256: "aa"},
+		{Path: "new.txt", Mode: 0644, Size: 1, SHA256: "cc"},
+		{Path: "stamp", Mode: 0644, Size: 8, SHA256: "dd"},
+	}}
+
+	require.Equal(t, []string{"bin/tool", "new.txt", "old.txt", "stamp"}, a.Diff(b))
+	require.Equal(t, a.Diff(b), b.Diff(a))
+	require.Empty(t, a.Diff(a))
+}
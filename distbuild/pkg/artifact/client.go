@@ -4,11 +4,191 @@ package artifact
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
 
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/tarstream"
 )
 
+// downloadAttempts limits the number of requests made by a single Download.
+const downloadAttempts = 3
+
+// Client downloads artifacts and their sidecars from the remote caches.
+type Client struct {
+	client *http.Client
+}
+
+// NewClient creates client sending requests through c, for example to present credentials.
+func NewClient(c *http.Client) *Client {
+	return &Client{client: c}
+}
+
+var defaultClient = NewClient(http.DefaultClient)
+
+// CloseIdleConnections closes kept-alive connections to the remote caches.
+func (cl *Client) CloseIdleConnections() {
+	cl.client.CloseIdleConnections()
+}
+
 // Download artifact from remote cache into local cache.
+//
+// When the remote cache sends the manifest digest, received files are checked against it
+// and ErrCorrupted is returned on mismatch. Interrupted transfer of such artifact is resumed
+// from the last received file.
 func Download(ctx context.Context, endpoint string, c *Cache, artifactID build.ID) error {
-	panic("implement me")
+	return defaultClient.Download(ctx, endpoint, c, artifactID)
+}
+
+// Download is the same as the package level Download, but uses the http client of cl.
+func (cl *Client) Download(ctx context.Context, endpoint string, c *Cache, artifactID build.ID) error {
+	var (
+		path          string
+		commit, abort func() error
+		receiver      *tarstream.Receiver
+		digest        string
+	)
+
+	for attempt := 1; ; attempt++ {
+		rsp, err := cl.requestArtifact(ctx, endpoint, artifactID, receiver, digest)
+		if err != nil {
+			if abort != nil {
+				_ = abort()
+			}
+			return err
+		}
+
+		if rsp.StatusCode != http.StatusPartialContent && receiver != nil {
+			// Remote cache does not continue the transfer, start over.
+			_ = abort()
+			receiver = nil
+		}
+
+		if receiver == nil {
+			path, commit, abort, err = c.Create(artifactID)
+			if err != nil {
+				_ = rsp.Body.Close()
+				return err
+			}
+
+			receiver = tarstream.NewReceiver(path)
+			digest = rsp.Header.Get(ManifestHeader)
+		}
+
+		err = receiveArtifact(rsp, receiver)
+		_ = rsp.Body.Close()
+		if err == nil {
+			break
+		}
+
+		if ctx.Err() != nil || digest == "" || attempt == downloadAttempts {
+			_ = abort()
+			return fmt.Errorf("download artifact %v: %w", artifactID, err)
+		}
+	}
+
+	if digest != "" {
+		m, err := BuildManifest(path)
+		if err != nil {
+			_ = abort()
+			return fmt.Errorf("download artifact %v: %w", artifactID, err)
+		}
+
+		if m.Digest() != digest {
+			_ = abort()
+			return fmt.Errorf("download artifact %v: %w", artifactID, ErrCorrupted)
+		}
+	}
+
+	return commit()
+}
+
+// requestArtifact starts the transfer, continuing it from the receiver offset when receiver is not nil.
+func (cl *Client) requestArtifact(ctx context.Context, endpoint string, artifactID build.ID, receiver *tarstream.Receiver, digest string) (*http.Response, error) {
+	u := endpoint + "/artifact?id=" + url.QueryEscape(artifactID.String())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Setting the header disables transparent decompression of the transport.
+	req.Header.Set("Accept-Encoding", tarstream.AcceptEncoding)
+	if receiver != nil && receiver.Offset() > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", receiver.Offset()))
+		req.Header.Set("If-Range", digest)
+	}
+
+	rsp, err := cl.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if rsp.StatusCode != http.StatusOK && rsp.StatusCode != http.StatusPartialContent {
+		defer rsp.Body.Close()
+
+		body, _ := io.ReadAll(rsp.Body)
+		return nil, fmt.Errorf("download artifact %v: http status %d: %s", artifactID, rsp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return rsp, nil
+}
+
+func receiveArtifact(rsp *http.Response, receiver *tarstream.Receiver) error {
+	r, err := tarstream.NewReader(rsp.Body, rsp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return receiver.Receive(r)
+}
+
+// DownloadResult fetches the job result stored next to the artifact in the remote cache.
+func (cl *Client) DownloadResult(ctx context.Context, endpoint string, artifactID build.ID) (*api.JobResult, error) {
+	var res api.JobResult
+	if err := cl.downloadSidecar(ctx, endpoint+"/artifact/result", "job result", artifactID, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// DownloadManifest fetches the manifest of the artifact in the remote cache.
+func (cl *Client) DownloadManifest(ctx context.Context, endpoint string, artifactID build.ID) (*Manifest, error) {
+	var m Manifest
+	if err := cl.downloadSidecar(ctx, endpoint+"/artifact/manifest", "manifest", artifactID, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (cl *Client) downloadSidecar(ctx context.Context, u, what string, artifactID build.ID, v any) error {
+	u += "?id=" + url.QueryEscape(artifactID.String())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	rsp, err := cl.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	switch rsp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return ErrNotFound
+	default:
+		body, _ := io.ReadAll(rsp.Body)
+		return fmt.Errorf("download %s %v: http status %d: %s", what, artifactID, rsp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.NewDecoder(rsp.Body).Decode(v); err != nil {
+		return fmt.Errorf("download %s %v: %w", what, artifactID, err)
+	}
+	return nil
 }
@@ -2,6 +2,7 @@ package artifact_test
 
 import (
 	"context"
+	"crypto/rand"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -24,6 +25,7 @@ func TestArtifactTransfer(t *testing.T) {
 	dir, commit, _, err := remoteCache.Create(id)
 	require.NoError(t, err)
 	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("foobar"), 0777))
+	require.NoError(t, os.Symlink("a.txt", filepath.Join(dir, "b.txt")))
 	require.NoError(t, commit())
 
 	l := zaptest.NewLogger(t)
@@ -46,6 +48,101 @@ func TestArtifactTransfer(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, []byte("foobar"), content)
 
+	target, err := os.Readlink(filepath.Join(dir, "b.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "a.txt", target)
+
 	err = artifact.Download(ctx, server.URL, localCache.Cache, build.ID{0x02})
 	require.Error(t, err)
 }
+
+func TestArtifactTransferCorrupted(t *testing.T) {
+	remoteCache := newTestCache(t)
+	localCache := newTestCache(t)
+
+	id := build.ID{0x01}
+
+	dir, commit, _, err := remoteCache.Create(id)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("foobar"), 0777))
+	require.NoError(t, commit())
+
+	// Disk corruption on the remote side is not noticed until the transfer.
+	require.NoError(t, os.WriteFile(filepath.Join(remoteCache.tmpDir, "c", id.Path(), "a.txt"), []byte("foo"), 0777))
+
+	mux := http.NewServeMux()
+	artifact.NewHandler(zaptest.NewLogger(t), remoteCache.Cache).Register(mux)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	err = artifact.Download(context.Background(), server.URL, localCache.Cache, id)
+	require.ErrorIs(t, err, artifact.ErrCorrupted)
+
+	_, _, err = localCache.Get(id)
+	require.ErrorIs(t, err, artifact.ErrNotFound)
+}
+
+// breakingWriter aborts the response after the limit is reached.
+type breakingWriter struct {
+	http.ResponseWriter
+	limit int
+}
+
+func (w *breakingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		_, _ = w.ResponseWriter.Write(p[:w.limit])
+		w.ResponseWriter.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+
+	w.limit -= len(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func TestArtifactTransferResumed(t *testing.T) {
+	remoteCache := newTestCache(t)
+	localCache := newTestCache(t)
+
+	id := build.ID{0x01}
+
+	// Random content does not compress, so the response breaks in the middle of the second file.
+	content := make([]byte, 256*1024)
+	_, _ = rand.Read(content)
+
+	dir, commit, _, err := remoteCache.Create(id)
+	require.NoError(t, err)
+	for _, name := range []string{"a.bin", "b.bin"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0666))
+	}
+	require.NoError(t, commit())
+
+	mux := http.NewServeMux()
+	artifact.NewHandler(zaptest.NewLogger(t), remoteCache.Cache).Register(mux)
+
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if len(ranges) == 1 {
+			w = &breakingWriter{ResponseWriter: w, limit: len(content) * 3 / 2}
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	require.NoError(t, artifact.Download(context.Background(), server.URL, localCache.Cache, id))
+
+	require.Len(t, ranges, 2)
+	require.Equal(t, "", ranges[0])
+	require.NotEmpty(t, ranges[1])
+
+	dir, unlock, err := localCache.Get(id)
+	require.NoError(t, err)
+	defer unlock()
+
+	for _, name := range []string{"a.bin", "b.bin"} {
+		received, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		require.Equal(t, content, received)
+	}
+}
@@ -0,0 +1,197 @@
+package artifact
+
+import (
+	"container/list"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// Config limits the size of the cache.
+//
+// When the limit is exceeded, least recently used artifacts are evicted. Artifacts locked for
+// read or write are never evicted. Zero value of the field disables the corresponding limit.
+type Config struct {
+	// MaxBytes limits total size of the files stored in the cache.
+	MaxBytes int64
+
+	// MaxEntries limits the number of artifacts stored in the cache.
+	MaxEntries int
+
+	// Verify makes Get check every artifact against its manifest before returning it.
+	//
+	// Verification rereads all files of the artifact, so it is meant for caches on unreliable disks.
+	Verify bool
+
+	// Logger receives errors of the eviction, that runs after each commit. Default is zap.NewNop().
+	Logger *zap.Logger
+}
+
+type indexEntry struct {
+	id         build.ID
+	size       int64
+	lastAccess time.Time
+}
+
+// index tracks size and access order of the committed artifacts.
+type index struct {
+	size    int64
+	order   *list.List
+	entries map[build.ID]*list.Element
+}
+
+func newIndex() *index {
+	return &index{
+		order:   list.New(),
+		entries: make(map[build.ID]*list.Element),
+	}
+}
+
+// add inserts artifact as the most recently used one.
+func (i *index) add(id build.ID, size int64, lastAccess time.Time) {
+	i.remove(id)
+
+	e := &indexEntry{id: id, size: size, lastAccess: lastAccess}
+	i.entries[id] = i.order.PushFront(e)
+	i.size += size
+}
+
+func (i *index) touch(id build.ID, now time.Time) {
+	if elem, ok := i.entries[id]; ok {
+		elem.Value.(*indexEntry).lastAccess = now
+		i.order.MoveToFront(elem)
+	}
+}
+
+func (i *index) remove(id build.ID) {
+	if elem, ok := i.entries[id]; ok {
+		i.size -= elem.Value.(*indexEntry).size
+		i.order.Remove(elem)
+		delete(i.entries, id)
+	}
+}
+
+// loadIndex restores the index from the cache directory.
+//
+// Last access time of the artifact is stored as modification time of the artifact directory.
+// Manifests are created for artifacts committed before manifests were introduced.
+func (c *Cache) loadIndex() error {
+	var entries []indexEntry
+	err := c.Range(func(id build.ID) error {
+		path := filepath.Join(c.cacheDir, id.Path())
+
+		st, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		m, err := c.Manifest(id)
+		if errors.Is(err, ErrNotFound) {
+			if m, err = BuildManifest(path); err == nil {
+				err = c.writeManifest(id, m)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, indexEntry{id: id, size: m.Size(), lastAccess: st.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].lastAccess.Before(entries[j].lastAccess)
+	})
+
+	for _, e := range entries {
+		c.index.add(e.id, e.size, e.lastAccess)
+	}
+	return nil
+}
+
+// SetEvictHandler registers function, that is called after an artifact is evicted from the cache.
+func (c *Cache) SetEvictHandler(onEvict func(artifact build.ID)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.onEvict = onEvict
+}
+
+func (c *Cache) overQuota() bool {
+	return (c.config.MaxBytes > 0 && c.index.size > c.config.MaxBytes) ||
+		(c.config.MaxEntries > 0 && len(c.index.entries) > c.config.MaxEntries)
+}
+
+// pickVictim selects least recently used unlocked artifact and locks it for write.
+func (c *Cache) pickVictim(keep *build.ID) (build.ID, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.overQuota() {
+		return build.ID{}, false
+	}
+
+	for elem := c.index.order.Back(); elem != nil; elem = elem.Prev() {
+		id := elem.Value.(*indexEntry).id
+		if keep != nil && *keep == id {
+			continue
+		}
+
+		if _, ok := c.writeLocked[id]; ok {
+			continue
+		}
+		if c.readLocked[id] > 0 {
+			continue
+		}
+
+		c.writeLocked[id] = struct{}{}
+		c.index.remove(id)
+		return id, true
+	}
+
+	return build.ID{}, false
+}
+
+// evict removes artifacts until the cache fits into the limits. Artifact keep is never evicted.
+func (c *Cache) evict(keep *build.ID) error {
+	for {
+		id, ok := c.pickVictim(keep)
+		if !ok {
+			return nil
+		}
+
+		err := os.RemoveAll(filepath.Join(c.cacheDir, id.Path()))
+		if err == nil {
+			err = c.removeSidecars(id)
+		}
+		c.writeUnlock(id)
+		if err != nil {
+			return err
+		}
+
+		c.mu.Lock()
+		onEvict := c.onEvict
+		c.mu.Unlock()
+
+		if onEvict != nil {
+			onEvict(id)
+		}
+	}
+}
+
+// Evict removes least recently used artifacts until the cache fits into the limits.
+//
+// Eviction happens automatically after each commit. Evict is useful after the cache is
+// opened with lower limits.
+func (c *Cache) Evict() error {
+	return c.evict(nil)
+}
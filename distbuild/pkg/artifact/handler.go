@@ -3,18 +3,159 @@
 package artifact
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/tarstream"
 )
 
 type Handler struct {
+	l *zap.Logger
+	c *Cache
 }
 
 func NewHandler(l *zap.Logger, c *Cache) *Handler {
-	panic("implement me")
+	return &Handler{l: l, c: c}
 }
 
 func (h *Handler) Register(mux *http.ServeMux) {
-	panic("implement me")
+	mux.HandleFunc("/artifact", h.artifact)
+	mux.HandleFunc("/artifact/result", h.result)
+	mux.HandleFunc("/artifact/manifest", h.manifest)
+}
+
+func (h *Handler) artifact(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var id build.ID
+	if err := id.UnmarshalText([]byte(r.URL.Query().Get("id"))); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	path, unlock, err := h.c.Get(id)
+	if err != nil {
+		h.l.Warn("artifact is not available", zap.String("id", id.String()), zap.Error(err))
+
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrNotFound) {
+			code = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	defer unlock()
+
+	digest := ""
+	if m, err := h.c.Manifest(id); err == nil {
+		digest = m.Digest()
+		w.Header().Set(ManifestHeader, digest)
+	} else {
+		h.l.Warn("artifact manifest is not available", zap.String("id", id.String()), zap.Error(err))
+	}
+
+	// Resume is allowed only when the client continues the same version of the artifact.
+	var offset int64
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" && digest != "" && r.Header.Get("If-Range") == digest {
+		if offset, err = parseRange(rangeHeader); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+	}
+
+	encoding := tarstream.NegotiateEncoding(r.Header.Get("Accept-Encoding"))
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	// Total length of the stream is not known in advance, so the partial response has no Content-Range.
+	if offset > 0 {
+		w.WriteHeader(http.StatusPartialContent)
+	}
+
+	h.l.Debug("sending artifact",
+		zap.String("id", id.String()),
+		zap.Int64("offset", offset),
+		zap.String("encoding", encoding))
+
+	cw, err := tarstream.NewWriter(w, encoding)
+	if err != nil {
+		h.l.Error("failed to send artifact", zap.String("id", id.String()), zap.Error(err))
+		return
+	}
+
+	if err := tarstream.SendFrom(path, cw, offset); err != nil {
+		h.l.Error("failed to send artifact", zap.String("id", id.String()), zap.Error(err))
+		return
+	}
+
+	if err := cw.Close(); err != nil {
+		h.l.Error("failed to send artifact", zap.String("id", id.String()), zap.Error(err))
+	}
+}
+
+// parseRange parses the open ended range "bytes=N-", the only form sent by Download.
+func parseRange(header string) (int64, error) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || !strings.HasSuffix(spec, "-") {
+		return 0, fmt.Errorf("unsupported range %q", header)
+	}
+
+	offset, err := strconv.ParseInt(strings.TrimSuffix(spec, "-"), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid range %q", header)
+	}
+	return offset, nil
+}
+
+func (h *Handler) result(w http.ResponseWriter, r *http.Request) {
+	h.sendSidecar(w, r, "job result", func(id build.ID) (any, error) {
+		return h.c.Result(id)
+	})
+}
+
+func (h *Handler) manifest(w http.ResponseWriter, r *http.Request) {
+	h.sendSidecar(w, r, "manifest", func(id build.ID) (any, error) {
+		return h.c.Manifest(id)
+	})
+}
+
+// sendSidecar sends JSON file stored next to the artifact.
+func (h *Handler) sendSidecar(w http.ResponseWriter, r *http.Request, what string, get func(id build.ID) (any, error)) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var id build.ID
+	if err := id.UnmarshalText([]byte(r.URL.Query().Get("id"))); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	v, err := get(id)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrNotFound) {
+			code = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.l.Warn("failed to send "+what, zap.String("id", id.String()), zap.Error(err))
+	}
 }
@@ -0,0 +1,242 @@
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// ErrCorrupted is returned when the content of the artifact does not match its manifest.
+//
+// Corrupted artifact is treated as missing, so callers checking ErrNotFound fetch it again.
+var ErrCorrupted = fmt.Errorf("%w: content does not match manifest", ErrNotFound)
+
+// ManifestHeader carries the manifest digest of the artifact sent by Handler.
+const ManifestHeader = "X-Artifact-Manifest"
+
+// ManifestEntry describes a single file, directory or symlink of the artifact.
+type ManifestEntry struct {
+	Path string
+	Dir  bool `json:",omitempty"`
+
+	// Link is the target of the symlink. Symlinks are not followed.
+	Link string `json:",omitempty"`
+
+	// Mode, Size and SHA256 are set for regular files only.
+	Mode   os.FileMode `json:",omitempty"`
+	Size   int64       `json:",omitempty"`
+	SHA256 string      `json:",omitempty"`
+}
+
+// Manifest lists the files of the artifact.
+//
+// Manifest is written when the artifact is committed to the cache and is used to detect
+// truncated transfers and disk corruption.
+type Manifest struct {
+	// Files are sorted by path.
+	Files []ManifestEntry
+}
+
+// BuildManifest hashes the content of the directory.
+func BuildManifest(dir string) (*Manifest, error) {
+	m := &Manifest{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		if rel == "." {
+			return nil
+		}
+
+		rel = filepath.ToSlash(rel)
+		if info.IsDir() {
+			m.Files = append(m.Files, ManifestEntry{Path: rel, Dir: true})
+			return nil
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+
+			m.Files = append(m.Files, ManifestEntry{Path: rel, Link: target})
+			return nil
+		}
+
+		sum, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+
+		m.Files = append(m.Files, ManifestEntry{
+			Path:   rel,
+			Mode:   info.Mode().Perm(),
+			Size:   info.Size(),
+			SHA256: sum,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(m.Files, func(i, j int) bool {
+		return m.Files[i].Path < m.Files[j].Path
+	})
+	return m, nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Size returns total size of the files.
+func (m *Manifest) Size() int64 {
+	var size int64
+	for _, f := range m.Files {
+		size += f.Size
+	}
+	return size
+}
+
+// Digest identifies the content of the artifact.
+func (m *Manifest) Digest() string {
+	data, _ := json.Marshal(m)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks that the directory matches the manifest.
+func (m *Manifest) Verify(dir string) error {
+	actual, err := BuildManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	if actual.Digest() != m.Digest() {
+		return ErrCorrupted
+	}
+	return nil
+}
+
+// Diff returns sorted paths of the files, that differ between two manifests.
+//
+// Files present in only one of the manifests are included.
+func (m *Manifest) Diff(other *Manifest) []string {
+	entries := make(map[string]ManifestEntry, len(m.Files))
+	for _, f := range m.Files {
+		entries[f.Path] = f
+	}
+
+	var diff []string
+	for _, f := range other.Files {
+		if e, ok := entries[f.Path]; !ok || e != f {
+			diff = append(diff, f.Path)
+		}
+		delete(entries, f.Path)
+	}
+	for path := range entries {
+		diff = append(diff, path)
+	}
+
+	sort.Strings(diff)
+	return diff
+}
+
+func (c *Cache) manifestPath(id build.ID) string {
+	return filepath.Join(c.manifestDir, id.Path()+".json")
+}
+
+func (c *Cache) writeManifest(id build.ID, m *Manifest) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	// Manifest is replaced atomically, so that a crash never leaves a truncated manifest.
+	tmp := filepath.Join(c.tmpDir, id.String()+".manifest")
+	if err := os.WriteFile(tmp, data, 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.manifestPath(id))
+}
+
+// Manifest returns the manifest of the committed artifact.
+func (c *Cache) Manifest(id build.ID) (*Manifest, error) {
+	data, err := os.ReadFile(c.manifestPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("artifact %v: invalid manifest: %w", id, err)
+	}
+	return &m, nil
+}
+
+// verify checks the artifact against its manifest.
+func (c *Cache) verify(id build.ID) error {
+	m, err := c.Manifest(id)
+	if errors.Is(err, ErrNotFound) {
+		return ErrCorrupted
+	} else if err != nil {
+		return err
+	}
+
+	return m.Verify(filepath.Join(c.cacheDir, id.Path()))
+}
+
+// quarantine moves the corrupted artifact out of the cache, keeping it for investigation.
+//
+// Artifact locked by other readers is left in place, the next Get tries again.
+func (c *Cache) quarantine(id build.ID) error {
+	if err := c.writeLock(id, true); err != nil {
+		return err
+	}
+	defer c.writeUnlock(id)
+
+	c.mu.Lock()
+	c.index.remove(id)
+	onEvict := c.onEvict
+	c.mu.Unlock()
+
+	dst := filepath.Join(c.quarantineDir, fmt.Sprintf("%s-%d", id, time.Now().UnixNano()))
+	if err := os.Rename(filepath.Join(c.cacheDir, id.Path()), dst); err != nil {
+		return err
+	}
+	_ = c.removeSidecars(id)
+
+	if onEvict != nil {
+		onEvict(id)
+	}
+	return nil
+}
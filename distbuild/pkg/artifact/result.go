@@ -0,0 +1,71 @@
+package artifact
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+func (c *Cache) resultPath(id build.ID) string {
+	return filepath.Join(c.resultDir, id.Path()+".json")
+}
+
+// WriteResult stores the result of the job, that produced the artifact.
+//
+// Result is kept next to the artifact and is removed together with it, so that the output of
+// the job can be shown again when the artifact is reused.
+func (c *Cache) WriteResult(res *api.JobResult) error {
+	// Read lock keeps the artifact from being removed while the result is written.
+	if err := c.readLock(res.ID); err != nil {
+		return err
+	}
+	defer c.readUnlock(res.ID)
+
+	if _, err := os.Stat(filepath.Join(c.cacheDir, res.ID.Path())); os.IsNotExist(err) {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(c.tmpDir, res.ID.String()+".result")
+	if err := os.WriteFile(tmp, data, 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.resultPath(res.ID))
+}
+
+// Result returns the job result stored by WriteResult.
+func (c *Cache) Result(id build.ID) (*api.JobResult, error) {
+	data, err := os.ReadFile(c.resultPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	var res api.JobResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf("artifact %v: invalid job result: %w", id, err)
+	}
+	return &res, nil
+}
+
+// removeSidecars removes files kept next to the artifact.
+func (c *Cache) removeSidecars(id build.ID) error {
+	for _, path := range []string{c.manifestPath(id), c.resultPath(id)} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
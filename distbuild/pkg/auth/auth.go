@@ -0,0 +1,219 @@
+// Package auth protects the traffic between distbuild components.
+//
+// Components authenticate each other with mutual TLS: every server and client presents a certificate
+// signed by the cluster CA. Build clients, that do not hold a certificate, may authenticate with
+// a bearer token instead.
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Config describes the credentials of a single component.
+//
+// Nil or empty Config disables authentication, the component speaks plain HTTP and accepts all requests.
+type Config struct {
+	// TLS holds the certificate of the component and the cluster CA in RootCAs.
+	//
+	// Servers require the client certificate signed by RootCAs, unless Tokens are set.
+	TLS *tls.Config
+
+	// Token is sent by the client in the Authorization header.
+	Token string
+
+	// Tokens are accepted by the server from the clients without a certificate. Tokens are accepted
+	// and sent only over TLS.
+	Tokens []string
+}
+
+// Peer is the authenticated sender of the request.
+type Peer struct {
+	// Certificate is the verified client certificate. It is nil for the peers authenticated by a token.
+	Certificate *x509.Certificate
+}
+
+type peerKey struct{}
+
+// PeerFromContext returns the peer authenticated by Config.Handler.
+func PeerFromContext(ctx context.Context) (Peer, bool) {
+	p, ok := ctx.Value(peerKey{}).(Peer)
+	return p, ok
+}
+
+// Enabled reports whether the server checks the credentials of the requests.
+func (c *Config) Enabled() bool {
+	return c != nil && (c.TLS != nil || len(c.Tokens) != 0)
+}
+
+// CheckServer returns an error, when the server with this config can not authenticate the requests safely.
+//
+// Server accepting only bearer tokens would receive them over plain HTTP, and would have no way to tell
+// the workers from the build clients.
+func (c *Config) CheckServer() error {
+	if c.Enabled() && c.TLS == nil {
+		return errors.New("bearer tokens require TLS: set the cluster CA and the certificate")
+	}
+	return nil
+}
+
+// HTTPClient returns the client presenting the credentials to the servers.
+func (c *Config) HTTPClient() *http.Client {
+	if c == nil || (c.TLS == nil && c.Token == "") {
+		return http.DefaultClient
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if c.TLS != nil {
+		transport.TLSClientConfig = c.TLS.Clone()
+	}
+
+	if c.Token == "" {
+		return &http.Client{Transport: transport}
+	}
+	return &http.Client{Transport: &tokenTransport{token: c.Token, next: transport}}
+}
+
+type tokenTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Scheme != "https" {
+		if r.Body != nil {
+			_ = r.Body.Close()
+		}
+		return nil, fmt.Errorf("bearer token is sent only over https: %s", r.URL.Redacted())
+	}
+
+	// RoundTripper must not modify the request.
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+t.token)
+	return t.next.RoundTrip(r)
+}
+
+// ServerTLS returns the configuration of the TLS listener. It returns nil when TLS is disabled.
+func (c *Config) ServerTLS() *tls.Config {
+	if c == nil || c.TLS == nil {
+		return nil
+	}
+
+	cfg := c.TLS.Clone()
+	cfg.ClientCAs = c.TLS.RootCAs
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	if len(c.Tokens) != 0 {
+		// Clients without a certificate are checked by Handler.
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return cfg
+}
+
+// Handler rejects requests without a verified client certificate or a valid bearer token.
+//
+// Authenticated peer is available to h through PeerFromContext.
+func (c *Config) Handler(h http.Handler) http.Handler {
+	if !c.Enabled() {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer, ok := c.authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="distbuild"`)
+			http.Error(w, "unauthenticated", http.StatusUnauthorized)
+			return
+		}
+
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), peerKey{}, peer)))
+	})
+}
+
+func (c *Config) authenticate(r *http.Request) (Peer, bool) {
+	return c.check(r.TLS, r.Header.Get("Authorization"))
+}
+
+// check authenticates the peer by the state of the TLS connection or by the Authorization header.
+func (c *Config) check(state *tls.ConnectionState, authorization string) (Peer, bool) {
+	if state != nil && len(state.VerifiedChains) != 0 {
+		return Peer{Certificate: state.VerifiedChains[0][0]}, true
+	}
+
+	// Token sent over plain HTTP is compromised, it is not accepted even if valid.
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok || token == "" || state == nil {
+		return Peer{}, false
+	}
+
+	for _, valid := range c.Tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(valid)) == 1 {
+			return Peer{}, true
+		}
+	}
+	return Peer{}, false
+}
+
+// LoadTLS reads PEM encoded certificate of the cluster CA and the certificate and the key of the component.
+//
+// certFile and keyFile may be empty for the build clients, that authenticate with a token.
+func LoadTLS(caFile, certFile, keyFile string) (*tls.Config, error) {
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("%s: no certificates found", caFile)
+	}
+
+	cfg := &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}
+	if certFile == "" && keyFile == "" {
+		return cfg, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Certificates = []tls.Certificate{cert}
+	return cfg, nil
+}
+
+// LoadTokens reads tokens from the file, one per line. Empty lines and lines starting with # are skipped.
+func LoadTokens(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var tokens []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tokens = append(tokens, line)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, errors.New(path + ": no tokens found")
+	}
+	return tokens, nil
+}
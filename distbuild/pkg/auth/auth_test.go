@@ -0,0 +1,142 @@
+package auth_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+)
+
+// newServer starts TLS server replying with the common name of the client certificate.
+func newServer(t *testing.T, ca *auth.CA, tokens []string) *httptest.Server {
+	serverTLS, err := ca.TLSConfig("server", "127.0.0.1")
+	require.NoError(t, err)
+
+	cfg := &auth.Config{TLS: serverTLS, Tokens: tokens}
+	server := httptest.NewUnstartedServer(cfg.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer, ok := auth.PeerFromContext(r.Context())
+		assert.True(t, ok)
+
+		if peer.Certificate != nil {
+			_, _ = w.Write([]byte(peer.Certificate.Subject.CommonName))
+		}
+	})))
+	server.TLS = cfg.ServerTLS()
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func get(t *testing.T, cfg *auth.Config, u string) (int, error) {
+	c := cfg.HTTPClient()
+	defer c.CloseIdleConnections()
+
+	rsp, err := c.Get(u)
+	if err != nil {
+		return 0, err
+	}
+	defer rsp.Body.Close()
+	return rsp.StatusCode, nil
+}
+
+func TestHandler(t *testing.T) {
+	ca, err := auth.NewCA()
+	require.NoError(t, err)
+
+	server := newServer(t, ca, []string{"secret"})
+
+	clientTLS, err := ca.TLSConfig("worker0")
+	require.NoError(t, err)
+
+	code, err := get(t, &auth.Config{TLS: clientTLS}, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+
+	anonymous := &tls.Config{RootCAs: ca.Pool()}
+
+	code, err = get(t, &auth.Config{TLS: anonymous, Token: "secret"}, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, err = get(t, &auth.Config{TLS: anonymous, Token: "guess"}, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, err = get(t, &auth.Config{TLS: anonymous}, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestHandlerRequiresCertificate(t *testing.T) {
+	ca, err := auth.NewCA()
+	require.NoError(t, err)
+
+	server := newServer(t, ca, nil)
+
+	_, err = get(t, &auth.Config{TLS: &tls.Config{RootCAs: ca.Pool()}, Token: "secret"}, server.URL)
+	require.Error(t, err)
+
+	otherCA, err := auth.NewCA()
+	require.NoError(t, err)
+
+	otherTLS, err := otherCA.TLSConfig("worker0")
+	require.NoError(t, err)
+	otherTLS.RootCAs = ca.Pool()
+
+	_, err = get(t, &auth.Config{TLS: otherTLS}, server.URL)
+	require.Error(t, err)
+}
+
+func TestTokenRequiresTLS(t *testing.T) {
+	cfg := &auth.Config{Tokens: []string{"secret"}}
+	require.Error(t, cfg.CheckServer())
+
+	server := httptest.NewServer(cfg.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	defer server.Close()
+
+	_, err := get(t, &auth.Config{Token: "secret"}, server.URL)
+	require.Error(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	rsp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, rsp.StatusCode)
+
+	ca, err := auth.NewCA()
+	require.NoError(t, err)
+	serverTLS, err := ca.TLSConfig("server", "127.0.0.1")
+	require.NoError(t, err)
+	require.NoError(t, (&auth.Config{TLS: serverTLS, Tokens: []string{"secret"}}).CheckServer())
+}
+
+func TestDisabled(t *testing.T) {
+	var cfg *auth.Config
+	assert.False(t, cfg.Enabled())
+	assert.Nil(t, cfg.ServerTLS())
+	assert.Same(t, http.DefaultClient, cfg.HTTPClient())
+}
+
+func TestLoadTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens")
+	require.NoError(t, os.WriteFile(path, []byte("# ci\nfirst\n\n  second  \n"), 0600))
+
+	tokens, err := auth.LoadTokens(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, tokens)
+
+	require.NoError(t, os.WriteFile(path, []byte("# nothing\n"), 0600))
+
+	_, err = auth.LoadTokens(path)
+	require.Error(t, err)
+}
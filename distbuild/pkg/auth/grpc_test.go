@@ -0,0 +1,84 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+)
+
+// newGRPCServer starts gRPC server, that records the common name of the authenticated client certificate.
+func newGRPCServer(t *testing.T, cfg *auth.Config, names chan<- string) string {
+	lsn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	record := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		peer, ok := auth.PeerFromContext(ctx)
+		assert.True(t, ok)
+
+		name := ""
+		if peer.Certificate != nil {
+			name = peer.Certificate.Subject.CommonName
+		}
+		names <- name
+		return handler(ctx, req)
+	}
+
+	server := grpc.NewServer(append(cfg.GRPCServerOptions(), grpc.ChainUnaryInterceptor(record))...)
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+	go func() { _ = server.Serve(lsn) }()
+	t.Cleanup(server.Stop)
+
+	return lsn.Addr().String()
+}
+
+func check(t *testing.T, cfg *auth.Config, addr string) error {
+	conn, err := grpc.Dial(addr, cfg.GRPCDialOptions()...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = grpc_health_v1.NewHealthClient(conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	return err
+}
+
+func TestGRPC(t *testing.T) {
+	ca, err := auth.NewCA()
+	require.NoError(t, err)
+
+	serverTLS, err := ca.TLSConfig("server", "127.0.0.1")
+	require.NoError(t, err)
+
+	names := make(chan string, 1)
+	addr := newGRPCServer(t, &auth.Config{TLS: serverTLS, Tokens: []string{"secret"}}, names)
+
+	clientTLS, err := ca.TLSConfig("worker0")
+	require.NoError(t, err)
+
+	require.NoError(t, check(t, &auth.Config{TLS: clientTLS}, addr))
+	assert.Equal(t, "worker0", <-names)
+
+	anonymous := &tls.Config{RootCAs: ca.Pool()}
+	require.NoError(t, check(t, &auth.Config{TLS: anonymous, Token: "secret"}, addr))
+	assert.Equal(t, "", <-names)
+
+	err = check(t, &auth.Config{TLS: anonymous, Token: "guess"}, addr)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	err = check(t, &auth.Config{TLS: anonymous}, addr)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// Token is not sent over the insecure connection.
+	require.Error(t, check(t, &auth.Config{Token: "secret"}, addr))
+}
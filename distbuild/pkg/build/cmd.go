@@ -6,6 +6,70 @@ import (
 	"text/template"
 )
 
+// CmdKind is the kind of the command, defined by the fields of Cmd that are filled.
+type CmdKind string
+
+const (
+	CmdExec      CmdKind = "exec"
+	CmdCat       CmdKind = "cat"
+	CmdCopy      CmdKind = "copy"
+	CmdSymlink   CmdKind = "symlink"
+	CmdMkdir     CmdKind = "mkdir"
+	CmdArchive   CmdKind = "archive"
+	CmdUnarchive CmdKind = "unarchive"
+)
+
+type cmdField struct {
+	name string
+	set  bool
+}
+
+// Kind returns the kind of the command. Command without any fields is an exec doing nothing.
+//
+// Kind fails, if the command mixes fields of several kinds or misses a required field.
+func (c *Cmd) Kind() (CmdKind, error) {
+	kinds := []struct {
+		kind   CmdKind
+		fields []cmdField
+		// required is the number of the leading fields, that must be set.
+		required int
+	}{
+		{CmdExec, []cmdField{{"Exec", len(c.Exec) != 0}, {"Environ", len(c.Environ) != 0}, {"WorkingDirectory", c.WorkingDirectory != ""}}, 0},
+		{CmdCat, []cmdField{{"CatOutput", c.CatOutput != ""}, {"CatTemplate", c.CatTemplate != ""}}, 1},
+		{CmdCopy, []cmdField{{"CopySource", c.CopySource != ""}, {"CopyOutput", c.CopyOutput != ""}}, 2},
+		{CmdSymlink, []cmdField{{"SymlinkTarget", c.SymlinkTarget != ""}, {"SymlinkOutput", c.SymlinkOutput != ""}}, 2},
+		{CmdMkdir, []cmdField{{"Mkdir", c.Mkdir != ""}}, 1},
+		{CmdArchive, []cmdField{{"ArchiveSource", c.ArchiveSource != ""}, {"ArchiveOutput", c.ArchiveOutput != ""}}, 2},
+		{CmdUnarchive, []cmdField{{"UnarchiveSource", c.UnarchiveSource != ""}, {"UnarchiveOutput", c.UnarchiveOutput != ""}}, 2},
+	}
+
+	var found []string
+	kind := CmdExec
+	for _, k := range kinds {
+		used := false
+		for _, f := range k.fields {
+			used = used || f.set
+		}
+		if !used {
+			continue
+		}
+
+		for _, f := range k.fields[:k.required] {
+			if !f.set {
+				return "", fmt.Errorf("%s command requires %s", k.kind, f.name)
+			}
+		}
+
+		found = append(found, string(k.kind))
+		kind = k.kind
+	}
+
+	if len(found) > 1 {
+		return "", fmt.Errorf("command mixes kinds %s", strings.Join(found, ", "))
+	}
+	return kind, nil
+}
+
 type JobContext struct {
 	SourceDir string
 	OutputDir string
@@ -60,6 +124,15 @@ func (c *Cmd) Render(ctx JobContext) (*Cmd, error) {
 	rendered.WorkingDirectory = render(c.WorkingDirectory)
 	rendered.Exec = renderList(c.Exec)
 	rendered.Environ = renderList(c.Environ)
+	rendered.CopySource = render(c.CopySource)
+	rendered.CopyOutput = render(c.CopyOutput)
+	rendered.SymlinkTarget = render(c.SymlinkTarget)
+	rendered.SymlinkOutput = render(c.SymlinkOutput)
+	rendered.Mkdir = render(c.Mkdir)
+	rendered.ArchiveSource = render(c.ArchiveSource)
+	rendered.ArchiveOutput = render(c.ArchiveOutput)
+	rendered.UnarchiveSource = render(c.UnarchiveSource)
+	rendered.UnarchiveOutput = render(c.UnarchiveOutput)
 
 	if len(errs) != 0 {
 		return nil, fmt.Errorf("error rendering cmd: %w", errs[0])
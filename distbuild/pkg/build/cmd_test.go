@@ -29,3 +29,57 @@ func TestCmdRender(t *testing.T) {
 
 	require.Equal(t, expected, result)
 }
+
+func TestCmdRenderFileOperations(t *testing.T) {
+	tmpl := Cmd{
+		CopySource:    `{{index .Deps "6100000000000000000000000000000000000000"}}/lib.a`,
+		CopyOutput:    "{{.OutputDir}}/lib/lib.a",
+		SymlinkTarget: "{{.OutputDir}}/lib/lib.a",
+		SymlinkOutput: "{{.OutputDir}}/lib.a",
+	}
+
+	ctx := JobContext{
+		OutputDir: "/distbuild/jobs/b",
+		Deps: map[ID]string{
+			{'a'}: "/distbuild/jobs/a",
+		},
+	}
+
+	result, err := tmpl.Render(ctx)
+	require.NoError(t, err)
+
+	expected := &Cmd{
+		CopySource:    "/distbuild/jobs/a/lib.a",
+		CopyOutput:    "/distbuild/jobs/b/lib/lib.a",
+		SymlinkTarget: "/distbuild/jobs/b/lib/lib.a",
+		SymlinkOutput: "/distbuild/jobs/b/lib.a",
+	}
+
+	require.Equal(t, expected, result)
+}
+
+func TestCmdKind(t *testing.T) {
+	for _, c := range []struct {
+		cmd  Cmd
+		kind CmdKind
+		err  string
+	}{
+		{cmd: Cmd{}, kind: CmdExec},
+		{cmd: Cmd{Exec: []string{"true"}, WorkingDirectory: "/"}, kind: CmdExec},
+		{cmd: Cmd{CatOutput: "a"}, kind: CmdCat},
+		{cmd: Cmd{Mkdir: "a"}, kind: CmdMkdir},
+		{cmd: Cmd{UnarchiveSource: "a.tar", UnarchiveOutput: "a"}, kind: CmdUnarchive},
+		{cmd: Cmd{CatTemplate: "a"}, err: "cat command requires CatOutput"},
+		{cmd: Cmd{SymlinkOutput: "a"}, err: "symlink command requires SymlinkTarget"},
+		{cmd: Cmd{Exec: []string{"true"}, Mkdir: "a"}, err: "command mixes kinds exec, mkdir"},
+	} {
+		kind, err := c.cmd.Kind()
+		if c.err != "" {
+			require.EqualError(t, err, c.err)
+			continue
+		}
+
+		require.NoError(t, err)
+		require.Equal(t, c.kind, kind)
+	}
+}
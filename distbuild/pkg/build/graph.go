@@ -1,5 +1,7 @@
 package build
 
+import "time"
+
 // Job описывает одну вершину графа сборки.
 type Job struct {
 	// ID задаёт уникальный идентификатор джоба.
@@ -29,6 +31,56 @@ type Job struct {
 
 	// Cmds описывает список команд, которые нужно выполнить в рамках этого джоба.
 	Cmds []Cmd
+
+	// Timeout ограничивает время выполнения джоба на воркере. Ноль означает отсутствие ограничения.
+	//
+	// Превышение таймаута считается системной ошибкой.
+	Timeout time.Duration
+
+	// Retries задаёт, сколько раз джоб можно перезапустить после неудачной попытки.
+	//
+	// По умолчанию перезапускаются только попытки, завершившиеся системной ошибкой: ошибкой
+	// подготовки окружения, запуска команды или таймаутом.
+	Retries int
+
+	// RetryOnExitCode разрешает перезапуск попыток, завершившихся ненулевым кодом возврата.
+	//
+	// Полезно для нестабильных тестов.
+	RetryOnExitCode bool
+
+	// Resources задаёт ресурсы, которые джоб занимает на воркере во время работы.
+	//
+	// Нулевое значение означает, что джоб помещается на любой воркер.
+	Resources Resources
+
+	// Labels перечисляет метки, которые должны быть у воркера, чтобы на нём можно было запустить джоб.
+	//
+	// Например, "has-docker".
+	Labels []string
+}
+
+// Resources описывает вычислительные ресурсы джоба или воркера.
+type Resources struct {
+	// MilliCPU задаёт число ядер в тысячных долях ядра.
+	MilliCPU int64
+
+	// Memory задаёт объём памяти в байтах.
+	Memory int64
+}
+
+// Fits проверяет, что ресурсы r помещаются в free.
+func (r Resources) Fits(free Resources) bool {
+	return r.MilliCPU <= free.MilliCPU && r.Memory <= free.Memory
+}
+
+// Add возвращает сумму ресурсов.
+func (r Resources) Add(other Resources) Resources {
+	return Resources{MilliCPU: r.MilliCPU + other.MilliCPU, Memory: r.Memory + other.Memory}
+}
+
+// Sub возвращает разность ресурсов.
+func (r Resources) Sub(other Resources) Resources {
+	return Resources{MilliCPU: r.MilliCPU - other.MilliCPU, Memory: r.Memory - other.Memory}
 }
 
 // Cmd описывает одну команду сборки.
@@ -36,8 +88,16 @@ type Job struct {
 // Есть несколько видов команд. Все виды команд описываются одной структурой.
 // Реальный тип определяется тем, какие поля структуры заполнены.
 //
-//	exec - выполняет произвольную команду
-//	cat  - записывает строку в файл
+//	exec      - выполняет произвольную команду
+//	cat       - записывает строку в файл
+//	copy      - копирует файл или директорию
+//	symlink   - создаёт символическую ссылку
+//	mkdir     - создаёт директорию
+//	archive   - упаковывает директорию в tar архив
+//	unarchive - распаковывает tar архив в директорию
+//
+// Все виды, кроме exec, воркер выполняет сам, не запуская процессов. Файлы, которые создают такие команды,
+// должны находиться внутри {{.OutputDir}}.
 //
 // Все строки в описании команды могут содержать в себе ссылки на контекстные переменные. Перед выполнением
 // реальной команды, переменные заменяются на их реальные значения.
@@ -61,6 +121,37 @@ type Cmd struct {
 
 	// CatOutput задаёт выходной файл для команды типа cat.
 	CatOutput string
+
+	// CopySource задаёт файл или директорию, которую команда типа copy копирует в CopyOutput.
+	CopySource string
+
+	// CopyOutput задаёт путь копии. Существующие файлы перезаписываются.
+	CopyOutput string
+
+	// SymlinkTarget задаёт путь, на который указывает ссылка SymlinkOutput.
+	//
+	// Ссылка должна указывать внутрь {{.OutputDir}}. Абсолютный путь превращается в относительный,
+	// чтобы ссылка оставалась верной после копирования артефакта на другой воркер.
+	SymlinkTarget string
+
+	// SymlinkOutput задаёт путь ссылки, которую создаёт команда типа symlink.
+	SymlinkOutput string
+
+	// Mkdir задаёт директорию, которую создаёт команда типа mkdir вместе с родительскими директориями.
+	Mkdir string
+
+	// ArchiveSource задаёт директорию, которую команда типа archive упаковывает в ArchiveOutput.
+	ArchiveSource string
+
+	// ArchiveOutput задаёт путь tar архива. Архив с расширением .tar.gz или .tgz сжимается gzip.
+	ArchiveOutput string
+
+	// UnarchiveSource задаёт tar архив, который команда типа unarchive распаковывает в UnarchiveOutput.
+	// Архив с расширением .tar.gz или .tgz распаковывается gzip.
+	UnarchiveSource string
+
+	// UnarchiveOutput задаёт директорию, в которую распаковывается архив.
+	UnarchiveOutput string
 }
 
 type Graph struct {
@@ -0,0 +1,87 @@
+package build
+
+// Levels groups jobs by the length of the longest dependency chain below them.
+//
+// Jobs of level 0 have no dependencies, jobs of level N depend on jobs of level N-1. Jobs of the same
+// level never depend on each other and may run in parallel. Levels assumes the graph contains no cycles,
+// dependencies missing from jobs are ignored.
+func Levels(jobs []Job) [][]Job {
+	level := jobLevels(jobs)
+
+	var levels [][]Job
+	for _, job := range jobs {
+		l := level[job.ID]
+		for len(levels) <= l {
+			levels = append(levels, nil)
+		}
+		levels[l] = append(levels[l], job)
+	}
+	return levels
+}
+
+// CriticalPath returns the longest chain of dependent jobs, starting from the job without dependencies.
+//
+// Jobs of the critical path run one after another, so the build takes at least as long as the path.
+func CriticalPath(jobs []Job) []Job {
+	level := jobLevels(jobs)
+
+	byID := map[ID]Job{}
+	for _, job := range jobs {
+		byID[job.ID] = job
+	}
+
+	var path []Job
+	for _, job := range jobs {
+		if len(path) == 0 || level[job.ID] > level[path[0].ID] {
+			path = []Job{job}
+		}
+	}
+	if len(path) == 0 {
+		return nil
+	}
+
+	for top := path[0]; level[top.ID] > 0; {
+		for _, dep := range top.Deps {
+			if next, ok := byID[dep]; ok && level[dep] == level[top.ID]-1 {
+				top = next
+				break
+			}
+		}
+		path = append(path, top)
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+func jobLevels(jobs []Job) map[ID]int {
+	byID := map[ID]*Job{}
+	for i := range jobs {
+		byID[jobs[i].ID] = &jobs[i]
+	}
+
+	level := map[ID]int{}
+	visited := map[ID]bool{}
+
+	var visit func(job *Job) int
+	visit = func(job *Job) int {
+		if visited[job.ID] {
+			return level[job.ID]
+		}
+		visited[job.ID] = true
+
+		for _, dep := range job.Deps {
+			if next, ok := byID[dep]; ok {
+				level[job.ID] = max(level[job.ID], visit(next)+1)
+			}
+		}
+		return level[job.ID]
+	}
+
+	for i := range jobs {
+		visit(&jobs[i])
+	}
+	return level
+}
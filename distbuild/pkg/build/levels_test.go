@@ -0,0 +1,39 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func jobNames(jobs []Job) []string {
+	var names []string
+	for _, job := range jobs {
+		names = append(names, job.Name)
+	}
+	return names
+}
+
+func TestLevels(t *testing.T) {
+	jobs := []Job{
+		{ID: ID{'e'}, Name: "e", Deps: []ID{{'b'}, {'d'}}},
+		{ID: ID{'a'}, Name: "a"},
+		{ID: ID{'b'}, Name: "b", Deps: []ID{{'a'}}},
+		{ID: ID{'c'}, Name: "c"},
+		{ID: ID{'d'}, Name: "d", Deps: []ID{{'c'}, {'b'}}},
+	}
+
+	levels := Levels(jobs)
+	require.Len(t, levels, 4)
+	require.Equal(t, []string{"a", "c"}, jobNames(levels[0]))
+	require.Equal(t, []string{"b"}, jobNames(levels[1]))
+	require.Equal(t, []string{"d"}, jobNames(levels[2]))
+	require.Equal(t, []string{"e"}, jobNames(levels[3]))
+
+	require.Equal(t, []string{"a", "b", "d", "e"}, jobNames(CriticalPath(jobs)))
+}
+
+func TestCriticalPathEmpty(t *testing.T) {
+	require.Empty(t, Levels(nil))
+	require.Empty(t, CriticalPath(nil))
+}
@@ -0,0 +1,231 @@
+package build
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// ValidationError describes a single problem of the graph.
+type ValidationError struct {
+	// Job and Name identify the broken job.
+	Job  ID
+	Name string
+
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("job %q (%s): %s", e.Name, e.Job, e.Message)
+}
+
+// Validate checks the graph and returns every problem found. Graph is valid, when the result is empty.
+//
+// Validate reports duplicate job ids, dependencies missing from the graph, dependency cycles,
+// commands mixing several kinds or missing required fields, malformed command templates,
+// references to {{index .Deps "id"}} not listed in Job.Deps and inputs missing from Graph.SourceFiles.
+func Validate(g *Graph) []ValidationError {
+	var errs []ValidationError
+	report := func(job *Job, format string, args ...any) {
+		errs = append(errs, ValidationError{Job: job.ID, Name: job.Name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	sources := map[string]bool{}
+	for _, path := range g.SourceFiles {
+		sources[path] = true
+	}
+
+	jobs := map[ID]*Job{}
+	for i := range g.Jobs {
+		job := &g.Jobs[i]
+		if other, ok := jobs[job.ID]; ok {
+			report(job, "duplicate job id, also used by job %q", other.Name)
+			continue
+		}
+		jobs[job.ID] = job
+	}
+
+	for i := range g.Jobs {
+		job := &g.Jobs[i]
+
+		deps := map[ID]bool{}
+		for _, dep := range job.Deps {
+			deps[dep] = true
+			if _, ok := jobs[dep]; !ok {
+				report(job, "dependency %s is not in the graph", dep)
+			}
+		}
+
+		for _, input := range job.Inputs {
+			if !sources[input] {
+				report(job, "input %q is not in source files", input)
+			}
+		}
+
+		for j, cmd := range job.Cmds {
+			if _, err := cmd.Kind(); err != nil {
+				report(job, "cmd %d: %v", j, err)
+			}
+
+			for _, str := range []string{
+				cmd.CatOutput, cmd.CatTemplate, cmd.WorkingDirectory,
+				cmd.CopySource, cmd.CopyOutput, cmd.SymlinkTarget, cmd.SymlinkOutput, cmd.Mkdir,
+				cmd.ArchiveSource, cmd.ArchiveOutput, cmd.UnarchiveSource, cmd.UnarchiveOutput,
+			} {
+				checkTemplate(str, deps, func(msg string) { report(job, "cmd %d: %s", j, msg) })
+			}
+			for _, str := range append(append([]string(nil), cmd.Exec...), cmd.Environ...) {
+				checkTemplate(str, deps, func(msg string) { report(job, "cmd %d: %s", j, msg) })
+			}
+		}
+	}
+
+	for _, cycle := range findCycles(g.Jobs, jobs) {
+		names := make([]string, len(cycle))
+		for i, job := range cycle {
+			names[i] = fmt.Sprintf("%q", job.Name)
+		}
+		report(cycle[0], "dependency cycle %s", strings.Join(names, " -> "))
+	}
+
+	return errs
+}
+
+// checkTemplate reports syntax errors, unknown variables and references to jobs outside of deps.
+func checkTemplate(str string, deps map[ID]bool, report func(msg string)) {
+	t, err := template.New("").Parse(str)
+	if err != nil {
+		report(err.Error())
+		return
+	}
+	if t.Tree == nil {
+		return
+	}
+
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			checkDepsIndex(n, deps, report)
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode:
+			switch n.Ident[0] {
+			case "SourceDir", "OutputDir", "Deps":
+			default:
+				report(fmt.Sprintf("unknown variable .%s", n.Ident[0]))
+			}
+		}
+	}
+	walk(t.Tree.Root)
+}
+
+// checkDepsIndex checks {{index .Deps "id"}} command.
+func checkDepsIndex(n *parse.CommandNode, deps map[ID]bool, report func(msg string)) {
+	if len(n.Args) != 3 {
+		return
+	}
+
+	fn, ok := n.Args[0].(*parse.IdentifierNode)
+	if !ok || fn.Ident != "index" {
+		return
+	}
+
+	field, ok := n.Args[1].(*parse.FieldNode)
+	if !ok || len(field.Ident) != 1 || field.Ident[0] != "Deps" {
+		return
+	}
+
+	key, ok := n.Args[2].(*parse.StringNode)
+	if !ok {
+		return
+	}
+
+	var id ID
+	if err := id.UnmarshalText([]byte(key.Text)); err != nil {
+		report(fmt.Sprintf("invalid dependency reference %q", key.Text))
+		return
+	}
+
+	if !deps[id] {
+		report(fmt.Sprintf("reference to %s, which is not in deps", id))
+	}
+}
+
+// findCycles returns a cycle for every back edge found by the depth-first search.
+func findCycles(list []Job, jobs map[ID]*Job) [][]*Job {
+	const (
+		visiting = 1
+		done     = 2
+	)
+
+	var cycles [][]*Job
+	state := map[ID]int{}
+	var stack []*Job
+
+	var visit func(job *Job)
+	visit = func(job *Job) {
+		state[job.ID] = visiting
+		stack = append(stack, job)
+
+		for _, dep := range job.Deps {
+			next, ok := jobs[dep]
+			if !ok {
+				continue
+			}
+
+			switch state[dep] {
+			case 0:
+				visit(next)
+			case visiting:
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i].ID == dep {
+						cycle := append([]*Job(nil), stack[i:]...)
+						cycles = append(cycles, append(cycle, next))
+						break
+					}
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[job.ID] = done
+	}
+
+	for i := range list {
+		if job := jobs[list[i].ID]; state[job.ID] == 0 {
+			visit(job)
+		}
+	}
+	return cycles
+}
@@ -0,0 +1,87 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	graph := Graph{
+		SourceFiles: map[ID]string{{'s'}: "a.c"},
+		Jobs: []Job{
+			{
+				ID:     ID{'a'},
+				Name:   "cc a.c",
+				Inputs: []string{"a.c"},
+				Cmds:   []Cmd{{Exec: []string{"cc", "{{.SourceDir}}/a.c", "-o", "{{.OutputDir}}/a.o"}}},
+			},
+			{
+				ID:   ID{'b'},
+				Name: "link",
+				Deps: []ID{{'a'}},
+				Cmds: []Cmd{{Exec: []string{"ld", `{{index .Deps "6100000000000000000000000000000000000000"}}/a.o`}}},
+			},
+		},
+	}
+
+	require.Empty(t, Validate(&graph))
+}
+
+func TestValidateErrors(t *testing.T) {
+	graph := Graph{
+		SourceFiles: map[ID]string{{'s'}: "a.c"},
+		Jobs: []Job{
+			{
+				ID:     ID{'a'},
+				Name:   "cc a.c",
+				Inputs: []string{"a.c", "b.c"},
+				Cmds: []Cmd{
+					{CatTemplate: "{{.Output}}", CatOutput: "{{.OutputDir"},
+				},
+			},
+			{
+				ID:   ID{'a'},
+				Name: "copy of cc a.c",
+			},
+			{
+				ID:   ID{'b'},
+				Name: "link",
+				Deps: []ID{{'a'}, {'x'}},
+				Cmds: []Cmd{
+					{Exec: []string{`{{index .Deps "6300000000000000000000000000000000000000"}}`, `{{index .Deps "c"}}`}},
+					{CopySource: "{{.SourceDir}}/a.h", CatOutput: "{{.OutputDir}}/a.h"},
+					{ArchiveSource: "{{.SourceDir}}"},
+				},
+			},
+			{
+				ID:   ID{'c'},
+				Name: "first",
+				Deps: []ID{{'d'}},
+			},
+			{
+				ID:   ID{'d'},
+				Name: "second",
+				Deps: []ID{{'c'}},
+			},
+		},
+	}
+
+	var messages []string
+	for _, err := range Validate(&graph) {
+		messages = append(messages, err.Error())
+	}
+
+	require.Equal(t, []string{
+		`job "copy of cc a.c" (6100000000000000000000000000000000000000): duplicate job id, also used by job "cc a.c"`,
+		`job "cc a.c" (6100000000000000000000000000000000000000): input "b.c" is not in source files`,
+		`job "cc a.c" (6100000000000000000000000000000000000000): cmd 0: template: :1: unclosed action`,
+		`job "cc a.c" (6100000000000000000000000000000000000000): cmd 0: unknown variable .Output`,
+		`job "link" (6200000000000000000000000000000000000000): dependency 7800000000000000000000000000000000000000 is not in the graph`,
+		`job "link" (6200000000000000000000000000000000000000): cmd 0: reference to 6300000000000000000000000000000000000000, which is not in deps`,
+		`job "link" (6200000000000000000000000000000000000000): cmd 0: invalid dependency reference "c"`,
+		`job "link" (6200000000000000000000000000000000000000): cmd 1: copy command requires CopyOutput`,
+		`job "link" (6200000000000000000000000000000000000000): cmd 2: archive command requires ArchiveOutput`,
+		`job "first" (6300000000000000000000000000000000000000): dependency cycle "first" -> "second" -> "first"`,
+	}, messages)
+}
@@ -4,13 +4,51 @@ package client
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"time"
 
 	"go.uber.org/zap"
 
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+)
+
+const (
+	reconnectDelay   = 100 * time.Millisecond
+	reconnectTimeout = time.Minute
+
+	// cancelTimeout bounds the time spent on cancelling the build after the context is done.
+	cancelTimeout = 5 * time.Second
+)
+
+var (
+	// ErrBuildCancelled is returned when the build was cancelled on the coordinator.
+	ErrBuildCancelled = errors.New("build cancelled")
+
+	// errDisconnected is returned when the connection to the coordinator is lost before the build completion.
+	errDisconnected = errors.New("disconnected from coordinator")
 )
 
 type Client struct {
+	l         *zap.Logger
+	sourceDir string
+
+	builds    *api.BuildClient
+	files     *filecache.Client
+	artifacts *artifact.Client
+}
+
+// Config configures client.
+type Config struct {
+	// Auth is presented to the coordinator. The client authenticates with the certificate or with Auth.Token.
+	Auth *auth.Config
 }
 
 func NewClient(
@@ -18,7 +56,24 @@ func NewClient(
 	apiEndpoint string,
 	sourceDir string,
 ) *Client {
-	panic("implement me")
+	return NewClientWithConfig(l, apiEndpoint, sourceDir, Config{})
+}
+
+// NewClientWithConfig creates client with the given credentials.
+func NewClientWithConfig(
+	l *zap.Logger,
+	apiEndpoint string,
+	sourceDir string,
+	config Config,
+) *Client {
+	httpClient := config.Auth.HTTPClient()
+	return &Client{
+		l:         l,
+		sourceDir: sourceDir,
+		builds:    api.NewBuildClientWithHTTPClient(l, apiEndpoint, httpClient),
+		files:     filecache.NewClientWithHTTPClient(l, apiEndpoint, httpClient),
+		artifacts: artifact.NewClient(httpClient),
+	}
 }
 
 type BuildListener interface {
@@ -29,6 +84,384 @@ type BuildListener interface {
 	OnJobFailed(jobID build.ID, code int, error string) error
 }
 
+// QueueListener is implemented by listeners interested in positions of the jobs waiting for a worker.
+type QueueListener interface {
+	// OnJobsQueued receives positions of the waiting jobs. Jobs missing from positions are not waiting.
+	OnJobsQueued(positions map[build.ID]int) error
+}
+
+// UnsatisfiableListener is implemented by listeners interested in the waiting jobs, that none of the
+// registered workers can run.
+type UnsatisfiableListener interface {
+	// OnJobsUnsatisfiable receives such jobs with every queue update. The list is empty, once suitable
+	// workers are registered.
+	OnJobsUnsatisfiable(ids []build.ID) error
+}
+
+// CachedListener is implemented by listeners distinguishing jobs, that were not executed because
+// their results were found in the cache.
+type CachedListener interface {
+	// OnJobCached is called instead of OnJobFinished for the cached job, after its stored output.
+	OnJobCached(jobID build.ID) error
+}
+
+// ReproducedListener is implemented by listeners interested in the reproducibility checks
+// requested by BuildOptions.Reproduce.
+type ReproducedListener interface {
+	// OnJobReproduced receives the result of the check. Non-reproducible job does not fail the build.
+	OnJobReproduced(rep *api.JobReproduced) error
+}
+
+// BuildOptions controls scheduling of the build jobs relative to the jobs of other builds.
+type BuildOptions struct {
+	// User shares the workers with other users.
+	User string
+
+	// Priority orders builds of all users. Jobs of the build with higher priority are run first.
+	Priority int
+
+	// Reproduce lists jobs, that are run twice on different workers to check that their outputs match.
+	Reproduce []build.ID
+
+	// Trace, if set, collects timings of the finished jobs.
+	Trace *Trace
+}
+
+// QueryOptions controls Query.
+type QueryOptions struct {
+	// Outputs, if set, receives artifacts of the cached jobs, downloaded from the workers storing them.
+	Outputs *artifact.Cache
+}
+
+func (c *Client) uploadFiles(ctx context.Context, graph *build.Graph, missing []build.ID) error {
+	for _, id := range missing {
+		path, ok := graph.SourceFiles[id]
+		if !ok {
+			return fmt.Errorf("coordinator requested unknown file %v", id)
+		}
+
+		if err := c.files.Upload(ctx, id, filepath.Join(c.sourceDir, path)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamedOutput counts bytes of the job output already passed to the listener.
+type streamedOutput struct {
+	// attempt is the job attempt, which output is streamed.
+	attempt        int
+	stdout, stderr int
+}
+
+// buildSession holds the build state preserved across reconnects to the coordinator.
+type buildSession struct {
+	req api.BuildRequest
+	lsn BuildListener
+
+	// reported contains jobs already passed to the listener.
+	reported map[build.ID]struct{}
+	// reproduced contains jobs, which reproducibility checks are already passed to the listener.
+	reproduced map[build.ID]struct{}
+	// trace collects timings of the reported jobs. May be nil.
+	trace *Trace
+	// streamed tracks output of the running jobs.
+	streamed map[build.ID]*streamedOutput
+	// lastAttached is the time of the last successful attach to the build.
+	lastAttached time.Time
+}
+
+// reportOutput passes output chunk to the listener.
+//
+// Chunks overlapping with already reported output are trimmed. Chunks following the lost
+// chunk are skipped, the rest of the output is reported together with the job result.
+// Output of the retried job is reported from the beginning, chunks of the earlier attempts
+// are skipped.
+func (s *buildSession) reportOutput(out *api.JobOutput) error {
+	if _, ok := s.reported[out.ID]; ok {
+		return nil
+	}
+
+	streamed, ok := s.streamed[out.ID]
+	if !ok || streamed.attempt < out.Attempt {
+		streamed = &streamedOutput{attempt: out.Attempt}
+		s.streamed[out.ID] = streamed
+	} else if streamed.attempt > out.Attempt {
+		return nil
+	}
+
+	pos, report := &streamed.stdout, s.lsn.OnJobStdout
+	if out.Stderr {
+		pos, report = &streamed.stderr, s.lsn.OnJobStderr
+	}
+
+	if out.Offset > *pos || out.Offset+len(out.Data) <= *pos {
+		return nil
+	}
+
+	data := out.Data[*pos-out.Offset:]
+	*pos += len(data)
+	return report(out.ID, data)
+}
+
+// reportJob passes job result to the listener, skipping the output streamed earlier.
+func (s *buildSession) reportJob(res *api.JobResult) error {
+	s.reported[res.ID] = struct{}{}
+	if s.trace != nil {
+		s.trace.add(res)
+	}
+
+	var streamed streamedOutput
+	if st, ok := s.streamed[res.ID]; ok {
+		if st.attempt == res.Attempts {
+			streamed = *st
+		}
+		delete(s.streamed, res.ID)
+	}
+
+	if len(res.Stdout) > streamed.stdout {
+		if err := s.lsn.OnJobStdout(res.ID, res.Stdout[streamed.stdout:]); err != nil {
+			return err
+		}
+	}
+
+	if len(res.Stderr) > streamed.stderr {
+		if err := s.lsn.OnJobStderr(res.ID, res.Stderr[streamed.stderr:]); err != nil {
+			return err
+		}
+	}
+
+	if res.Error != nil {
+		return s.lsn.OnJobFailed(res.ID, res.ExitCode, *res.Error)
+	}
+	if res.ExitCode != 0 {
+		return s.lsn.OnJobFailed(res.ID, res.ExitCode, "")
+	}
+	if cl, ok := s.lsn.(CachedListener); ok && res.Cached {
+		return cl.OnJobCached(res.ID)
+	}
+	return s.lsn.OnJobFinished(res.ID)
+}
+
+func disconnected(err error) error {
+	return fmt.Errorf("%w: %w", errDisconnected, err)
+}
+
+// transportErr marks network errors as disconnects.
+func transportErr(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return disconnected(err)
+	}
+	return err
+}
+
+// attach starts the build or attaches to the build started earlier and follows its progress.
+func (c *Client) attach(ctx context.Context, s *buildSession) error {
+	started, r, err := c.builds.StartBuild(ctx, &s.req)
+	if err != nil {
+		return transportErr(err)
+	}
+	defer r.Close()
+
+	s.req.BuildID = &started.ID
+	s.lastAttached = time.Now()
+
+	c.l.Debug("build started",
+		zap.String("build_id", started.ID.String()),
+		zap.Int("missing_files", len(started.MissingFiles)))
+
+	if err := c.uploadFiles(ctx, &s.req.Graph, started.MissingFiles); err != nil {
+		return transportErr(err)
+	}
+
+	if _, err := c.builds.SignalBuild(ctx, started.ID, &api.SignalRequest{UploadDone: &api.UploadDone{}}); err != nil {
+		return transportErr(err)
+	}
+
+	for {
+		update, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return disconnected(fmt.Errorf("build %v: status stream closed unexpectedly", started.ID))
+		} else if err != nil {
+			return disconnected(err)
+		}
+
+		switch {
+		case update.JobOutput != nil:
+			if err := s.reportOutput(update.JobOutput); err != nil {
+				return err
+			}
+
+		case update.JobFinished != nil:
+			if _, ok := s.reported[update.JobFinished.ID]; ok {
+				continue
+			}
+
+			if err := s.reportJob(update.JobFinished); err != nil {
+				return err
+			}
+
+		case update.BuildFailed != nil:
+			return fmt.Errorf("build %v failed: %s", started.ID, update.BuildFailed.Error)
+
+		case update.BuildFinished != nil:
+			c.l.Debug("build finished", zap.String("build_id", started.ID.String()))
+			return nil
+
+		case update.BuildCancelled != nil:
+			return fmt.Errorf("build %v: %w", started.ID, ErrBuildCancelled)
+
+		case update.JobsQueued != nil:
+			if lsn, ok := s.lsn.(QueueListener); ok {
+				if err := lsn.OnJobsQueued(update.JobsQueued.Positions); err != nil {
+					return err
+				}
+			}
+			if lsn, ok := s.lsn.(UnsatisfiableListener); ok {
+				if err := lsn.OnJobsUnsatisfiable(update.JobsQueued.Unsatisfiable); err != nil {
+					return err
+				}
+			}
+
+		case update.JobReproduced != nil:
+			if _, ok := s.reproduced[update.JobReproduced.ID]; ok {
+				continue
+			}
+			s.reproduced[update.JobReproduced.ID] = struct{}{}
+
+			if lsn, ok := s.lsn.(ReproducedListener); ok {
+				if err := lsn.OnJobReproduced(update.JobReproduced); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// cancel stops the build on the coordinator after the build context is done.
+func (c *Client) cancel(s *buildSession) {
+	if s.req.BuildID == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+	defer cancel()
+
+	_, err := c.builds.SignalBuild(ctx, *s.req.BuildID, &api.SignalRequest{CancelBuild: &api.CancelBuild{}})
+	if err != nil {
+		c.l.Warn("failed to cancel build", zap.String("build_id", s.req.BuildID.String()), zap.Error(err))
+	}
+}
+
+// Build runs the build and waits for its completion.
+//
+// When connection to the coordinator is lost after the build has started, Build attaches to the
+// same build again. Each job is reported to the listener only once.
+//
+// When ctx is done, the build is cancelled on the coordinator together with the running jobs.
 func (c *Client) Build(ctx context.Context, graph build.Graph, lsn BuildListener) error {
-	panic("implement me")
+	return c.BuildWithOptions(ctx, graph, lsn, BuildOptions{})
+}
+
+// BuildWithOptions runs the build on behalf of the user with the given priority.
+//
+// When lsn implements QueueListener, it receives positions of the jobs waiting for a worker.
+// When lsn implements ReproducedListener, it receives results of the reproducibility checks.
+func (c *Client) BuildWithOptions(ctx context.Context, graph build.Graph, lsn BuildListener, opts BuildOptions) error {
+	s := &buildSession{
+		req: api.BuildRequest{
+			Graph:     graph,
+			User:      opts.User,
+			Priority:  opts.Priority,
+			Reproduce: opts.Reproduce,
+		},
+		lsn:        lsn,
+		reported:   map[build.ID]struct{}{},
+		reproduced: map[build.ID]struct{}{},
+		streamed:   map[build.ID]*streamedOutput{},
+		trace:      opts.Trace,
+	}
+
+	for {
+		err := c.attach(ctx, s)
+		if err == nil {
+			return nil
+		} else if ctx.Err() != nil {
+			c.cancel(s)
+			return ctx.Err()
+		}
+
+		if !errors.Is(err, errDisconnected) || s.req.BuildID == nil {
+			return err
+		}
+
+		if time.Since(s.lastAttached) > reconnectTimeout {
+			return err
+		}
+
+		c.l.Warn("lost connection to coordinator, reconnecting",
+			zap.String("build_id", s.req.BuildID.String()),
+			zap.Error(err))
+
+		select {
+		case <-time.After(reconnectDelay):
+		case <-ctx.Done():
+			c.cancel(s)
+			return ctx.Err()
+		}
+	}
+}
+
+// Query reports jobs of the graph, which artifacts are already stored on the workers, without
+// running anything.
+//
+// Stored results of the cached jobs are passed to lsn. Cached job with unknown result is reported
+// as finished without output. Query returns jobs, that have to be executed.
+func (c *Client) Query(ctx context.Context, graph build.Graph, lsn BuildListener) (missing []build.ID, err error) {
+	return c.QueryWithOptions(ctx, graph, lsn, QueryOptions{})
+}
+
+// QueryWithOptions is the same as Query, but also downloads outputs of the cached jobs into opts.Outputs.
+// Cached job is passed to lsn after its output is downloaded.
+func (c *Client) QueryWithOptions(ctx context.Context, graph build.Graph, lsn BuildListener, opts QueryOptions) (missing []build.ID, err error) {
+	jobs := build.TopSort(graph.Jobs)
+
+	req := &api.QueryRequest{Jobs: make([]build.ID, 0, len(jobs))}
+	for _, job := range jobs {
+		req.Jobs = append(req.Jobs, job.ID)
+	}
+
+	rsp, err := c.builds.QueryCache(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &buildSession{lsn: lsn, reported: map[build.ID]struct{}{}, streamed: map[build.ID]*streamedOutput{}}
+	for _, job := range jobs {
+		cached, ok := rsp.Cached[job.ID]
+		if !ok {
+			missing = append(missing, job.ID)
+			continue
+		}
+
+		if opts.Outputs != nil {
+			err := c.artifacts.Download(ctx, cached.WorkerID.String(), opts.Outputs, job.ID)
+			if err != nil && !errors.Is(err, artifact.ErrExists) {
+				return nil, fmt.Errorf("output of job %v: %w", job.ID, err)
+			}
+		}
+
+		res := cached.Result
+		if res == nil {
+			res = &api.JobResult{ID: job.ID, Cached: true}
+		}
+
+		if err := s.reportJob(res); err != nil {
+			return nil, err
+		}
+	}
+
+	c.l.Debug("cache queried", zap.Int("jobs", len(jobs)), zap.Int("missing", len(missing)))
+	return missing, nil
 }
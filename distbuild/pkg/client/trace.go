@@ -0,0 +1,250 @@
+//go:build !solution
+
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// Trace collects timings of the build jobs and writes them in the Chrome trace event format.
+//
+// The trace is viewed in chrome://tracing or https://ui.perfetto.dev. Each worker is shown as a process
+// with one thread per slot. Time spent in the coordinator queue is shown in the separate "coordinator"
+// process. Jobs on the critical path of the build are colored red.
+//
+// Trace is passed to the build in BuildOptions.Trace and must not be written while the build is running.
+type Trace struct {
+	graph    build.Graph
+	results  map[build.ID]*api.JobResult
+	finished map[build.ID]time.Time
+}
+
+func NewTrace(graph build.Graph) *Trace {
+	return &Trace{
+		graph:    graph,
+		results:  map[build.ID]*api.JobResult{},
+		finished: map[build.ID]time.Time{},
+	}
+}
+
+func (t *Trace) add(res *api.JobResult) {
+	t.results[res.ID] = res
+	t.finished[res.ID] = time.Now()
+}
+
+// traceEvent is a single record of the trace event format.
+type traceEvent struct {
+	Name  string         `json:"name"`
+	Cat   string         `json:"cat,omitempty"`
+	Ph    string         `json:"ph"`
+	Ts    float64        `json:"ts"`
+	Dur   float64        `json:"dur,omitempty"`
+	Pid   int            `json:"pid"`
+	Tid   int            `json:"tid"`
+	ID    int            `json:"id,omitempty"`
+	Scope string         `json:"s,omitempty"`
+	Color string         `json:"cname,omitempty"`
+	Args  map[string]any `json:"args,omitempty"`
+}
+
+const (
+	coordinatorPid = 0
+	queueTid       = 0
+	cacheTid       = 1
+
+	// criticalColor is one of the reserved color names of the trace viewer.
+	criticalColor = "terrible"
+)
+
+// end returns the time, when the job was finished.
+//
+// Jobs executed by the workers end at the last recorded stage, jobs taken from the cache end
+// at the time the client received the result.
+func (t *Trace) end(id build.ID) time.Time {
+	res := t.results[id]
+	if res.Trace == nil {
+		return t.finished[id]
+	}
+
+	end := res.Trace.Started
+	for _, ts := range []time.Time{res.Trace.Prepared, res.Trace.Executed, res.Trace.Committed} {
+		if ts.After(end) {
+			end = ts
+		}
+	}
+	return end
+}
+
+// criticalPath returns the chain of jobs, that finished last. Each job of the chain waited for the next one.
+func (t *Trace) criticalPath() map[build.ID]bool {
+	deps := map[build.ID][]build.ID{}
+	for _, job := range t.graph.Jobs {
+		deps[job.ID] = job.Deps
+	}
+
+	latest := func(ids []build.ID) (build.ID, bool) {
+		var last build.ID
+		found := false
+		for _, id := range ids {
+			if _, ok := t.results[id]; !ok {
+				continue
+			}
+
+			if !found || t.end(id).After(t.end(last)) {
+				last, found = id, true
+			}
+		}
+		return last, found
+	}
+
+	ids := make([]build.ID, 0, len(t.results))
+	for id := range t.results {
+		ids = append(ids, id)
+	}
+
+	path := map[build.ID]bool{}
+	for id, ok := latest(ids); ok && !path[id]; id, ok = latest(deps[id]) {
+		path[id] = true
+	}
+	return path
+}
+
+// Write writes the trace of the jobs finished so far in JSON.
+func (t *Trace) Write(w io.Writer) error {
+	var start time.Time
+	observe := func(ts time.Time) {
+		if !ts.IsZero() && (start.IsZero() || ts.Before(start)) {
+			start = ts
+		}
+	}
+	for id, res := range t.results {
+		observe(t.finished[id])
+		if res.Trace != nil {
+			observe(res.Trace.Queued)
+			observe(res.Trace.Started)
+		}
+	}
+
+	ts := func(at time.Time) float64 {
+		return float64(at.Sub(start).Nanoseconds()) / 1e3
+	}
+
+	// Workers are numbered in the order of their ids, so the layout does not change between runs.
+	var workers []api.WorkerID
+	pids := map[api.WorkerID]int{}
+	slots := map[api.WorkerID][]int{}
+	for _, res := range t.results {
+		if res.Trace == nil {
+			continue
+		}
+
+		workerSlots, ok := slots[res.Trace.WorkerID]
+		if !ok {
+			workers = append(workers, res.Trace.WorkerID)
+		}
+		if !slices.Contains(workerSlots, res.Trace.Slot) {
+			slots[res.Trace.WorkerID] = append(workerSlots, res.Trace.Slot)
+		}
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i] < workers[j] })
+
+	events := []traceEvent{
+		{Name: "process_name", Ph: "M", Pid: coordinatorPid, Args: map[string]any{"name": "coordinator"}},
+		{Name: "thread_name", Ph: "M", Pid: coordinatorPid, Tid: queueTid, Args: map[string]any{"name": "queue"}},
+		{Name: "thread_name", Ph: "M", Pid: coordinatorPid, Tid: cacheTid, Args: map[string]any{"name": "cache"}},
+	}
+	for i, workerID := range workers {
+		pids[workerID] = i + 1
+		events = append(events, traceEvent{
+			Name: "process_name", Ph: "M", Pid: i + 1, Args: map[string]any{"name": workerID.String()},
+		})
+
+		slices.Sort(slots[workerID])
+		for _, slot := range slots[workerID] {
+			events = append(events, traceEvent{
+				Name: "thread_name", Ph: "M", Pid: i + 1, Tid: slot, Args: map[string]any{"name": "slot " + strconv.Itoa(slot)},
+			})
+		}
+	}
+
+	critical := t.criticalPath()
+	for i, job := range build.TopSort(t.graph.Jobs) {
+		res, ok := t.results[job.ID]
+		if !ok {
+			continue
+		}
+
+		args := map[string]any{
+			"id":        job.ID.String(),
+			"exit_code": res.ExitCode,
+			"attempts":  res.Attempts,
+		}
+		if res.Error != nil {
+			args["error"] = *res.Error
+		}
+		if res.Cached {
+			args["cached"] = true
+		}
+
+		color := ""
+		if critical[job.ID] {
+			color = criticalColor
+			args["critical_path"] = true
+		}
+
+		if res.Trace == nil {
+			events = append(events, traceEvent{
+				Name: job.Name, Cat: "cached", Ph: "i", Ts: ts(t.finished[job.ID]),
+				Pid: coordinatorPid, Tid: cacheTid, Scope: "t", Color: color, Args: args,
+			})
+			continue
+		}
+
+		tr := res.Trace
+		pid := pids[tr.WorkerID]
+
+		if !tr.Queued.IsZero() && !tr.Picked.IsZero() {
+			events = append(events,
+				traceEvent{Name: job.Name, Cat: "queue", Ph: "b", Ts: ts(tr.Queued), Pid: coordinatorPid, Tid: queueTid, ID: i + 1},
+				traceEvent{Name: job.Name, Cat: "queue", Ph: "e", Ts: ts(tr.Picked), Pid: coordinatorPid, Tid: queueTid, ID: i + 1},
+			)
+		}
+
+		events = append(events, traceEvent{
+			Name: job.Name, Cat: "job", Ph: "X", Ts: ts(tr.Started), Dur: ts(t.end(job.ID)) - ts(tr.Started),
+			Pid: pid, Tid: tr.Slot, Color: color, Args: args,
+		})
+
+		stages := []struct {
+			name       string
+			begin, end time.Time
+		}{
+			{"prepare", tr.Started, tr.Prepared},
+			{"execute", tr.Prepared, tr.Executed},
+			{"commit", tr.Executed, tr.Committed},
+		}
+		for _, stage := range stages {
+			if stage.begin.IsZero() || stage.end.IsZero() {
+				continue
+			}
+
+			events = append(events, traceEvent{
+				Name: stage.name, Cat: "stage", Ph: "X", Ts: ts(stage.begin), Dur: ts(stage.end) - ts(stage.begin),
+				Pid: pid, Tid: tr.Slot, Color: color,
+			})
+		}
+	}
+
+	return json.NewEncoder(w).Encode(struct {
+		TraceEvents     []traceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}{events, "ms"})
+}
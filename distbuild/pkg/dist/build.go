@@ -0,0 +1,541 @@
+//go:build !solution
+
+package dist
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/scheduler"
+)
+
+// Build tracks state of a single build.
+//
+// Build outlives the client connection, when coordinator is durable. In that case the client
+// may attach to the build again after the connection is lost or coordinator is restarted.
+type Build struct {
+	ID build.ID
+
+	l         *zap.Logger
+	scheduler *scheduler.Scheduler
+	journal   *journal
+	metrics   *coordinatorMetrics
+	artifacts *artifact.Client
+	graph     build.Graph
+	jobs      map[build.ID]struct{}
+
+	user     string
+	priority int
+	// criticalPaths maps job id to the length of the longest chain of jobs starting from the job.
+	criticalPaths map[build.ID]int
+	// reproduce is the set of jobs checked for reproducibility.
+	reproduce map[build.ID]struct{}
+
+	uploadOnce sync.Once
+	uploadDone chan struct{}
+
+	cancelOnce sync.Once
+	cancelled  chan struct{}
+
+	// output buffers job output chunks until they are sent to the client.
+	output chan *api.JobOutput
+
+	mu       sync.Mutex
+	attached bool
+	done     bool
+	results  map[build.ID]*api.JobResult
+}
+
+func newBuild(l *zap.Logger, s *scheduler.Scheduler, j *journal, m *coordinatorMetrics, a *artifact.Client, started *buildStartedRecord) *Build {
+	graph := started.Graph
+
+	b := &Build{
+		ID:            started.ID,
+		l:             l.With(zap.String("build_id", started.ID.String())),
+		scheduler:     s,
+		journal:       j,
+		metrics:       m,
+		artifacts:     a,
+		graph:         graph,
+		jobs:          make(map[build.ID]struct{}),
+		user:          started.User,
+		priority:      started.Priority,
+		criticalPaths: criticalPaths(graph.Jobs),
+		reproduce:     make(map[build.ID]struct{}),
+		uploadDone:    make(chan struct{}),
+		cancelled:     make(chan struct{}),
+		output:        make(chan *api.JobOutput, outputBufferSize),
+		results:       make(map[build.ID]*api.JobResult),
+	}
+
+	for _, job := range graph.Jobs {
+		b.jobs[job.ID] = struct{}{}
+	}
+	for _, id := range started.Reproduce {
+		b.reproduce[id] = struct{}{}
+	}
+	return b
+}
+
+// criticalPaths computes the length of the longest chain of jobs starting from each job.
+func criticalPaths(jobs []build.Job) map[build.ID]int {
+	sorted := build.TopSort(jobs)
+
+	paths := make(map[build.ID]int, len(sorted))
+	for i := len(sorted) - 1; i >= 0; i-- {
+		job := &sorted[i]
+		paths[job.ID]++
+
+		for _, dep := range job.Deps {
+			paths[dep] = max(paths[dep], paths[job.ID])
+		}
+	}
+	return paths
+}
+
+// sendOutput forwards job output chunk to the client. Chunk is dropped when the buffer is full.
+func (b *Build) sendOutput(out *api.JobOutput) {
+	select {
+	case b.output <- out:
+	default:
+		b.l.Debug("job output dropped", zap.String("job_id", out.ID.String()), zap.Int("offset", out.Offset))
+	}
+}
+
+func (b *Build) hasJob(jobID build.ID) bool {
+	_, ok := b.jobs[jobID]
+	return ok
+}
+
+func (b *Build) signalUploadDone() {
+	b.uploadOnce.Do(func() {
+		close(b.uploadDone)
+	})
+}
+
+// cancel stops the build. It reports whether some client is attached to the build.
+func (b *Build) cancel() (attached bool) {
+	b.cancelOnce.Do(func() {
+		b.l.Info("build cancelled")
+		close(b.cancelled)
+	})
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.attached
+}
+
+func (b *Build) isCancelled() bool {
+	select {
+	case <-b.cancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// reportCancelled completes the cancelled build.
+func (b *Build) reportCancelled(w api.StatusWriter) error {
+	if err := b.finish(); err != nil {
+		return err
+	}
+	return w.Updated(&api.StatusUpdate{BuildCancelled: &api.BuildCancelled{}})
+}
+
+// attach marks build as served by some client connection.
+func (b *Build) attach() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.attached {
+		return fmt.Errorf("build %v is already attached", b.ID)
+	}
+	b.attached = true
+	return nil
+}
+
+func (b *Build) detach() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.attached = false
+}
+
+func (b *Build) result(jobID build.ID) (*api.JobResult, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	res, ok := b.results[jobID]
+	return res, ok
+}
+
+// setResult updates job result in memory. Caller is responsible for writing the result to the journal.
+func (b *Build) setResult(res *api.JobResult) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.results[res.ID] = res
+}
+
+func (b *Build) recordResult(res *api.JobResult) error {
+	if recorded, ok := b.result(res.ID); ok && recorded == res {
+		return nil
+	}
+
+	if err := b.journal.append(&record{JobFinished: &jobFinishedRecord{BuildID: b.ID, Result: *res}}); err != nil {
+		return err
+	}
+
+	b.setResult(res)
+	return nil
+}
+
+// finish marks build as completed, so it is not restored after restart.
+func (b *Build) finish() error {
+	if err := b.journal.append(&record{BuildDone: &buildDoneRecord{ID: b.ID}}); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.done = true
+	return nil
+}
+
+func (b *Build) isDone() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.done
+}
+
+func (b *Build) jobSpec(job *build.Job) *api.JobSpec {
+	paths := map[string]build.ID{}
+	for id, path := range b.graph.SourceFiles {
+		paths[path] = id
+	}
+
+	spec := &api.JobSpec{
+		SourceFiles: map[build.ID]string{},
+		Artifacts:   map[build.ID][]api.WorkerID{},
+		Job:         *job,
+	}
+
+	for _, input := range job.Inputs {
+		if id, ok := paths[input]; ok {
+			spec.SourceFiles[id] = input
+		}
+	}
+
+	for _, dep := range job.Deps {
+		if workerIDs := b.scheduler.LocateArtifacts(dep); len(workerIDs) != 0 {
+			spec.Artifacts[dep] = workerIDs
+		}
+	}
+
+	return spec
+}
+
+func failed(res *api.JobResult) bool {
+	return res.Error != nil || res.ExitCode != 0
+}
+
+// runJob waits for job dependencies and executes the job.
+func (b *Build) runJob(ctx context.Context, job *build.Job, finished map[build.ID]chan struct{}) (*api.JobResult, error) {
+	for _, dep := range job.Deps {
+		select {
+		case <-finished[dep]:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if res, ok := b.result(job.ID); ok && !failed(res) {
+		b.l.Debug("job result is restored", zap.String("job_id", job.ID.String()))
+		return res, nil
+	}
+
+	if workerID, ok := b.scheduler.LocateArtifact(job.ID); ok {
+		b.l.Debug("job is cached", zap.String("job_id", job.ID.String()), zap.String("worker_id", workerID.String()))
+		b.metrics.jobsCached.Inc()
+
+		res, err := fetchResult(ctx, b.artifacts, workerID, job.ID)
+		if err != nil {
+			if !errors.Is(err, artifact.ErrNotFound) {
+				b.l.Warn("failed to fetch cached job result", zap.String("job_id", job.ID.String()), zap.Error(err))
+			}
+
+			// Job is still cached, it is reported without output.
+			res = &api.JobResult{ID: job.ID, Cached: true}
+		}
+		return res, nil
+	}
+
+	b.metrics.jobsScheduled.Inc()
+	pending := b.scheduler.ScheduleJobWithPolicy(b.jobSpec(job), scheduler.Policy{
+		User:         b.user,
+		Priority:     b.priority,
+		CriticalPath: b.criticalPaths[job.ID],
+	})
+	select {
+	case <-pending.Finished:
+		return pending.Result, nil
+	case <-ctx.Done():
+		// Jobs of the durable build keep running, so the client can attach to the build again.
+		if b.journal == nil || b.isCancelled() {
+			b.scheduler.CancelJob(job.ID)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// reproduceID returns id of the second run of the job. The second run is stored in the cache
+// separately, so that its artifact does not replace the artifact of the first run. The artifact
+// of the second run is removed, once the manifests are compared.
+func reproduceID(buildID, jobID build.ID) build.ID {
+	return sha1.Sum(append(buildID[:], jobID[:]...))
+}
+
+// removeArtifact asks the worker to delete the artifact. The removal is journaled, so that it is not
+// forgotten, if the coordinator restarts before the next heartbeat of the worker.
+func (b *Build) removeArtifact(workerID api.WorkerID, id build.ID) {
+	rec := &record{ArtifactRemoved: &artifactRemovedRecord{WorkerID: workerID, ID: id, Delete: true}}
+	if err := b.journal.append(rec); err != nil {
+		b.l.Warn("failed to journal artifact removal", zap.String("id", id.String()), zap.Error(err))
+	}
+
+	b.scheduler.RemoveArtifact(workerID, id)
+}
+
+// reproduceJob runs the finished job once more on a different worker and compares the artifacts of two runs.
+func (b *Build) reproduceJob(ctx context.Context, job *build.Job) (*api.JobReproduced, error) {
+	rep := &api.JobReproduced{ID: job.ID}
+
+	first, ok := b.scheduler.LocateArtifact(job.ID)
+	if !ok {
+		rep.Error = "artifact of the first run is evicted"
+		return rep, nil
+	}
+	rep.Workers[0] = first
+
+	if !slices.ContainsFunc(b.scheduler.Workers(), func(id api.WorkerID) bool { return id != first }) {
+		rep.Error = "no other worker to repeat the job"
+		return rep, nil
+	}
+
+	spec := b.jobSpec(job)
+	spec.ID = reproduceID(b.ID, job.ID)
+
+	b.l.Debug("reproducing job",
+		zap.String("job_id", job.ID.String()),
+		zap.String("reproduce_id", spec.ID.String()),
+		zap.String("first_worker_id", first.String()))
+
+	b.metrics.jobsScheduled.Inc()
+	pending := b.scheduler.ScheduleJobWithPolicy(spec, scheduler.Policy{
+		User:         b.user,
+		Priority:     b.priority,
+		CriticalPath: b.criticalPaths[job.ID],
+		AvoidWorker:  first,
+	})
+	select {
+	case <-pending.Finished:
+	case <-ctx.Done():
+		if b.journal == nil || b.isCancelled() {
+			b.scheduler.CancelJob(spec.ID)
+		}
+		return nil, ctx.Err()
+	}
+
+	if res := pending.Result; res.Error != nil {
+		rep.Error = "second run failed: " + *res.Error
+		return rep, nil
+	} else if res.ExitCode != 0 {
+		rep.Error = fmt.Sprintf("second run failed: exit code %d", res.ExitCode)
+		return rep, nil
+	}
+
+	second, ok := b.scheduler.LocateArtifact(spec.ID)
+	if !ok {
+		rep.Error = "artifact of the second run is evicted"
+		return rep, nil
+	}
+	rep.Workers[1] = second
+	defer b.removeArtifact(second, spec.ID)
+
+	fetchCtx, cancel := context.WithTimeout(ctx, resultTimeout)
+	defer cancel()
+
+	firstManifest, err := b.artifacts.DownloadManifest(fetchCtx, first.String(), job.ID)
+	if err != nil {
+		rep.Error = fmt.Sprintf("manifest of the first run: %v", err)
+		return rep, nil
+	}
+
+	secondManifest, err := b.artifacts.DownloadManifest(fetchCtx, second.String(), spec.ID)
+	if err != nil {
+		rep.Error = fmt.Sprintf("manifest of the second run: %v", err)
+		return rep, nil
+	}
+
+	rep.DiffFiles = firstManifest.Diff(secondManifest)
+	if len(rep.DiffFiles) != 0 {
+		b.l.Warn("job is not reproducible",
+			zap.String("job_id", job.ID.String()),
+			zap.Strings("diff_files", rep.DiffFiles))
+	}
+	return rep, nil
+}
+
+// Run executes the build, sending updates to w.
+//
+// Jobs finished during the previous attempts are not executed again, their results are
+// sent to w once more.
+func (b *Build) Run(ctx context.Context, w api.StatusWriter) error {
+	ctx, cancel := context.WithCancel(ctx)
+
+	jobs := build.TopSort(b.graph.Jobs)
+
+	finished := map[build.ID]chan struct{}{}
+	for _, job := range jobs {
+		finished[job.ID] = make(chan struct{})
+	}
+
+	type jobResult struct {
+		job *build.Job
+		res *api.JobResult
+		err error
+	}
+
+	results := make(chan jobResult)
+	reproduced := make(chan *api.JobReproduced)
+
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	for i := range jobs {
+		job := &jobs[i]
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			res, err := b.runJob(ctx, job, finished)
+			select {
+			case results <- jobResult{job: job, res: res, err: err}:
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	ids := make([]build.ID, 0, len(jobs))
+	for _, job := range jobs {
+		ids = append(ids, job.ID)
+	}
+
+	queueTicker := time.NewTicker(queueReportInterval)
+	defer queueTicker.Stop()
+
+	var positions map[build.ID]int
+	var unsatisfiable []build.ID
+
+	// remaining counts unfinished jobs and reproducibility checks.
+	for remaining := len(jobs); remaining > 0; {
+		var r jobResult
+		select {
+		case out := <-b.output:
+			if err := w.Updated(&api.StatusUpdate{JobOutput: out}); err != nil {
+				return err
+			}
+			continue
+		case <-queueTicker.C:
+			current := b.scheduler.QueuePositions(ids)
+			currentUnsatisfiable := b.scheduler.Unsatisfiable(ids)
+			if maps.Equal(current, positions) && slices.Equal(currentUnsatisfiable, unsatisfiable) {
+				continue
+			}
+
+			positions, unsatisfiable = current, currentUnsatisfiable
+			queued := &api.JobsQueued{Positions: positions, Unsatisfiable: unsatisfiable}
+			if err := w.Updated(&api.StatusUpdate{JobsQueued: queued}); err != nil {
+				return err
+			}
+			continue
+		case rep := <-reproduced:
+			remaining--
+			if err := w.Updated(&api.StatusUpdate{JobReproduced: rep}); err != nil {
+				return err
+			}
+			continue
+		case r = <-results:
+			remaining--
+		case <-b.cancelled:
+			return b.reportCancelled(w)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if r.err != nil {
+			return r.err
+		}
+
+		if err := b.recordResult(r.res); err != nil {
+			return err
+		}
+
+		if err := w.Updated(&api.StatusUpdate{JobFinished: r.res}); err != nil {
+			return err
+		}
+
+		if failed(r.res) {
+			if err := b.finish(); err != nil {
+				return err
+			}
+			return fmt.Errorf("job %q failed", r.job.Name)
+		}
+
+		close(finished[r.job.ID])
+
+		if _, ok := b.reproduce[r.job.ID]; ok {
+			remaining++
+
+			wg.Add(1)
+			go func(job *build.Job) {
+				defer wg.Done()
+
+				rep, err := b.reproduceJob(ctx, job)
+				if err != nil {
+					return
+				}
+
+				select {
+				case reproduced <- rep:
+				case <-ctx.Done():
+				}
+			}(r.job)
+		}
+	}
+
+	if err := b.finish(); err != nil {
+		return err
+	}
+
+	b.l.Info("build finished")
+	return w.Updated(&api.StatusUpdate{BuildFinished: &api.BuildFinished{}})
+}
@@ -3,16 +3,73 @@
 package dist
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
 
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
 	"gitlab.com/slon/shad-go/distbuild/pkg/scheduler"
 )
 
 type Coordinator struct {
+	l         *zap.Logger
+	fileCache *filecache.Cache
+	scheduler *scheduler.Scheduler
+	journal   *journal
+	metrics   *coordinatorMetrics
+	artifacts *artifact.Client
+	auth      *auth.Config
+	workers   []api.WorkerID
+	mux       *http.ServeMux
+	handler   http.Handler
+
+	// stopped is cancelled when coordinator is stopped.
+	stopped context.Context
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+
+	mu     sync.Mutex
+	builds map[build.ID]*Build
+	// results keeps results of the successful jobs, which artifacts are stored on some worker.
+	results map[build.ID]*api.JobResult
+	// drains tracks the workers taken out of service.
+	drains map[api.WorkerID]*drainState
+	// lastSeen is the time of the last heartbeat of the worker.
+	lastSeen map[api.WorkerID]time.Time
+}
+
+type Config struct {
+	// Scheduler configures job placement. Zero timeouts select the default timeouts.
+	Scheduler scheduler.Config
+
+	// JournalPath sets the location of the coordinator journal.
+	//
+	// When JournalPath is empty, coordinator keeps its state only in memory.
+	JournalPath string
+
+	// Auth protects the coordinator API and is presented to the workers, when fetching job results
+	// and manifests. When authentication is enabled, heartbeats and drains are accepted only from the workers
+	// with a client certificate valid for the host of the worker id.
+	Auth *auth.Config
+
+	// Workers lists ids of the workers allowed to send heartbeats. Empty list allows any worker.
+	Workers []api.WorkerID
+
+	// WorkerTimeout is the time without heartbeats, after which the worker is considered dead. Jobs of the dead
+	// worker are requeued and the worker is unregistered, as if it was drained with api.DrainRequest.Force.
+	// Zero selects defaultWorkerTimeout.
+	WorkerTimeout time.Duration
 }
 
 var defaultConfig = scheduler.Config{
@@ -20,15 +77,593 @@ var defaultConfig = scheduler.Config{
 	DepsTimeout:  time.Millisecond * 100,
 }
 
+const (
+	// pickTimeout bounds the time heartbeat waits for a new job.
+	pickTimeout = time.Second
+
+	// defaultWorkerTimeout is the default Config.WorkerTimeout. Idle worker sends heartbeat at least once
+	// per pickTimeout, so the dead worker misses many heartbeats before it is unregistered.
+	defaultWorkerTimeout = 30 * pickTimeout
+
+	// outputBufferSize is the number of job output chunks buffered for the client of the build.
+	outputBufferSize = 64
+
+	// queueReportInterval is the period of the queue position updates sent to the client.
+	queueReportInterval = 250 * time.Millisecond
+
+	// resultTimeout bounds the time spent on fetching the result of the cached job from the worker.
+	resultTimeout = 5 * time.Second
+)
+
+var (
+	_ api.Service          = (*Coordinator)(nil)
+	_ api.HeartbeatService = (*Coordinator)(nil)
+)
+
 func NewCoordinator(
 	log *zap.Logger,
 	fileCache *filecache.Cache,
 ) *Coordinator {
-	panic("implement me")
+	return newCoordinator(log, fileCache, Config{Scheduler: defaultConfig}, nil)
 }
 
-func (c *Coordinator) Stop() {}
+// OpenCoordinator creates coordinator, restoring its state from the journal.
+//
+// Unfinished builds from the journal are not executed until some client attaches to them.
+func OpenCoordinator(
+	log *zap.Logger,
+	fileCache *filecache.Cache,
+	config Config,
+) (*Coordinator, error) {
+	if config.Scheduler.CacheTimeout == 0 && config.Scheduler.DepsTimeout == 0 {
+		config.Scheduler.CacheTimeout = defaultConfig.CacheTimeout
+		config.Scheduler.DepsTimeout = defaultConfig.DepsTimeout
+	}
+
+	if config.JournalPath == "" {
+		return newCoordinator(log, fileCache, config, nil), nil
+	}
+
+	j, state, err := openJournal(config.JournalPath)
+	if err != nil {
+		return nil, fmt.Errorf("open journal: %w", err)
+	}
+
+	c := newCoordinator(log, fileCache, config, j)
+	for id, workers := range state.artifacts {
+		for workerID := range workers {
+			c.scheduler.OnArtifactAdded(workerID, id)
+		}
+	}
+
+	// Artifacts, that workers were not asked to delete before the restart, are removed once more.
+	for workerID, ids := range state.removals {
+		for id := range ids {
+			c.scheduler.OnArtifactAdded(workerID, id)
+			c.scheduler.RemoveArtifact(workerID, id)
+		}
+	}
+
+	// Workers restored from the journal are given the full timeout to send a heartbeat.
+	c.mu.Lock()
+	for _, workerID := range c.scheduler.Workers() {
+		c.lastSeen[workerID] = time.Now()
+	}
+	c.mu.Unlock()
+
+	for id, started := range state.builds {
+		b := newBuild(c.l, c.scheduler, j, c.metrics, c.artifacts, started)
+		b.results = state.results[id]
+		c.builds[id] = b
+
+		for jobID, res := range b.results {
+			if !failed(res) {
+				c.results[jobID] = res
+			}
+		}
+	}
+
+	log.Info("coordinator state restored",
+		zap.Int("builds", len(state.builds)),
+		zap.Int("artifacts", len(state.artifacts)))
+	return c, nil
+}
+
+func newCoordinator(
+	log *zap.Logger,
+	fileCache *filecache.Cache,
+	config Config,
+	j *journal,
+) *Coordinator {
+	c := &Coordinator{
+		l:         log,
+		fileCache: fileCache,
+		scheduler: scheduler.NewScheduler(log.Named("scheduler"), config.Scheduler, time.After),
+		journal:   j,
+		artifacts: artifact.NewClient(config.Auth.HTTPClient()),
+		auth:      config.Auth,
+		workers:   config.Workers,
+		mux:       http.NewServeMux(),
+		builds:    make(map[build.ID]*Build),
+		results:   make(map[build.ID]*api.JobResult),
+		drains:    make(map[api.WorkerID]*drainState),
+		lastSeen:  make(map[api.WorkerID]time.Time),
+	}
+	c.stopped, c.stop = context.WithCancel(context.Background())
+	c.metrics = newCoordinatorMetrics(c, c.scheduler, fileCache)
+
+	api.NewBuildService(log, c).Register(c.mux)
+	api.NewHeartbeatHandler(log, c).Register(c.mux)
+	api.NewDrainHandler(log, c).Register(c.mux)
+	filecache.NewHandler(log, fileCache).Register(c.mux)
+	c.metrics.Register(c.mux)
+	c.handler = config.Auth.Handler(c.mux)
+
+	timeout := config.WorkerTimeout
+	if timeout == 0 {
+		timeout = defaultWorkerTimeout
+	}
+	c.wg.Add(1)
+	go c.watchWorkers(timeout)
+	return c
+}
+
+// Stop interrupts running builds and releases coordinator resources.
+//
+// Status streams of the interrupted builds are closed without final status, so the clients
+// of durable coordinator can attach to the build after restart.
+func (c *Coordinator) Stop() {
+	c.mu.Lock()
+	c.stop()
+	c.mu.Unlock()
+
+	c.wg.Wait()
+	c.scheduler.Stop()
+	c.artifacts.CloseIdleConnections()
+
+	if err := c.journal.Close(); err != nil {
+		c.l.Warn("failed to close journal", zap.Error(err))
+	}
+}
 
 func (c *Coordinator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	panic("implement me")
+	c.handler.ServeHTTP(w, r)
+}
+
+func (c *Coordinator) missingFiles(graph *build.Graph) ([]build.ID, error) {
+	var missing []build.ID
+	for id := range graph.SourceFiles {
+		_, unlock, err := c.fileCache.Get(id)
+		if errors.Is(err, filecache.ErrNotFound) {
+			missing = append(missing, id)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		unlock()
+	}
+	return missing, nil
+}
+
+// lockSources prevents eviction of the build source files from the file cache.
+func (c *Coordinator) lockSources(graph *build.Graph) (unlock func(), err error) {
+	var unlocks []func()
+	unlock = func() {
+		for _, u := range unlocks {
+			u()
+		}
+	}
+
+	for id := range graph.SourceFiles {
+		_, u, err := c.fileCache.Get(id)
+		if err != nil {
+			unlock()
+			return nil, fmt.Errorf("source file %v: %w", id, err)
+		}
+		unlocks = append(unlocks, u)
+	}
+	return unlock, nil
+}
+
+func (c *Coordinator) createBuild(request *api.BuildRequest) (*Build, error) {
+	started := &buildStartedRecord{
+		ID:        build.NewID(),
+		Graph:     request.Graph,
+		User:      request.User,
+		Priority:  request.Priority,
+		Reproduce: request.Reproduce,
+	}
+
+	b := newBuild(c.l, c.scheduler, c.journal, c.metrics, c.artifacts, started)
+	if err := c.journal.append(&record{BuildStarted: started}); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.builds[b.ID] = b
+	return b, b.attach()
+}
+
+func (c *Coordinator) attachBuild(id build.ID) (*Build, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.builds[id]
+	if !ok {
+		return nil, fmt.Errorf("build %v not found", id)
+	}
+	return b, b.attach()
+}
+
+// detachBuild forgets the build, unless it might be attached again later.
+func (c *Coordinator) detachBuild(b *Build) {
+	b.detach()
+
+	if c.journal != nil && !b.isDone() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.builds, b.ID)
+}
+
+func (c *Coordinator) StartBuild(ctx context.Context, request *api.BuildRequest, w api.StatusWriter) error {
+	c.mu.Lock()
+	if c.stopped.Err() != nil {
+		c.mu.Unlock()
+		return fmt.Errorf("coordinator is stopped")
+	}
+	c.wg.Add(1)
+	c.mu.Unlock()
+	defer c.wg.Done()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(c.stopped, cancel)()
+
+	var b *Build
+	var err error
+	if request.BuildID != nil {
+		b, err = c.attachBuild(*request.BuildID)
+	} else {
+		b, err = c.createBuild(request)
+	}
+	if err != nil {
+		return err
+	}
+	defer c.detachBuild(b)
+
+	missing, err := c.missingFiles(&b.graph)
+	if err != nil {
+		return err
+	}
+
+	c.l.Info("build started",
+		zap.String("build_id", b.ID.String()),
+		zap.Bool("attached", request.BuildID != nil),
+		zap.Int("jobs", len(b.graph.Jobs)),
+		zap.Int("missing_files", len(missing)))
+
+	if err := w.Started(&api.BuildStarted{ID: b.ID, MissingFiles: missing}); err != nil {
+		return err
+	}
+
+	select {
+	case <-b.uploadDone:
+		var unlock func()
+		if unlock, err = c.lockSources(&b.graph); err == nil {
+			err = b.Run(ctx, w)
+			unlock()
+		}
+	case <-b.cancelled:
+		err = b.reportCancelled(w)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil && c.stopped.Err() != nil {
+		c.l.Info("build interrupted by coordinator stop", zap.String("build_id", b.ID.String()))
+		return nil
+	}
+	return err
+}
+
+func (c *Coordinator) SignalBuild(ctx context.Context, buildID build.ID, signal *api.SignalRequest) (*api.SignalResponse, error) {
+	c.mu.Lock()
+	b, ok := c.builds[buildID]
+	c.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("build %v not found", buildID)
+	}
+
+	switch {
+	case signal.UploadDone != nil:
+		b.signalUploadDone()
+	case signal.CancelBuild != nil:
+		if !b.cancel() {
+			// Nobody runs the build, so it is completed right away.
+			if err := b.finish(); err != nil {
+				return nil, err
+			}
+
+			c.mu.Lock()
+			delete(c.builds, b.ID)
+			c.mu.Unlock()
+		}
+	default:
+		return nil, fmt.Errorf("unknown signal")
+	}
+
+	return &api.SignalResponse{}, nil
+}
+
+// QueryCache reports jobs, which artifacts are present in the cache of some worker.
+//
+// Results are taken from memory for the jobs finished since the coordinator start, and from
+// the worker holding the artifact otherwise.
+func (c *Coordinator) QueryCache(ctx context.Context, request *api.QueryRequest) (*api.QueryResponse, error) {
+	var err error
+	rsp := &api.QueryResponse{Cached: map[build.ID]api.CachedJob{}}
+	for _, id := range request.Jobs {
+		workerID, ok := c.scheduler.LocateArtifact(id)
+		if !ok {
+			rsp.Missing = append(rsp.Missing, id)
+			continue
+		}
+
+		c.mu.Lock()
+		res, ok := c.results[id]
+		c.mu.Unlock()
+
+		if ok {
+			cached := *res
+			cached.Cached = true
+			cached.Trace = nil
+			res = &cached
+		} else if res, err = fetchResult(ctx, c.artifacts, workerID, id); err != nil {
+			res = nil
+			if !errors.Is(err, artifact.ErrNotFound) {
+				c.l.Warn("failed to fetch cached job result", zap.String("job_id", id.String()), zap.Error(err))
+			}
+		}
+
+		rsp.Cached[id] = api.CachedJob{WorkerID: workerID, Result: res}
+	}
+	return rsp, nil
+}
+
+// fetchResult downloads the result stored next to the artifact on the worker.
+func fetchResult(ctx context.Context, a *artifact.Client, workerID api.WorkerID, id build.ID) (*api.JobResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, resultTimeout)
+	defer cancel()
+
+	res, err := a.DownloadResult(ctx, workerID.String(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Trace describes the original run, the cached job is not executed again.
+	res.Cached = true
+	res.Trace = nil
+	return res, nil
+}
+
+// checkWorker rejects heartbeats of the workers missing from the allowed list and, when authentication
+// is enabled, of the peers without a certificate valid for the host of the worker id.
+func (c *Coordinator) checkWorker(ctx context.Context, workerID api.WorkerID) error {
+	if len(c.workers) != 0 && !slices.Contains(c.workers, workerID) {
+		return fmt.Errorf("worker %s: %w", workerID, api.ErrUnknownWorker)
+	}
+
+	if !c.auth.Enabled() {
+		return nil
+	}
+
+	// Bearer tokens belong to the build clients, only the certificate identifies the worker.
+	peer, _ := auth.PeerFromContext(ctx)
+	if peer.Certificate == nil {
+		return fmt.Errorf("worker %s: client certificate required: %w", workerID, api.ErrUnknownWorker)
+	}
+
+	u, err := url.Parse(workerID.String())
+	if err != nil {
+		return fmt.Errorf("worker %s: %w", workerID, api.ErrUnknownWorker)
+	}
+
+	if err := peer.Certificate.VerifyHostname(u.Hostname()); err != nil {
+		return fmt.Errorf("worker %s: %v: %w", workerID, err, api.ErrUnknownWorker)
+	}
+	return nil
+}
+
+func (c *Coordinator) Heartbeat(ctx context.Context, req *api.HeartbeatRequest) (*api.HeartbeatResponse, error) {
+	start := time.Now()
+
+	if err := c.checkWorker(ctx, req.WorkerID); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.lastSeen[req.WorkerID] = start
+	c.mu.Unlock()
+
+	c.scheduler.UpdateWorker(req.WorkerID, req.Resources, req.FreeResources, req.Labels)
+	draining := c.onDrainHeartbeat(req)
+
+	c.mu.Lock()
+	builds := make([]*Build, 0, len(c.builds))
+	for _, b := range c.builds {
+		builds = append(builds, b)
+	}
+	c.mu.Unlock()
+
+	// Failed attempts of the retried jobs are not reported to the builds.
+	finished := req.FinishedJob[:0:0]
+	for i := range req.FinishedJob {
+		res := &req.FinishedJob[i]
+
+		// Result of the losing copy of the speculatively executed job is dropped, but its artifact is kept.
+		if c.scheduler.Superseded(req.WorkerID, res) {
+			c.l.Debug("superseded job result dropped",
+				zap.String("job_id", res.ID.String()),
+				zap.String("worker_id", req.WorkerID.String()))
+			if !failed(res) {
+				req.AddedArtifacts = append(req.AddedArtifacts, res.ID)
+			}
+			continue
+		}
+
+		if failed(res) && c.scheduler.RetryJob(req.WorkerID, res) {
+			c.l.Info("job attempt failed, retrying",
+				zap.String("job_id", res.ID.String()),
+				zap.String("worker_id", req.WorkerID.String()),
+				zap.Int("attempt", res.Attempts),
+				zap.Int("exit_code", res.ExitCode),
+				zap.Stringp("error", res.Error))
+			continue
+		}
+		finished = append(finished, *res)
+
+		c.metrics.jobsFinished.Inc()
+		if failed(res) {
+			c.metrics.jobsFailed.Inc()
+		}
+	}
+
+	// Job results are attributed to every build containing the job, including the builds
+	// restored from the journal, that are waiting for the client to attach.
+	var records []*record
+	for _, res := range finished {
+		if !failed(&res) {
+			records = append(records, &record{ArtifactAdded: &artifactAddedRecord{WorkerID: req.WorkerID, ID: res.ID}})
+		}
+
+		for _, b := range builds {
+			if b.hasJob(res.ID) {
+				records = append(records, &record{JobFinished: &jobFinishedRecord{BuildID: b.ID, Result: res}})
+			}
+		}
+	}
+	for _, id := range req.AddedArtifacts {
+		records = append(records, &record{ArtifactAdded: &artifactAddedRecord{WorkerID: req.WorkerID, ID: id}})
+	}
+	for _, id := range req.RemovedArtifacts {
+		records = append(records, &record{ArtifactRemoved: &artifactRemovedRecord{WorkerID: req.WorkerID, ID: id}})
+	}
+
+	if err := c.journal.append(records...); err != nil {
+		return nil, err
+	}
+
+	// Output is not journaled, the full output is part of the job result.
+	for i := range req.JobOutput {
+		out := &req.JobOutput[i]
+		for _, b := range builds {
+			if b.hasJob(out.ID) {
+				b.sendOutput(out)
+			}
+		}
+	}
+
+	for i := range finished {
+		res := &finished[i]
+		for _, b := range builds {
+			if b.hasJob(res.ID) {
+				b.setResult(res)
+			}
+		}
+
+		if !failed(res) {
+			c.mu.Lock()
+			c.results[res.ID] = res
+			c.mu.Unlock()
+		}
+
+		c.scheduler.OnJobComplete(req.WorkerID, res.ID, res)
+	}
+
+	for _, id := range req.AddedArtifacts {
+		c.scheduler.OnArtifactAdded(req.WorkerID, id)
+	}
+
+	for _, id := range req.RemovedArtifacts {
+		c.scheduler.OnArtifactRemoved(req.WorkerID, id)
+
+		if _, ok := c.scheduler.LocateArtifact(id); !ok {
+			c.mu.Lock()
+			delete(c.results, id)
+			c.mu.Unlock()
+		}
+	}
+
+	for _, id := range c.scheduler.RequeueLost(req.WorkerID, req.RunningJobs) {
+		c.l.Warn("job lost by worker, requeued",
+			zap.String("job_id", id.String()),
+			zap.String("worker_id", req.WorkerID.String()))
+	}
+
+	rsp := &api.HeartbeatResponse{
+		JobsToRun:         map[build.ID]api.JobSpec{},
+		JobsToCancel:      c.scheduler.TakeCancelledJobs(req.WorkerID),
+		Draining:          draining,
+		ArtifactsToFetch:  c.takeFetches(req.WorkerID),
+		ArtifactsToRemove: c.scheduler.TakeRemovedArtifacts(req.WorkerID),
+	}
+
+	// Removals are delivered once, worker ignores the artifacts it does not have.
+	removals := make([]*record, 0, len(rsp.ArtifactsToRemove))
+	for _, id := range rsp.ArtifactsToRemove {
+		removals = append(removals, &record{ArtifactRemoved: &artifactRemovedRecord{WorkerID: req.WorkerID, ID: id}})
+	}
+	if err := c.journal.append(removals...); err != nil {
+		return nil, err
+	}
+
+	for _, id := range rsp.ArtifactsToRemove {
+		if _, ok := c.scheduler.LocateArtifact(id); !ok {
+			c.mu.Lock()
+			delete(c.results, id)
+			c.mu.Unlock()
+		}
+	}
+
+	if draining {
+		var err error
+		if rsp.Drained, err = c.continueDrain(req.WorkerID); err != nil {
+			return nil, err
+		}
+	}
+	c.metrics.heartbeatDuration.ObserveSince(start)
+
+	// Draining worker finishes running jobs, but does not get new ones.
+	if req.FreeSlots <= 0 || draining {
+		return rsp, nil
+	}
+
+	// Worker with running jobs is not kept waiting, so that it reports their results without delay.
+	timeout := pickTimeout
+	if len(req.RunningJobs) != 0 {
+		timeout = 0
+	}
+
+	pickCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for len(rsp.JobsToRun) < req.FreeSlots {
+		job := c.scheduler.PickJob(pickCtx, req.WorkerID)
+		if job == nil {
+			break
+		}
+		rsp.JobsToRun[job.Job.ID] = *job.Job
+
+		// Only the first job is waited for, the rest are taken only if already available.
+		cancel()
+	}
+
+	return rsp, nil
 }
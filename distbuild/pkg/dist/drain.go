@@ -0,0 +1,329 @@
+//go:build !solution
+
+package dist
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// fetchRetryInterval is the time after which the copy of the artifact is requested again,
+// if the artifact is still stored only on the draining worker.
+const fetchRetryInterval = 10 * time.Second
+
+var _ api.DrainService = (*Coordinator)(nil)
+
+// drainState tracks the worker taken out of service.
+type drainState struct {
+	replicate bool
+	// running is the number of jobs running on the worker according to its last heartbeat.
+	running int
+	// fetches maps unique artifact of the worker to the worker copying it.
+	fetches map[build.ID]*fetch
+	// next rotates the workers receiving the copies.
+	next    int
+	drained bool
+}
+
+type fetch struct {
+	target api.WorkerID
+	// sent is zero until the target is told to copy the artifact.
+	sent time.Time
+}
+
+// Drain takes the worker out of service. Worker stops getting new jobs, and is unregistered once its
+// running jobs are finished and, if requested, its unique artifacts are copied to other workers.
+func (c *Coordinator) Drain(ctx context.Context, workerID api.WorkerID, req *api.DrainRequest) (*api.DrainStatus, error) {
+	if workerID == "" {
+		return nil, fmt.Errorf("worker_id is required")
+	}
+
+	// Drain is an operator request, build clients holding only a token may not take workers out of service.
+	if c.auth.Enabled() {
+		if peer, _ := auth.PeerFromContext(ctx); peer.Certificate == nil {
+			return nil, fmt.Errorf("drain %s: client certificate required: %w", workerID, api.ErrUnknownWorker)
+		}
+	}
+
+	c.mu.Lock()
+	d, ok := c.drains[workerID]
+	c.mu.Unlock()
+
+	if !ok && !slices.Contains(c.scheduler.Workers(), workerID) {
+		return nil, fmt.Errorf("worker %s is not registered", workerID)
+	}
+
+	if !ok || !d.drained {
+		c.startDrain(workerID, req)
+	}
+
+	if req.Force {
+		if err := c.decommission(workerID); err != nil {
+			return nil, err
+		}
+		return c.drainStatus(workerID), nil
+	}
+
+	// Copies are requested right away, without waiting for the heartbeat of the draining worker.
+	if req.Replicate {
+		c.assignFetches(workerID, c.scheduler.UniqueArtifacts(workerID))
+	}
+	return c.drainStatus(workerID), nil
+}
+
+// startDrain marks the worker as draining. Replication, once requested, stays enabled.
+func (c *Coordinator) startDrain(workerID api.WorkerID, req *api.DrainRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	d, ok := c.drains[workerID]
+	if !ok || d.drained {
+		d = &drainState{fetches: map[build.ID]*fetch{}}
+		c.drains[workerID] = d
+
+		c.l.Info("draining worker", zap.String("worker_id", workerID.String()), zap.Bool("replicate", req.Replicate))
+	}
+	d.replicate = d.replicate || req.Replicate
+}
+
+func (c *Coordinator) drainStatus(workerID api.WorkerID) *api.DrainStatus {
+	c.mu.Lock()
+	d, ok := c.drains[workerID]
+	if !ok {
+		// Drained worker came back into service.
+		c.mu.Unlock()
+		return &api.DrainStatus{}
+	}
+	status := &api.DrainStatus{Draining: true, RunningJobs: d.running, Drained: d.drained}
+	replicate := d.replicate && !d.drained
+	c.mu.Unlock()
+
+	if replicate {
+		status.PendingArtifacts = len(c.scheduler.UniqueArtifacts(workerID))
+	}
+	return status
+}
+
+// onDrainHeartbeat updates the drain state from the heartbeat and reports whether the worker is draining.
+//
+// Heartbeat of the drained worker means, that the worker is back in service.
+func (c *Coordinator) onDrainHeartbeat(req *api.HeartbeatRequest) bool {
+	if req.Drain != nil {
+		c.startDrain(req.WorkerID, req.Drain)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	d, ok := c.drains[req.WorkerID]
+	if !ok {
+		return false
+	}
+
+	if d.drained {
+		delete(c.drains, req.WorkerID)
+		return false
+	}
+
+	d.running = len(req.RunningJobs)
+	return true
+}
+
+// isDraining reports whether the worker is draining. Must be called under mu.
+func (c *Coordinator) isDraining(workerID api.WorkerID) bool {
+	d, ok := c.drains[workerID]
+	return ok && !d.drained
+}
+
+// continueDrain requests copies of the unique artifacts of the draining worker and unregisters the worker,
+// when nothing is left to wait for. It reports whether the worker is drained.
+func (c *Coordinator) continueDrain(workerID api.WorkerID) (bool, error) {
+	c.mu.Lock()
+	d := c.drains[workerID]
+	running, replicate := d.running, d.replicate
+	c.mu.Unlock()
+
+	var unique []build.ID
+	if replicate {
+		unique = c.scheduler.UniqueArtifacts(workerID)
+		c.assignFetches(workerID, unique)
+	}
+
+	if running != 0 || len(unique) != 0 {
+		return false, nil
+	}
+
+	return true, c.unregister(workerID)
+}
+
+// decommission unregisters the worker without waiting for its heartbeat. Jobs running on the worker
+// are requeued, its unique artifacts are lost. Worker sending a heartbeat later is registered again.
+func (c *Coordinator) decommission(workerID api.WorkerID) error {
+	c.mu.Lock()
+	d, ok := c.drains[workerID]
+	drained := ok && d.drained
+	c.mu.Unlock()
+
+	if drained {
+		return nil
+	}
+
+	for _, id := range c.scheduler.RequeueLost(workerID, nil) {
+		c.l.Warn("job of decommissioned worker requeued",
+			zap.String("job_id", id.String()),
+			zap.String("worker_id", workerID.String()))
+	}
+	return c.unregister(workerID)
+}
+
+// watchWorkers decommissions the workers, that sent no heartbeat for longer than timeout.
+func (c *Coordinator) watchWorkers(timeout time.Duration) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(timeout / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.stopped.Done():
+			return
+		}
+
+		for _, workerID := range c.silentWorkers(timeout) {
+			c.l.Warn("worker sends no heartbeats, unregistering", zap.String("worker_id", workerID.String()))
+			if err := c.decommission(workerID); err != nil {
+				c.l.Error("failed to unregister silent worker", zap.String("worker_id", workerID.String()), zap.Error(err))
+			}
+		}
+	}
+}
+
+// silentWorkers returns the workers, that sent no heartbeat for longer than timeout, and forgets them.
+// Drained workers stop sending heartbeats on purpose and are skipped.
+func (c *Coordinator) silentWorkers(timeout time.Duration) []api.WorkerID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var silent []api.WorkerID
+	for workerID, seen := range c.lastSeen {
+		if time.Since(seen) <= timeout {
+			continue
+		}
+		delete(c.lastSeen, workerID)
+		if d, ok := c.drains[workerID]; ok && d.drained {
+			continue
+		}
+		silent = append(silent, workerID)
+	}
+	return silent
+}
+
+// unregister removes the worker from the scheduler and journals removal of its artifacts.
+func (c *Coordinator) unregister(workerID api.WorkerID) error {
+	// Pending removals are dropped together with the worker.
+	dropped := c.scheduler.TakeRemovedArtifacts(workerID)
+	removed := c.scheduler.UnregisterWorker(workerID)
+
+	records := make([]*record, 0, len(removed)+len(dropped))
+	for _, id := range append(removed, dropped...) {
+		records = append(records, &record{ArtifactRemoved: &artifactRemovedRecord{WorkerID: workerID, ID: id}})
+	}
+	if err := c.journal.append(records...); err != nil {
+		return err
+	}
+
+	var lost []build.ID
+	for _, id := range removed {
+		if _, ok := c.scheduler.LocateArtifact(id); !ok {
+			lost = append(lost, id)
+		}
+	}
+
+	c.mu.Lock()
+	for _, id := range lost {
+		delete(c.results, id)
+	}
+	if d, ok := c.drains[workerID]; ok {
+		d.drained = true
+		d.fetches = nil
+	}
+	delete(c.lastSeen, workerID)
+	c.mu.Unlock()
+
+	c.l.Info("worker drained",
+		zap.String("worker_id", workerID.String()),
+		zap.Int("removed_artifacts", len(removed)))
+	return nil
+}
+
+// assignFetches chooses workers copying the unique artifacts of the draining worker.
+//
+// Copies not done in fetchRetryInterval are requested again, possibly from another worker.
+func (c *Coordinator) assignFetches(workerID api.WorkerID, unique []build.ID) {
+	workers := c.scheduler.Workers()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	d, ok := c.drains[workerID]
+	if !ok || d.drained {
+		return
+	}
+
+	var targets []api.WorkerID
+	for _, target := range workers {
+		if target != workerID && !c.isDraining(target) {
+			targets = append(targets, target)
+		}
+	}
+
+	pending := make(map[build.ID]*fetch, len(unique))
+	for _, id := range unique {
+		f, ok := d.fetches[id]
+		if ok && (!slices.Contains(targets, f.target) || (!f.sent.IsZero() && time.Since(f.sent) > fetchRetryInterval)) {
+			ok = false
+		}
+
+		if !ok {
+			if len(targets) == 0 {
+				continue
+			}
+
+			f = &fetch{target: targets[d.next%len(targets)]}
+			d.next++
+		}
+		pending[id] = f
+	}
+	d.fetches = pending
+}
+
+// takeFetches returns artifacts of the draining workers, that the worker should copy.
+func (c *Coordinator) takeFetches(workerID api.WorkerID) map[build.ID]api.WorkerID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var fetches map[build.ID]api.WorkerID
+	for source, d := range c.drains {
+		for id, f := range d.fetches {
+			if f.target != workerID || !f.sent.IsZero() {
+				continue
+			}
+
+			if fetches == nil {
+				fetches = map[build.ID]api.WorkerID{}
+			}
+			fetches[id] = source
+			f.sent = time.Now()
+		}
+	}
+	return fetches
+}
@@ -0,0 +1,282 @@
+//go:build !solution
+
+package dist
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// record is a single entry of the coordinator journal.
+//
+// Exactly one field is set.
+type record struct {
+	BuildStarted    *buildStartedRecord    `json:",omitempty"`
+	JobFinished     *jobFinishedRecord     `json:",omitempty"`
+	BuildDone       *buildDoneRecord       `json:",omitempty"`
+	ArtifactAdded   *artifactAddedRecord   `json:",omitempty"`
+	ArtifactRemoved *artifactRemovedRecord `json:",omitempty"`
+}
+
+type buildStartedRecord struct {
+	ID        build.ID
+	Graph     build.Graph
+	User      string     `json:",omitempty"`
+	Priority  int        `json:",omitempty"`
+	Reproduce []build.ID `json:",omitempty"`
+}
+
+type jobFinishedRecord struct {
+	BuildID build.ID
+	Result  api.JobResult
+}
+
+type buildDoneRecord struct {
+	ID build.ID
+}
+
+type artifactAddedRecord struct {
+	WorkerID api.WorkerID
+	ID       build.ID
+}
+
+type artifactRemovedRecord struct {
+	WorkerID api.WorkerID
+	ID       build.ID
+	// Delete is set, when the coordinator has yet to ask the worker to delete the artifact.
+	Delete bool `json:",omitempty"`
+}
+
+// journal is an append-only log of coordinator state changes.
+//
+// Every change is written to the journal and synced to disk before it becomes visible to
+// workers and clients. On restart the coordinator replays the journal to restore builds
+// and artifact locations.
+//
+// All methods of nil *journal are no-op.
+type journal struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// journalState is the state restored from the journal.
+type journalState struct {
+	builds    map[build.ID]*buildStartedRecord
+	results   map[build.ID]map[build.ID]*api.JobResult
+	artifacts map[build.ID]map[api.WorkerID]struct{}
+	// removals are the artifacts, that workers have yet to be asked to delete.
+	removals map[api.WorkerID]map[build.ID]struct{}
+}
+
+func newJournalState() *journalState {
+	return &journalState{
+		builds:    make(map[build.ID]*buildStartedRecord),
+		results:   make(map[build.ID]map[build.ID]*api.JobResult),
+		artifacts: make(map[build.ID]map[api.WorkerID]struct{}),
+		removals:  make(map[api.WorkerID]map[build.ID]struct{}),
+	}
+}
+
+func (s *journalState) apply(r *record) error {
+	switch {
+	case r.BuildStarted != nil:
+		s.builds[r.BuildStarted.ID] = r.BuildStarted
+		s.results[r.BuildStarted.ID] = make(map[build.ID]*api.JobResult)
+
+	case r.JobFinished != nil:
+		// Results of already finished builds are not needed.
+		if results, ok := s.results[r.JobFinished.BuildID]; ok {
+			res := r.JobFinished.Result
+			results[res.ID] = &res
+		}
+
+	case r.BuildDone != nil:
+		delete(s.builds, r.BuildDone.ID)
+		delete(s.results, r.BuildDone.ID)
+
+	case r.ArtifactAdded != nil:
+		workers, ok := s.artifacts[r.ArtifactAdded.ID]
+		if !ok {
+			workers = make(map[api.WorkerID]struct{})
+			s.artifacts[r.ArtifactAdded.ID] = workers
+		}
+		workers[r.ArtifactAdded.WorkerID] = struct{}{}
+		s.forgetRemoval(r.ArtifactAdded.WorkerID, r.ArtifactAdded.ID)
+
+	case r.ArtifactRemoved != nil:
+		workers := s.artifacts[r.ArtifactRemoved.ID]
+		delete(workers, r.ArtifactRemoved.WorkerID)
+		if len(workers) == 0 {
+			delete(s.artifacts, r.ArtifactRemoved.ID)
+		}
+
+		if !r.ArtifactRemoved.Delete {
+			s.forgetRemoval(r.ArtifactRemoved.WorkerID, r.ArtifactRemoved.ID)
+			break
+		}
+		ids, ok := s.removals[r.ArtifactRemoved.WorkerID]
+		if !ok {
+			ids = make(map[build.ID]struct{})
+			s.removals[r.ArtifactRemoved.WorkerID] = ids
+		}
+		ids[r.ArtifactRemoved.ID] = struct{}{}
+
+	default:
+		return fmt.Errorf("empty journal record")
+	}
+
+	return nil
+}
+
+func (s *journalState) forgetRemoval(workerID api.WorkerID, id build.ID) {
+	ids := s.removals[workerID]
+	delete(ids, id)
+	if len(ids) == 0 {
+		delete(s.removals, workerID)
+	}
+}
+
+// records returns the minimal sequence of records reproducing the state.
+func (s *journalState) records() []*record {
+	var records []*record
+
+	for id, workers := range s.artifacts {
+		for workerID := range workers {
+			records = append(records, &record{ArtifactAdded: &artifactAddedRecord{WorkerID: workerID, ID: id}})
+		}
+	}
+
+	for workerID, ids := range s.removals {
+		for id := range ids {
+			records = append(records, &record{ArtifactRemoved: &artifactRemovedRecord{WorkerID: workerID, ID: id, Delete: true}})
+		}
+	}
+
+	for id, started := range s.builds {
+		records = append(records, &record{BuildStarted: started})
+		for _, res := range s.results[id] {
+			records = append(records, &record{JobFinished: &jobFinishedRecord{BuildID: id, Result: *res}})
+		}
+	}
+
+	return records
+}
+
+// readJournal reads all complete records from the journal file.
+//
+// Incomplete trailing record, left after a crash in the middle of the write, is ignored.
+func readJournal(path string) (*journalState, error) {
+	state := newJournalState()
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return state, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return nil, fmt.Errorf("corrupted journal %s: %w", path, err)
+		}
+
+		if err := state.apply(&rec); err != nil {
+			return nil, fmt.Errorf("corrupted journal %s: %w", path, err)
+		}
+	}
+}
+
+// openJournal replays the journal at path and compacts it.
+func openJournal(path string) (*journal, *journalState, error) {
+	state, err := readJournal(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return nil, nil, err
+	}
+
+	tmpPath := path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	j := &journal{f: tmp}
+	if err := j.append(state.records()...); err != nil {
+		_ = tmp.Close()
+		return nil, nil, err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = tmp.Close()
+		return nil, nil, err
+	}
+
+	return j, state, nil
+}
+
+// append writes records to the journal and waits until they reach the disk.
+func (j *journal) append(records ...*record) error {
+	if j == nil || len(records) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.f.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("journal write: %w", err)
+	}
+
+	if err := j.f.Sync(); err != nil {
+		return fmt.Errorf("journal sync: %w", err)
+	}
+
+	return nil
+}
+
+func (j *journal) Close() error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.f.Close()
+}
@@ -0,0 +1,63 @@
+//go:build !solution
+
+package dist
+
+import (
+	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+	"gitlab.com/slon/shad-go/distbuild/pkg/metrics"
+	"gitlab.com/slon/shad-go/distbuild/pkg/scheduler"
+)
+
+// coordinatorMetrics are served at /metrics of the coordinator.
+type coordinatorMetrics struct {
+	*metrics.Registry
+
+	heartbeatDuration *metrics.Histogram
+	jobsScheduled     *metrics.Counter
+	jobsCached        *metrics.Counter
+	jobsFinished      *metrics.Counter
+	jobsFailed        *metrics.Counter
+}
+
+func newCoordinatorMetrics(c *Coordinator, s *scheduler.Scheduler, fileCache *filecache.Cache) *coordinatorMetrics {
+	r := metrics.NewRegistry()
+
+	m := &coordinatorMetrics{
+		Registry: r,
+
+		heartbeatDuration: r.NewHistogram("distbuild_coordinator_heartbeat_duration_seconds",
+			"Time spent on processing worker heartbeats, excluding waiting for new jobs.", metrics.DurationBuckets),
+		jobsScheduled: r.NewCounter("distbuild_coordinator_jobs_scheduled_total",
+			"Jobs of the builds sent to the scheduler."),
+		jobsCached: r.NewCounter("distbuild_coordinator_jobs_cached_total",
+			"Jobs of the builds, which artifacts were found in the cache of some worker."),
+		jobsFinished: r.NewCounter("distbuild_coordinator_jobs_finished_total",
+			"Job results reported by the workers, excluding retried attempts."),
+		jobsFailed: r.NewCounter("distbuild_coordinator_jobs_failed_total",
+			"Failed job results reported by the workers, excluding retried attempts."),
+	}
+
+	r.NewGaugeFunc("distbuild_coordinator_builds", "Builds known to the coordinator.", func() float64 {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return float64(len(c.builds))
+	})
+
+	r.NewGaugeFunc("distbuild_scheduler_queued_jobs", "Jobs waiting for a worker.", func() float64 {
+		return float64(s.Stats().Queued)
+	})
+	r.NewGaugeFunc("distbuild_scheduler_running_jobs", "Jobs picked by the workers and not yet completed.", func() float64 {
+		return float64(s.Stats().Running)
+	})
+	r.NewGaugeFunc("distbuild_scheduler_workers", "Workers registered in the scheduler.", func() float64 {
+		return float64(s.Stats().Workers)
+	})
+
+	r.NewCounterFunc("distbuild_coordinator_file_cache_hits_total", "Source file lookups found in the file cache.", func() float64 {
+		return float64(fileCache.Stats().Hits)
+	})
+	r.NewCounterFunc("distbuild_coordinator_file_cache_misses_total", "Source file lookups missing from the file cache.", func() float64 {
+		return float64(fileCache.Stats().Misses)
+	})
+	return m
+}
@@ -0,0 +1,254 @@
+package filecache
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// ErrChunkCorrupted is returned when the content of the chunk does not match its id.
+var ErrChunkCorrupted = errors.New("chunk content does not match id")
+
+// Chunk is a part of the file, identified by sha1 of its content.
+type Chunk struct {
+	ID   build.ID
+	Size int64
+}
+
+const (
+	minChunkSize = 16 << 10
+	maxChunkSize = 256 << 10
+
+	// chunkMask selects 16 bits of the rolling hash, so the average chunk is 64KiB above minChunkSize.
+	// The highest bits are used, because they depend on the last 64 bytes of the input.
+	chunkMask = uint64(0xffff) << 48
+
+	chunkFileName   = "chunk"
+	chunkRecipeName = "chunks.json"
+)
+
+// gear maps input bytes to random values of the rolling hash.
+var gear [256]uint64
+
+func init() {
+	// splitmix64 with a fixed seed, so that all parts of the system cut files at the same places.
+	seed := uint64(0x5eed)
+	for i := range gear {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gear[i] = z ^ (z >> 31)
+	}
+}
+
+// SplitChunks cuts the stream into content-defined chunks.
+//
+// Chunk boundaries depend only on the nearby content, so a local change of the file changes only
+// the chunks around it. data is valid only during the call of chunkFn.
+func SplitChunks(r io.Reader, chunkFn func(chunk Chunk, data []byte) error) error {
+	// buf holds the current chunk followed by the bytes read ahead, n is the number of bytes in buf.
+	buf := make([]byte, maxChunkSize)
+	n, eof := 0, false
+
+	fill := func() error {
+		for n < len(buf) && !eof {
+			m, err := r.Read(buf[n:])
+			n += m
+			if err == io.EOF {
+				eof = true
+			} else if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for {
+		if err := fill(); err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+
+		// buf is full unless the stream ended, so the chunk ends at the boundary, at maxChunkSize or at EOF.
+		size := cutPoint(buf[:n])
+		data := buf[:size]
+		if err := chunkFn(Chunk{ID: sha1.Sum(data), Size: int64(size)}, data); err != nil {
+			return err
+		}
+
+		n = copy(buf, buf[size:n])
+	}
+}
+
+// cutPoint returns the size of the first chunk of data, or len(data) when data has no chunk boundary.
+func cutPoint(data []byte) int {
+	if len(data) <= minChunkSize {
+		return len(data)
+	}
+	// The masked bits of the hash depend only on the last 64 bytes, so the bytes before them are skipped.
+	var h uint64
+	for i := minChunkSize - 64; i < len(data); i++ {
+		h = (h << 1) + gear[data[i]]
+		if i+1 >= minChunkSize && h&chunkMask == 0 {
+			return i + 1
+		}
+	}
+	return len(data)
+}
+
+// MissingChunks returns chunks absent from the chunk store.
+func (c *Cache) MissingChunks(chunks []build.ID) []build.ID {
+	var missing []build.ID
+	for _, id := range chunks {
+		_, unlock, err := c.chunks.Get(id)
+		if err != nil {
+			missing = append(missing, id)
+			continue
+		}
+		unlock()
+	}
+	return missing
+}
+
+// WriteChunk stores the chunk, checking that the content matches the id.
+//
+// Writing the chunk already present in the store is not an error.
+func (c *Cache) WriteChunk(id build.ID, r io.Reader) error {
+	path, commit, abort, err := c.chunks.Create(id)
+	if errors.Is(err, artifact.ErrExists) {
+		return nil
+	} else if err != nil {
+		return convertErr(err)
+	}
+
+	writeChunk := func() error {
+		f, err := os.Create(filepath.Join(path, chunkFileName))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		h := sha1.New()
+		if _, err := io.Copy(io.MultiWriter(f, h), r); err != nil {
+			return err
+		}
+
+		if build.ID(h.Sum(nil)) != id {
+			return fmt.Errorf("chunk %v: %w", id, ErrChunkCorrupted)
+		}
+		return f.Close()
+	}
+
+	if err := writeChunk(); err != nil {
+		_ = abort()
+		return err
+	}
+	return commit()
+}
+
+// GetChunk returns path of the chunk. unlock must be called after the chunk is read.
+func (c *Cache) GetChunk(id build.ID) (path string, unlock func(), err error) {
+	root, unlock, err := c.chunks.Get(id)
+	path = filepath.Join(root, chunkFileName)
+	err = convertErr(err)
+	return
+}
+
+// Assemble writes the file from the chunks present in the chunk store.
+//
+// The list of chunks is kept next to the file, so that it can be sent to the other caches.
+// ErrNotFound is returned when some chunk is missing from the store.
+func (c *Cache) Assemble(file build.ID, chunks []Chunk) error {
+	path, commit, abort, err := c.cache.Create(file)
+	if err != nil {
+		return convertErr(err)
+	}
+
+	if err := c.assemble(path, chunks); err != nil {
+		_ = abort()
+		return err
+	}
+	return commit()
+}
+
+func (c *Cache) assemble(dir string, chunks []Chunk) error {
+	f, err := os.Create(filepath.Join(dir, fileName))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	for _, chunk := range chunks {
+		if err := c.appendChunk(f, chunk); err != nil {
+			return err
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	recipe, err := json.Marshal(chunks)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, chunkRecipeName), recipe, 0666)
+}
+
+func (c *Cache) appendChunk(w io.Writer, chunk Chunk) error {
+	path, unlock, err := c.GetChunk(chunk.ID)
+	if err != nil {
+		return fmt.Errorf("chunk %v: %w", chunk.ID, err)
+	}
+	defer unlock()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := io.Copy(w, f)
+	if err != nil {
+		return err
+	}
+
+	if n != chunk.Size {
+		return fmt.Errorf("chunk %v: size %d does not match expected %d", chunk.ID, n, chunk.Size)
+	}
+	return nil
+}
+
+// Chunks returns the chunks of the file assembled by Assemble.
+//
+// ErrNotFound is returned when the file is missing or was written whole.
+func (c *Cache) Chunks(file build.ID) ([]Chunk, error) {
+	root, unlock, err := c.cache.Get(file)
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer unlock()
+
+	recipe, err := os.ReadFile(filepath.Join(root, chunkRecipeName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	var chunks []Chunk
+	if err := json.Unmarshal(recipe, &chunks); err != nil {
+		return nil, fmt.Errorf("file %v: invalid chunk list: %w", file, err)
+	}
+	return chunks, nil
+}
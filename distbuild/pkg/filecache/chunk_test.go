@@ -0,0 +1,62 @@
+package filecache
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+// splitBytes is the straightforward version of SplitChunks, hashing every byte of the chunk.
+func splitBytes(content []byte) []Chunk {
+	var chunks []Chunk
+	var h uint64
+	start := 0
+	for i, b := range content {
+		h = (h << 1) + gear[b]
+
+		size := i + 1 - start
+		if size >= minChunkSize && h&chunkMask == 0 || size == maxChunkSize || i == len(content)-1 {
+			chunks = append(chunks, Chunk{ID: sha1.Sum(content[start : i+1]), Size: int64(size)})
+			start, h = i+1, 0
+		}
+	}
+	return chunks
+}
+
+func TestSplitChunks(t *testing.T) {
+	random := make([]byte, 4<<20)
+	_, _ = rand.Read(random)
+
+	for _, tc := range []struct {
+		name    string
+		content []byte
+	}{
+		{"Empty", nil},
+		{"Small", []byte("foobar")},
+		{"Random", random},
+		{"Repeated", bytes.Repeat([]byte("foobar"), 1024*1024)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Short reads must not move the chunk boundaries.
+			split := func(r io.Reader) []Chunk {
+				var chunks []Chunk
+				require.NoError(t, SplitChunks(r, func(chunk Chunk, data []byte) error {
+					require.Equal(t, Chunk{ID: sha1.Sum(data), Size: int64(len(data))}, chunk)
+					chunks = append(chunks, chunk)
+					return nil
+				}))
+				return chunks
+			}
+
+			expected := splitBytes(tc.content)
+			require.Equal(t, expected, split(bytes.NewReader(tc.content)))
+			require.Equal(t, expected, split(iotest.HalfReader(bytes.NewReader(tc.content))))
+			require.Equal(t, expected, split(iotest.OneByteReader(bytes.NewReader(tc.content))))
+		})
+	}
+}
@@ -3,24 +3,348 @@
 package filecache
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
 
 	"go.uber.org/zap"
 
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/tarstream"
 )
 
 type Client struct {
+	l        *zap.Logger
+	endpoint string
+	client   *http.Client
 }
 
 func NewClient(l *zap.Logger, endpoint string) *Client {
-	panic("implement me")
+	return NewClientWithHTTPClient(l, endpoint, http.DefaultClient)
 }
 
+// NewClientWithHTTPClient creates client sending requests through c, for example to present credentials.
+func NewClientWithHTTPClient(l *zap.Logger, endpoint string, c *http.Client) *Client {
+	return &Client{l: l, endpoint: endpoint, client: c}
+}
+
+func (c *Client) fileURL(id build.ID) string {
+	return c.endpoint + "/file?id=" + url.QueryEscape(id.String())
+}
+
+func checkStatus(rsp *http.Response) error {
+	if rsp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	body, _ := io.ReadAll(rsp.Body)
+	return fmt.Errorf("http status %d: %s", rsp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// chunkedUploadThreshold is the size of the file, starting from which it is uploaded in chunks.
+//
+// Smaller files fit into a few chunks and are sent in a single request.
+const chunkedUploadThreshold = 4 * maxChunkSize
+
+// errChunksEvicted is returned when the server lost the chunks before assembling the file.
+var errChunksEvicted = errors.New("chunks evicted before assembly")
+
+// Upload sends the file to the remote cache. Chunks of the large files already known
+// to the remote cache are not sent.
 func (c *Client) Upload(ctx context.Context, id build.ID, localPath string) error {
-	panic("implement me")
+	st, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+
+	if st.Size() >= chunkedUploadThreshold {
+		// Splitting the large file costs a full read, which is wasted when the file is already there.
+		if c.exists(ctx, id) {
+			c.l.Debug("file is already uploaded", zap.String("id", id.String()))
+			return nil
+		}
+
+		err := c.uploadChunks(ctx, id, localPath)
+		if !errors.Is(err, errChunksEvicted) {
+			return err
+		}
+
+		c.l.Warn("chunked upload failed, sending whole file", zap.String("id", id.String()), zap.Error(err))
+	}
+
+	return c.uploadFile(ctx, id, localPath)
+}
+
+// exists reports whether the remote cache has the file. Errors are treated as absence of the file.
+func (c *Client) exists(ctx context.Context, id build.ID) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.fileURL(id), nil)
+	if err != nil {
+		return false
+	}
+
+	rsp, err := c.client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer rsp.Body.Close()
+
+	return rsp.StatusCode == http.StatusOK
+}
+
+func (c *Client) uploadFile(ctx context.Context, id build.ID, localPath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.fileURL(id), f)
+	if err != nil {
+		return err
+	}
+
+	c.l.Debug("uploading file", zap.String("id", id.String()), zap.String("path", localPath))
+
+	rsp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if err := checkStatus(rsp); err != nil {
+		return fmt.Errorf("upload file %v: %w", id, err)
+	}
+	return nil
+}
+
+func (c *Client) uploadChunks(ctx context.Context, id build.ID, localPath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var (
+		chunks  []Chunk
+		offsets []int64
+		offset  int64
+	)
+
+	err = SplitChunks(f, func(chunk Chunk, data []byte) error {
+		chunks = append(chunks, chunk)
+		offsets = append(offsets, offset)
+		offset += chunk.Size
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ids := make([]build.ID, len(chunks))
+	for i, chunk := range chunks {
+		ids[i] = chunk.ID
+	}
+
+	missing, err := c.missingChunks(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("upload file %v: %w", id, err)
+	}
+
+	c.l.Debug("uploading file in chunks",
+		zap.String("id", id.String()),
+		zap.String("path", localPath),
+		zap.Int("chunks", len(chunks)),
+		zap.Int("missing", len(missing)))
+
+	for i, chunk := range chunks {
+		if _, ok := missing[chunk.ID]; !ok {
+			continue
+		}
+		delete(missing, chunk.ID)
+
+		body := io.NewSectionReader(f, offsets[i], chunk.Size)
+		if err := c.do(ctx, http.MethodPut, c.chunkURL(chunk.ID), body, ""); err != nil {
+			return fmt.Errorf("upload file %v: chunk %v: %w", id, chunk.ID, err)
+		}
+	}
+
+	recipe, err := json.Marshal(chunks)
+	if err != nil {
+		return err
+	}
+
+	err = c.do(ctx, http.MethodPut, c.fileURL(id), bytes.NewReader(recipe), ChunksContentType)
+	if err != nil {
+		return fmt.Errorf("upload file %v: %w", id, err)
+	}
+	return nil
+}
+
+func (c *Client) chunkURL(id build.ID) string {
+	return c.endpoint + "/chunk?id=" + url.QueryEscape(id.String())
+}
+
+func (c *Client) do(ctx context.Context, method, u string, body io.Reader, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	rsp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode == http.StatusConflict {
+		return errChunksEvicted
+	}
+	return checkStatus(rsp)
+}
+
+func (c *Client) missingChunks(ctx context.Context, chunks []build.ID) (map[build.ID]struct{}, error) {
+	body, err := json.Marshal(chunks)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/chunks/missing", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	if err := checkStatus(rsp); err != nil {
+		return nil, err
+	}
+
+	var missing []build.ID
+	if err := json.NewDecoder(rsp.Body).Decode(&missing); err != nil {
+		return nil, err
+	}
+
+	set := make(map[build.ID]struct{}, len(missing))
+	for _, id := range missing {
+		set[id] = struct{}{}
+	}
+	return set, nil
 }
 
 func (c *Client) Download(ctx context.Context, localCache *Cache, id build.ID) error {
-	panic("implement me")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.fileURL(id), nil)
+	if err != nil {
+		return err
+	}
+
+	// Setting the header disables transparent decompression of the transport.
+	req.Header.Set("Accept-Encoding", tarstream.AcceptEncoding)
+	req.Header.Set("Accept", ChunksContentType+", */*")
+
+	rsp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if err := checkStatus(rsp); err != nil {
+		return fmt.Errorf("download file %v: %w", id, err)
+	}
+
+	body, err := tarstream.NewReader(rsp.Body, rsp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return fmt.Errorf("download file %v: %w", id, err)
+	}
+	defer body.Close()
+
+	if rsp.Header.Get("Content-Type") == ChunksContentType {
+		var chunks []Chunk
+		if err := json.NewDecoder(body).Decode(&chunks); err != nil {
+			return fmt.Errorf("download file %v: %w", id, err)
+		}
+		return c.downloadChunks(ctx, localCache, id, chunks)
+	}
+
+	w, abort, err := localCache.Write(id)
+	if errors.Is(err, ErrExists) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, body); err != nil {
+		_ = abort()
+		return fmt.Errorf("download file %v: %w", id, err)
+	}
+
+	c.l.Debug("file downloaded", zap.String("id", id.String()))
+	return w.Close()
+}
+
+// downloadChunks fetches chunks missing from the local cache and assembles the file from them.
+func (c *Client) downloadChunks(ctx context.Context, localCache *Cache, id build.ID, chunks []Chunk) error {
+	ids := make([]build.ID, len(chunks))
+	for i, chunk := range chunks {
+		ids[i] = chunk.ID
+	}
+
+	missing := localCache.MissingChunks(ids)
+	for _, chunk := range missing {
+		if err := c.downloadChunk(ctx, localCache, chunk); err != nil {
+			return fmt.Errorf("download file %v: chunk %v: %w", id, chunk, err)
+		}
+	}
+
+	c.l.Debug("assembling file from chunks",
+		zap.String("id", id.String()),
+		zap.Int("chunks", len(chunks)),
+		zap.Int("downloaded", len(missing)))
+
+	err := localCache.Assemble(id, chunks)
+	if errors.Is(err, ErrExists) {
+		return nil
+	}
+	return err
+}
+
+func (c *Client) downloadChunk(ctx context.Context, localCache *Cache, id build.ID) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.chunkURL(id), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept-Encoding", tarstream.AcceptEncoding)
+
+	rsp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if err := checkStatus(rsp); err != nil {
+		return err
+	}
+
+	body, err := tarstream.NewReader(rsp.Body, rsp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	return localCache.WriteChunk(id, body)
 }
@@ -3,11 +3,13 @@ package filecache_test
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -127,3 +129,107 @@ func TestFileDownload(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, []byte("foobar"), content)
 }
+
+// countChunks counts requests to the chunk store made by the client.
+func countChunks(handler http.Handler, puts, gets *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunk" {
+			switch r.Method {
+			case http.MethodPut:
+				puts.Add(1)
+			case http.MethodGet:
+				gets.Add(1)
+			}
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func TestChunkedTransfer(t *testing.T) {
+	l := zaptest.NewLogger(t)
+	remoteCache := newCache(t)
+	localCache := newCache(t)
+
+	mux := http.NewServeMux()
+	filecache.NewHandler(l, remoteCache.Cache).Register(mux)
+
+	var puts, gets atomic.Int32
+	server := httptest.NewServer(countChunks(mux, &puts, &gets))
+	defer server.Close()
+
+	client := filecache.NewClient(l, server.URL)
+	ctx := context.Background()
+
+	content := make([]byte, 4<<20)
+	_, _ = rand.Read(content)
+
+	// The second version differs from the first one in a few bytes in the middle.
+	changed := bytes.Clone(content)
+	copy(changed[len(changed)/2:], "generated code changed")
+
+	tmpDir := t.TempDir()
+	upload := func(id build.ID, content []byte) {
+		path := filepath.Join(tmpDir, id.String())
+		require.NoError(t, os.WriteFile(path, content, 0666))
+		require.NoError(t, client.Upload(ctx, id, path))
+	}
+
+	checkFile := func(cache *testCache, id build.ID, content []byte) {
+		path, unlock, err := cache.Get(id)
+		require.NoError(t, err)
+		defer unlock()
+
+		actual, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.True(t, bytes.Equal(content, actual))
+	}
+
+	upload(build.ID{0x01}, content)
+	checkFile(remoteCache, build.ID{0x01}, content)
+	firstPuts := puts.Load()
+	require.Greater(t, firstPuts, int32(16))
+
+	upload(build.ID{0x02}, changed)
+	checkFile(remoteCache, build.ID{0x02}, changed)
+	require.LessOrEqual(t, puts.Load()-firstPuts, int32(2))
+
+	require.NoError(t, client.Download(ctx, localCache.Cache, build.ID{0x01}))
+	checkFile(localCache, build.ID{0x01}, content)
+	require.Equal(t, firstPuts, gets.Load())
+
+	require.NoError(t, client.Download(ctx, localCache.Cache, build.ID{0x02}))
+	checkFile(localCache, build.ID{0x02}, changed)
+	require.LessOrEqual(t, gets.Load()-firstPuts, int32(2))
+}
+
+func TestRepeatedUploadSkipsChunking(t *testing.T) {
+	l := zaptest.NewLogger(t)
+	remoteCache := newCache(t)
+
+	mux := http.NewServeMux()
+	filecache.NewHandler(l, remoteCache.Cache).Register(mux)
+
+	var missing atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunks/missing" {
+			missing.Add(1)
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := filecache.NewClient(l, server.URL)
+	ctx := context.Background()
+
+	content := make([]byte, 4<<20)
+	_, _ = rand.Read(content)
+
+	path := filepath.Join(t.TempDir(), "foo.bin")
+	require.NoError(t, os.WriteFile(path, content, 0666))
+
+	require.NoError(t, client.Upload(ctx, build.ID{0x01}, path))
+	require.Equal(t, int32(1), missing.Load())
+
+	require.NoError(t, client.Upload(ctx, build.ID{0x01}, path))
+	require.Equal(t, int32(1), missing.Load())
+}
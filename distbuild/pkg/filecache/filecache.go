@@ -36,18 +36,40 @@ func convertErr(err error) error {
 
 type Cache struct {
 	cache *artifact.Cache
+	// chunks stores parts of the files uploaded in chunks, see Assemble.
+	chunks *artifact.Cache
 }
 
 func New(rootDir string) (*Cache, error) {
-	cache, err := artifact.NewCache(rootDir)
+	return NewWithConfig(rootDir, artifact.Config{})
+}
+
+// NewWithConfig creates file cache with limited size. See artifact.Config for details.
+//
+// Chunk store is kept in the chunks subdirectory and is limited by the same config.
+func NewWithConfig(rootDir string, config artifact.Config) (*Cache, error) {
+	cache, err := artifact.NewCacheWithConfig(rootDir, config)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := artifact.NewCacheWithConfig(filepath.Join(rootDir, "chunks"), config)
 	if err != nil {
 		return nil, err
 	}
 
-	c := &Cache{cache: cache}
+	c := &Cache{cache: cache, chunks: chunks}
 	return c, nil
 }
 
+// Evict removes least recently used files and chunks until the cache fits into the limits.
+func (c *Cache) Evict() error {
+	if err := c.cache.Evict(); err != nil {
+		return err
+	}
+	return c.chunks.Evict()
+}
+
 func (c *Cache) Range(fileFn func(file build.ID) error) error {
 	return c.cache.Range(fileFn)
 }
@@ -85,7 +107,7 @@ func (c *Cache) Write(file build.ID) (w io.WriteCloser, abort func() error, err
 
 	f, err := os.Create(filepath.Join(path, fileName))
 	if err != nil {
-		_ = abort()
+		_ = abortDir()
 		return
 	}
 
@@ -103,6 +125,11 @@ func (c *Cache) Write(file build.ID) (w io.WriteCloser, abort func() error, err
 	return
 }
 
+// Stats counts lookups of the files by Get. Lookups of the chunks are not counted.
+func (c *Cache) Stats() artifact.Stats {
+	return c.cache.Stats()
+}
+
 func (c *Cache) Get(file build.ID) (path string, unlock func(), err error) {
 	root, unlock, err := c.cache.Get(file)
 	path = filepath.Join(root, fileName)
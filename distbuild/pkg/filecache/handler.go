@@ -3,18 +3,256 @@
 package filecache
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"os"
+	"strings"
 
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/tarstream"
 )
 
+// ChunksContentType marks the body containing JSON list of the file chunks instead of the file content.
+const ChunksContentType = "application/vnd.distbuild.chunks+json"
+
 type Handler struct {
+	l      *zap.Logger
+	cache  *Cache
+	upload singleflight.Group
 }
 
 func NewHandler(l *zap.Logger, cache *Cache) *Handler {
-	panic("implement me")
+	return &Handler{l: l, cache: cache}
 }
 
 func (h *Handler) Register(mux *http.ServeMux) {
-	panic("implement me")
+	mux.HandleFunc("/file", h.file)
+	mux.HandleFunc("/chunk", h.chunk)
+	mux.HandleFunc("/chunks/missing", h.missingChunks)
+}
+
+func (h *Handler) file(w http.ResponseWriter, r *http.Request) {
+	var id build.ID
+	if err := id.UnmarshalText([]byte(r.URL.Query().Get("id"))); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.get(w, r, id)
+	case http.MethodHead:
+		h.head(w, id)
+	case http.MethodPut:
+		if r.Header.Get("Content-Type") == ChunksContentType {
+			h.assemble(w, r, id)
+		} else {
+			h.put(w, r, id)
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, id build.ID) {
+	if strings.Contains(r.Header.Get("Accept"), ChunksContentType) {
+		chunks, err := h.cache.Chunks(id)
+		if err == nil {
+			w.Header().Set("Content-Type", ChunksContentType)
+			_ = json.NewEncoder(w).Encode(chunks)
+			return
+		} else if !errors.Is(err, ErrNotFound) {
+			h.l.Warn("file chunks are not available", zap.String("id", id.String()), zap.Error(err))
+		}
+	}
+
+	path, unlock, err := h.cache.Get(id)
+	if err != nil {
+		h.l.Warn("file is not available", zap.String("id", id.String()), zap.Error(err))
+
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrNotFound) {
+			code = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	defer unlock()
+
+	if err := h.send(w, r, path); err != nil {
+		h.l.Warn("failed to send file", zap.String("id", id.String()), zap.Error(err))
+	}
+}
+
+// head reports whether the file is present, without sending it.
+func (h *Handler) head(w http.ResponseWriter, id build.ID) {
+	_, unlock, err := h.cache.Get(id)
+	switch {
+	case err == nil:
+		unlock()
+		w.WriteHeader(http.StatusOK)
+	case errors.Is(err, ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// send writes the file to the response, compressing it when the client accepts that.
+func (h *Handler) send(w http.ResponseWriter, r *http.Request, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
+	}
+	defer f.Close()
+
+	encoding := tarstream.NegotiateEncoding(r.Header.Get("Accept-Encoding"))
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	cw, err := tarstream.NewWriter(w, encoding)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(cw, f); err != nil {
+		return err
+	}
+	return cw.Close()
+}
+
+func (h *Handler) put(w http.ResponseWriter, r *http.Request, id build.ID) {
+	_, err, _ := h.upload.Do(id.String(), func() (any, error) {
+		fw, abort, err := h.cache.Write(id)
+		if errors.Is(err, ErrExists) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if _, err := io.Copy(fw, r.Body); err != nil {
+			_ = abort()
+			return nil, err
+		}
+
+		return nil, fw.Close()
+	})
+
+	if err != nil {
+		h.l.Error("file upload failed", zap.String("id", id.String()), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.l.Debug("file uploaded", zap.String("id", id.String()))
+	w.WriteHeader(http.StatusOK)
+}
+
+// assemble writes the file from the chunks uploaded before.
+func (h *Handler) assemble(w http.ResponseWriter, r *http.Request, id build.ID) {
+	var chunks []Chunk
+	if err := json.NewDecoder(r.Body).Decode(&chunks); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err, _ := h.upload.Do(id.String(), func() (any, error) {
+		err := h.cache.Assemble(id, chunks)
+		if errors.Is(err, ErrExists) {
+			return nil, nil
+		}
+		return nil, err
+	})
+
+	if err != nil {
+		h.l.Warn("file assembly failed", zap.String("id", id.String()), zap.Error(err))
+
+		// Chunk could be evicted after the upload, client sends the whole file then.
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrNotFound) {
+			code = http.StatusConflict
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	h.l.Debug("file assembled", zap.String("id", id.String()), zap.Int("chunks", len(chunks)))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) chunk(w http.ResponseWriter, r *http.Request) {
+	var id build.ID
+	if err := id.UnmarshalText([]byte(r.URL.Query().Get("id"))); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.getChunk(w, r, id)
+	case http.MethodPut:
+		h.putChunk(w, r, id)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) getChunk(w http.ResponseWriter, r *http.Request, id build.ID) {
+	path, unlock, err := h.cache.GetChunk(id)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrNotFound) {
+			code = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	defer unlock()
+
+	if err := h.send(w, r, path); err != nil {
+		h.l.Warn("failed to send chunk", zap.String("id", id.String()), zap.Error(err))
+	}
+}
+
+func (h *Handler) putChunk(w http.ResponseWriter, r *http.Request, id build.ID) {
+	_, err, _ := h.upload.Do("chunk "+id.String(), func() (any, error) {
+		return nil, h.cache.WriteChunk(id, r.Body)
+	})
+
+	if err != nil {
+		h.l.Warn("chunk upload failed", zap.String("id", id.String()), zap.Error(err))
+
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrChunkCorrupted) {
+			code = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) missingChunks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var chunks []build.ID
+	if err := json.NewDecoder(r.Body).Decode(&chunks); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(h.cache.MissingChunks(chunks))
 }
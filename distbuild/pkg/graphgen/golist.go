@@ -0,0 +1,138 @@
+package graphgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+type goModule struct {
+	Path      string
+	Dir       string
+	Main      bool
+	GoVersion string
+}
+
+type goPackageError struct {
+	Err string
+}
+
+// goPackage is a subset of the go list -json output.
+type goPackage struct {
+	Dir        string
+	ImportPath string
+	Name       string
+	Standard   bool
+	DepOnly    bool
+	Module     *goModule
+
+	GoFiles      []string
+	CgoFiles     []string
+	CFiles       []string
+	CXXFiles     []string
+	SFiles       []string
+	SysoFiles    []string
+	EmbedFiles   []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+
+	Imports      []string
+	Deps         []string
+	TestImports  []string
+	XTestImports []string
+
+	Error *goPackageError
+}
+
+func (p *goPackage) isMain() bool {
+	return p.Module != nil && p.Module.Main
+}
+
+// hasTests reports whether the selected package of the main module has test files.
+func (p *goPackage) hasTests() bool {
+	return p.isMain() && !p.DepOnly && len(p.TestGoFiles)+len(p.XTestGoFiles) != 0
+}
+
+// unsupportedFiles returns the first kind of files, that graphgen cannot compile.
+func (p *goPackage) unsupportedFiles() string {
+	switch {
+	case len(p.CgoFiles) != 0:
+		return "cgo"
+	case len(p.CFiles) != 0 || len(p.CXXFiles) != 0:
+		return "C"
+	case len(p.SFiles) != 0:
+		return "assembly"
+	case len(p.SysoFiles) != 0:
+		return "syso"
+	case len(p.EmbedFiles) != 0:
+		return "embedded"
+	default:
+		return ""
+	}
+}
+
+type goEnv struct {
+	GOVERSION string
+	GOOS      string
+	GOARCH    string
+}
+
+func runGo(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "CGO_ENABLED=0")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go %s: %w: %s", args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
+}
+
+func readGoEnv(ctx context.Context, dir string) (*goEnv, error) {
+	out, err := runGo(ctx, dir, "env", "-json", "GOVERSION", "GOOS", "GOARCH")
+	if err != nil {
+		return nil, err
+	}
+
+	var env goEnv
+	if err := json.Unmarshal(out, &env); err != nil {
+		return nil, fmt.Errorf("decode go env: %w", err)
+	}
+	return &env, nil
+}
+
+// listPackages returns packages matching patterns together with their dependencies.
+//
+// Dependencies precede the packages importing them.
+func listPackages(ctx context.Context, dir string, patterns []string) ([]*goPackage, error) {
+	out, err := runGo(ctx, dir, append([]string{"list", "-json", "-deps"}, patterns...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []*goPackage
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var pkg goPackage
+		if err := dec.Decode(&pkg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("decode go list output: %w", err)
+		}
+
+		if pkg.Error != nil {
+			return nil, fmt.Errorf("package %s: %s", pkg.ImportPath, pkg.Error.Err)
+		}
+		pkgs = append(pkgs, &pkg)
+	}
+	return pkgs, nil
+}
@@ -0,0 +1,267 @@
+// Package graphgen generates build graph for a Go module.
+package graphgen
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// Config configures graph generation.
+type Config struct {
+	// Dir is the root directory of the main module.
+	//
+	// Paths of the source files in the generated graph are relative to Dir, so the client
+	// must use Dir as its source directory.
+	Dir string
+
+	// Patterns select packages of the main module. Default is ./...
+	Patterns []string
+
+	// Vet adds vet job for each selected package.
+	Vet bool
+
+	// Test adds test job for each selected package having tests.
+	Test bool
+
+	// Environ adds variables to the environment of the commands.
+	//
+	// Job ids depend on Environ, so it must not contain paths of the client machine. The go command
+	// and its caches are located by the environment of the worker, see worker.Config.Environ.
+	Environ []string
+}
+
+// Cgo is disabled for all commands, the generated jobs compile only Go files.
+const cgoDisabled = "CGO_ENABLED=0"
+
+type generator struct {
+	config  Config
+	env     *goEnv
+	module  *goModule
+	environ []string
+	graph   build.Graph
+
+	// files maps source file path to its id.
+	files map[string]build.ID
+	// packages maps import path of the main module package to its description.
+	packages map[string]*goPackage
+	// compiled maps import path of the main module package to its compile job.
+	compiled map[string]build.ID
+
+	// external lists standard and third party packages, compiled by the single job. The value
+	// reports whether the package belongs to the standard library.
+	external map[string]bool
+	// externalJob is the job compiling external packages.
+	externalJob build.ID
+}
+
+// Generate lists packages of the module and returns graph compiling them.
+//
+// Each package of the main module is compiled by a separate job. Main packages are linked,
+// vet and test jobs are added on request. Test jobs link the test binary from the compiled
+// packages. Standard library and third party packages are compiled by the single job with
+// go list -export. Packages using cgo, assembly or embedded files are not supported.
+//
+// Job ids are computed from the job commands, contents of the input files and ids of the
+// dependencies, so unchanged jobs keep their ids between invocations.
+func Generate(ctx context.Context, config Config) (*build.Graph, error) {
+	dir, err := filepath.Abs(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	config.Dir = dir
+
+	if len(config.Patterns) == 0 {
+		config.Patterns = []string{"./..."}
+	}
+
+	g := &generator{
+		config:   config,
+		environ:  environ(config.Environ),
+		files:    map[string]build.ID{},
+		packages: map[string]*goPackage{},
+		compiled: map[string]build.ID{},
+		external: map[string]bool{},
+		graph:    build.Graph{SourceFiles: map[build.ID]string{}},
+	}
+
+	if g.env, err = readGoEnv(ctx, dir); err != nil {
+		return nil, err
+	}
+
+	pkgs, err := listPackages(ctx, dir, config.Patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pkg := range pkgs {
+		if !pkg.isMain() {
+			g.external[pkg.ImportPath] = pkg.Standard
+			continue
+		}
+
+		if g.module == nil {
+			g.module = pkg.Module
+		}
+		g.packages[pkg.ImportPath] = pkg
+	}
+
+	if g.module == nil {
+		return nil, fmt.Errorf("no packages of the main module match %v", config.Patterns)
+	}
+
+	if g.module.Dir != dir {
+		return nil, fmt.Errorf("%s is not the root of the module %s", dir, g.module.Path)
+	}
+
+	// Linker always needs the runtime.
+	g.external["runtime"] = true
+
+	if config.Test {
+		if err := g.listTestImports(ctx, pkgs); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := g.addExternalJob(); err != nil {
+		return nil, err
+	}
+
+	for _, pkg := range pkgs {
+		if !pkg.isMain() {
+			continue
+		}
+
+		if err := g.addPackageJobs(pkg); err != nil {
+			return nil, fmt.Errorf("package %s: %w", pkg.ImportPath, err)
+		}
+	}
+
+	// Tests are added after all packages are compiled, since a test may import packages of the module
+	// depending on the tested package.
+	for _, pkg := range pkgs {
+		if !config.Test || !pkg.hasTests() {
+			continue
+		}
+
+		if err := g.addTestJob(pkg); err != nil {
+			return nil, fmt.Errorf("package %s: %w", pkg.ImportPath, err)
+		}
+	}
+
+	return &g.graph, nil
+}
+
+func environ(environ []string) []string {
+	return append(append([]string{}, environ...), cgoDisabled)
+}
+
+// depDir references output directory of the dependency inside the command template.
+//
+// Raw string literal keeps the template valid inside JSON files.
+func depDir(id build.ID) string {
+	return fmt.Sprintf("{{index .Deps `%s`}}", id)
+}
+
+// addFile adds file of the main module to the graph.
+func (g *generator) addFile(path string) (build.ID, error) {
+	if id, ok := g.files[path]; ok {
+		return id, nil
+	}
+
+	content, err := os.ReadFile(filepath.Join(g.config.Dir, path))
+	if err != nil {
+		return build.ID{}, err
+	}
+
+	// Path is part of the id, so that files with the same content do not collide.
+	h := sha1.New()
+	_, _ = h.Write([]byte(path))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write(content)
+
+	var id build.ID
+	copy(id[:], h.Sum(nil))
+
+	g.files[path] = id
+	g.graph.SourceFiles[id] = path
+	return id, nil
+}
+
+// addJob computes job id and adds the job to the graph.
+func (g *generator) addJob(job build.Job) (build.ID, error) {
+	sort.Strings(job.Inputs)
+	sort.Slice(job.Deps, func(i, j int) bool {
+		return job.Deps[i].String() < job.Deps[j].String()
+	})
+
+	inputs := make([]build.ID, 0, len(job.Inputs))
+	for _, path := range job.Inputs {
+		id, err := g.addFile(path)
+		if err != nil {
+			return build.ID{}, err
+		}
+		inputs = append(inputs, id)
+	}
+
+	h := sha1.New()
+	err := json.NewEncoder(h).Encode(struct {
+		Env    *goEnv
+		Name   string
+		Inputs []build.ID
+		Deps   []build.ID
+		Cmds   []build.Cmd
+	}{g.env, job.Name, inputs, job.Deps, job.Cmds})
+	if err != nil {
+		return build.ID{}, err
+	}
+	copy(job.ID[:], h.Sum(nil))
+
+	g.graph.Jobs = append(g.graph.Jobs, job)
+	return job.ID, nil
+}
+
+// moduleFiles returns paths of go.mod and go.sum files.
+func (g *generator) moduleFiles() ([]string, error) {
+	files := []string{"go.mod"}
+	if _, err := os.Stat(filepath.Join(g.config.Dir, "go.sum")); err == nil {
+		files = append(files, "go.sum")
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return files, nil
+}
+
+// relPath returns path relative to the module root.
+func (g *generator) relPath(path string) (string, error) {
+	rel, err := filepath.Rel(g.config.Dir, path)
+	if err != nil {
+		return "", err
+	}
+
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("%s is outside of %s", path, g.config.Dir)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// packageFiles returns paths of the package files relative to the module root.
+func (g *generator) packageFiles(pkg *goPackage, names []string) ([]string, error) {
+	var files []string
+	for _, name := range names {
+		path, err := g.relPath(filepath.Join(pkg.Dir, name))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
@@ -0,0 +1,122 @@
+package graphgen_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/graphgen"
+)
+
+func writeModule(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for path, content := range files {
+		path = filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0777))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0666))
+	}
+	return dir
+}
+
+var testModule = map[string]string{
+	"go.mod":          "module example.com/m\n\ngo 1.21\n",
+	"main.go":         "package main\n\nimport \"example.com/m/lib\"\n\nfunc main() { lib.F() }\n",
+	"lib/lib.go":      "package lib\n\nimport \"fmt\"\n\nfunc F() { fmt.Println(\"F\") }\n",
+	"lib/lib_test.go": "package lib\n\nimport \"testing\"\n\nfunc TestF(t *testing.T) { F() }\n",
+}
+
+func jobsByName(graph *build.Graph) map[string]build.Job {
+	jobs := map[string]build.Job{}
+	for _, job := range graph.Jobs {
+		jobs[job.Name] = job
+	}
+	return jobs
+}
+
+func TestGenerate(t *testing.T) {
+	dir := writeModule(t, testModule)
+
+	graph, err := graphgen.Generate(context.Background(), graphgen.Config{Dir: dir, Vet: true, Test: true})
+	require.NoError(t, err)
+
+	jobs := jobsByName(graph)
+	require.Len(t, jobs, 7)
+
+	external := jobs["build external packages"]
+	buildLib := jobs["build example.com/m/lib"]
+	buildMain := jobs["build example.com/m"]
+
+	require.Equal(t, []string{"go.mod"}, external.Inputs)
+	require.Equal(t, []string{"lib/lib.go"}, buildLib.Inputs)
+	require.Equal(t, []build.ID{external.ID}, buildLib.Deps)
+	require.Equal(t, []build.ID{buildLib.ID}, buildMain.Deps)
+	require.Contains(t, jobs["link example.com/m"].Deps, buildMain.ID)
+	require.Contains(t, jobs, "vet example.com/m/lib")
+	require.NotContains(t, jobs, "test example.com/m")
+
+	// Test binary is linked from the compiled packages, lib itself is compiled again with its test.
+	test := jobs["test example.com/m/lib"]
+	require.Equal(t, []string{"lib/lib.go", "lib/lib_test.go"}, test.Inputs)
+	require.Equal(t, []build.ID{external.ID}, test.Deps)
+	require.Equal(t, []string{"{{.OutputDir}}/lib.test"}, test.Cmds[len(test.Cmds)-1].Exec)
+
+	require.Len(t, graph.SourceFiles, 4)
+	require.Equal(t, graph.Jobs, build.TopSort(graph.Jobs))
+}
+
+func TestGenerateIDs(t *testing.T) {
+	dir := writeModule(t, testModule)
+
+	generate := func() map[string]build.Job {
+		graph, err := graphgen.Generate(context.Background(), graphgen.Config{Dir: dir})
+		require.NoError(t, err)
+		return jobsByName(graph)
+	}
+
+	first := generate()
+	require.Equal(t, first, generate())
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0666))
+	second := generate()
+
+	require.Equal(t, first["build example.com/m/lib"].ID, second["build example.com/m/lib"].ID)
+	require.NotEqual(t, first["build example.com/m"].ID, second["build example.com/m"].ID)
+	require.NotEqual(t, first["link example.com/m"].ID, second["link example.com/m"].ID)
+}
+
+func TestGenerateIDsIgnoreEnvironment(t *testing.T) {
+	dir := writeModule(t, testModule)
+
+	generate := func() map[string]build.Job {
+		graph, err := graphgen.Generate(context.Background(), graphgen.Config{Dir: dir, Test: true})
+		require.NoError(t, err)
+		return jobsByName(graph)
+	}
+
+	first := generate()
+
+	t.Setenv("GOPATH", t.TempDir())
+	t.Setenv("PATH", t.TempDir()+string(os.PathListSeparator)+os.Getenv("PATH"))
+	require.Equal(t, first, generate())
+
+	for _, job := range first {
+		for _, cmd := range job.Cmds {
+			require.Subset(t, []string{"CGO_ENABLED=0"}, cmd.Environ, job.Name)
+		}
+	}
+}
+
+func TestGenerateRejectsAssembly(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":     "module example.com/m\n\ngo 1.21\n",
+		"lib/lib.go": "package lib\n\nfunc F()\n",
+		"lib/lib.s":  "",
+	})
+
+	_, err := graphgen.Generate(context.Background(), graphgen.Config{Dir: dir})
+	require.ErrorContains(t, err, "assembly files are not supported")
+}
@@ -0,0 +1,431 @@
+package graphgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// exportFormat is go list -f template, escaped from the rendering of the command.
+const exportFormat = `{{"{{.ImportPath}} {{.Export}}"}}`
+
+// exportScript compiles packages passed as arguments and copies their export data into the
+// directory passed as the first argument.
+const exportScript = `set -e
+out="$1"
+shift
+go list -export -f '` + exportFormat + `' "$@" > "$out/exports"
+while read -r pkg export; do
+	[ -n "$export" ] || continue
+	mkdir -p "$(dirname "$out/$pkg.a")"
+	cp "$export" "$out/$pkg.a"
+done < "$out/exports"
+`
+
+// addExternalJob adds the job compiling standard library and third party packages.
+func (g *generator) addExternalJob() error {
+	inputs, err := g.moduleFiles()
+	if err != nil {
+		return err
+	}
+
+	var pkgs []string
+	for pkg := range g.external {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+
+	g.externalJob, err = g.addJob(build.Job{
+		Name:   "build external packages",
+		Inputs: inputs,
+		Cmds: []build.Cmd{
+			{
+				Exec:             append([]string{"sh", "-c", exportScript, "sh", "{{.OutputDir}}"}, pkgs...),
+				Environ:          g.environ,
+				WorkingDirectory: "{{.SourceDir}}",
+			},
+		},
+	})
+	return err
+}
+
+// packageFile returns location of the compiled package together with the job producing it.
+func (g *generator) packageFile(importPath string) (string, build.ID, error) {
+	if id, ok := g.compiled[importPath]; ok {
+		return depDir(id) + "/pkg.a", id, nil
+	}
+
+	if _, ok := g.external[importPath]; ok {
+		return depDir(g.externalJob) + "/" + importPath + ".a", g.externalJob, nil
+	}
+
+	return "", build.ID{}, fmt.Errorf("package %s is not compiled", importPath)
+}
+
+// packageFileMap locates compiled packages. It returns import path to file mapping and the jobs
+// producing the files.
+func (g *generator) packageFileMap(imports []string) (map[string]string, []build.ID, error) {
+	files := map[string]string{}
+	deps := map[build.ID]struct{}{}
+
+	for _, importPath := range imports {
+		if importPath == "unsafe" {
+			continue
+		}
+
+		file, dep, err := g.packageFile(importPath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		files[importPath] = file
+		deps[dep] = struct{}{}
+	}
+
+	var depList []build.ID
+	for id := range deps {
+		depList = append(depList, id)
+	}
+	return files, depList, nil
+}
+
+// importcfg returns configuration of the compiler and the linker, together with the jobs it depends on.
+func (g *generator) importcfg(imports []string) (string, []build.ID, error) {
+	files, deps, err := g.packageFileMap(imports)
+	if err != nil {
+		return "", nil, err
+	}
+	return formatImportcfg(files), deps, nil
+}
+
+// formatImportcfg formats import path to file mapping, lines are sorted to keep the job id stable.
+func formatImportcfg(files map[string]string) string {
+	var lines []string
+	for importPath, file := range files {
+		lines = append(lines, fmt.Sprintf("packagefile %s=%s\n", importPath, file))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "")
+}
+
+func sourcePaths(files []string) []string {
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, "{{.SourceDir}}/"+file)
+	}
+	return paths
+}
+
+// langVersion converts go directive of go.mod into the compiler -lang flag.
+func langVersion(goVersion string) string {
+	parts := strings.SplitN(goVersion, ".", 3)
+	if len(parts) > 2 {
+		parts = parts[:2]
+	}
+	return "go" + strings.Join(parts, ".")
+}
+
+func (g *generator) addPackageJobs(pkg *goPackage) error {
+	if kind := pkg.unsupportedFiles(); kind != "" {
+		return fmt.Errorf("%s files are not supported", kind)
+	}
+
+	compileID, err := g.addCompileJob(pkg)
+	if err != nil {
+		return err
+	}
+	g.compiled[pkg.ImportPath] = compileID
+
+	if pkg.DepOnly {
+		return nil
+	}
+
+	if pkg.Name == "main" {
+		if err := g.addLinkJob(pkg, compileID); err != nil {
+			return err
+		}
+	}
+
+	if g.config.Vet {
+		if err := g.addVetJob(pkg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *generator) addCompileJob(pkg *goPackage) (build.ID, error) {
+	files, err := g.packageFiles(pkg, pkg.GoFiles)
+	if err != nil {
+		return build.ID{}, err
+	}
+
+	importcfg, deps, err := g.importcfg(pkg.Imports)
+	if err != nil {
+		return build.ID{}, err
+	}
+
+	packagePath := pkg.ImportPath
+	if pkg.Name == "main" {
+		packagePath = "main"
+	}
+
+	return g.addJob(build.Job{
+		Name:   "build " + pkg.ImportPath,
+		Inputs: files,
+		Deps:   deps,
+		Cmds: []build.Cmd{
+			{CatTemplate: importcfg, CatOutput: "{{.OutputDir}}/importcfg"},
+			g.compileCmd(packagePath, "{{.OutputDir}}/pkg.a", sourcePaths(files)),
+		},
+	})
+}
+
+// compileCmd compiles Go files into the package archive, using {{.OutputDir}}/importcfg.
+func (g *generator) compileCmd(packagePath, output string, files []string) build.Cmd {
+	compile := []string{
+		"go", "tool", "compile",
+		"-o", output,
+		"-p", packagePath,
+		"-trimpath", "{{.SourceDir}}=>",
+		"-importcfg", "{{.OutputDir}}/importcfg",
+		"-pack", "-complete",
+	}
+	if g.module.GoVersion != "" {
+		compile = append(compile, "-lang="+langVersion(g.module.GoVersion))
+	}
+	return build.Cmd{Exec: append(compile, files...), Environ: g.environ}
+}
+
+func (g *generator) addLinkJob(pkg *goPackage, compileID build.ID) error {
+	// Linker needs every package of the program. Unused packages are ignored.
+	imports := append([]string{}, pkg.Deps...)
+	for importPath := range g.external {
+		imports = append(imports, importPath)
+	}
+
+	importcfg, deps, err := g.importcfg(imports)
+	if err != nil {
+		return err
+	}
+
+	_, err = g.addJob(build.Job{
+		Name: "link " + pkg.ImportPath,
+		Deps: append(deps, compileID),
+		Cmds: []build.Cmd{
+			{CatTemplate: importcfg, CatOutput: "{{.OutputDir}}/importcfg"},
+			{
+				Exec: []string{
+					"go", "tool", "link",
+					"-o", "{{.OutputDir}}/" + path.Base(pkg.ImportPath),
+					"-importcfg", "{{.OutputDir}}/importcfg",
+					"-buildmode=exe",
+					depDir(compileID) + "/pkg.a",
+				},
+				Environ: g.environ,
+			},
+		},
+	})
+	return err
+}
+
+// vetConfig is the configuration of the vet tool, see golang.org/x/tools/go/analysis/unitchecker.
+type vetConfig struct {
+	ID          string
+	Compiler    string
+	Dir         string
+	ImportPath  string
+	GoVersion   string
+	GoFiles     []string
+	ImportMap   map[string]string
+	PackageFile map[string]string
+	Standard    map[string]bool
+	VetxOutput  string
+}
+
+func (g *generator) addVetJob(pkg *goPackage) error {
+	files, err := g.packageFiles(pkg, pkg.GoFiles)
+	if err != nil {
+		return err
+	}
+
+	packageFiles, deps, err := g.packageFileMap(pkg.Imports)
+	if err != nil {
+		return err
+	}
+
+	dir, err := g.relPath(pkg.Dir)
+	if err != nil {
+		return err
+	}
+
+	cfg := vetConfig{
+		ID:          pkg.ImportPath,
+		Compiler:    "gc",
+		Dir:         "{{.SourceDir}}/" + dir,
+		ImportPath:  pkg.ImportPath,
+		GoFiles:     sourcePaths(files),
+		ImportMap:   map[string]string{},
+		PackageFile: packageFiles,
+		Standard:    map[string]bool{},
+		VetxOutput:  "{{.OutputDir}}/vet.out",
+	}
+	if g.module.GoVersion != "" {
+		cfg.GoVersion = langVersion(g.module.GoVersion)
+	}
+
+	for _, importPath := range pkg.Imports {
+		cfg.ImportMap[importPath] = importPath
+		if g.external[importPath] {
+			cfg.Standard[importPath] = true
+		}
+	}
+
+	var cfgJSON strings.Builder
+	enc := json.NewEncoder(&cfgJSON)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(cfg); err != nil {
+		return err
+	}
+
+	_, err = g.addJob(build.Job{
+		Name:   "vet " + pkg.ImportPath,
+		Inputs: files,
+		Deps:   deps,
+		Cmds: []build.Cmd{
+			{CatTemplate: cfgJSON.String(), CatOutput: "{{.OutputDir}}/vet.cfg"},
+			{Exec: []string{"go", "tool", "vet", "{{.OutputDir}}/vet.cfg"}, Environ: g.environ},
+		},
+	})
+	return err
+}
+
+// inModule reports whether the import path belongs to the main module.
+func (g *generator) inModule(importPath string) bool {
+	return importPath == g.module.Path || strings.HasPrefix(importPath, g.module.Path+"/")
+}
+
+// testDeps returns packages of the main module linked into the test binary, except the tested package.
+func (g *generator) testDeps(pkg *goPackage) ([]string, error) {
+	deps := map[string]struct{}{}
+	for _, imports := range [][]string{pkg.Imports, pkg.TestImports, pkg.XTestImports} {
+		for _, importPath := range imports {
+			if !g.inModule(importPath) || importPath == pkg.ImportPath {
+				continue
+			}
+
+			dep, ok := g.packages[importPath]
+			if !ok {
+				return nil, fmt.Errorf("test dependency %s is not selected", importPath)
+			}
+
+			deps[importPath] = struct{}{}
+			for _, depPath := range dep.Deps {
+				if g.inModule(depPath) {
+					deps[depPath] = struct{}{}
+				}
+			}
+		}
+	}
+	delete(deps, pkg.ImportPath)
+
+	var depList []string
+	for importPath := range deps {
+		// go test recompiles such packages against the package with its internal tests.
+		if len(pkg.TestGoFiles) != 0 && slices.Contains(g.packages[importPath].Deps, pkg.ImportPath) {
+			return nil, fmt.Errorf("test dependency %s imports the tested package", importPath)
+		}
+		depList = append(depList, importPath)
+	}
+	sort.Strings(depList)
+	return depList, nil
+}
+
+// addTestJob adds job building and running the test binary.
+//
+// Package is compiled again together with its internal test files, the external test package and
+// the generated main package are compiled against it. All other packages come from their compile jobs.
+func (g *generator) addTestJob(pkg *goPackage) error {
+	files, err := g.packageFiles(pkg, pkg.GoFiles)
+	if err != nil {
+		return err
+	}
+	testFiles, err := g.packageFiles(pkg, pkg.TestGoFiles)
+	if err != nil {
+		return err
+	}
+	xtestFiles, err := g.packageFiles(pkg, pkg.XTestGoFiles)
+	if err != nil {
+		return err
+	}
+
+	main, err := g.testMain(pkg)
+	if err != nil {
+		return err
+	}
+
+	imports, err := g.testDeps(pkg)
+	if err != nil {
+		return err
+	}
+	for importPath := range g.external {
+		imports = append(imports, importPath)
+	}
+	if len(testFiles) == 0 {
+		imports = append(imports, pkg.ImportPath)
+	}
+
+	packageFiles, deps, err := g.packageFileMap(imports)
+	if err != nil {
+		return err
+	}
+
+	dir, err := g.relPath(pkg.Dir)
+	if err != nil {
+		return err
+	}
+
+	var cmds []build.Cmd
+	if len(testFiles) != 0 {
+		packageFiles[pkg.ImportPath] = "{{.OutputDir}}/test.a"
+		cmds = append(cmds, g.compileCmd(pkg.ImportPath, "{{.OutputDir}}/test.a", sourcePaths(append(files, testFiles...))))
+	}
+	if len(xtestFiles) != 0 {
+		packageFiles[pkg.ImportPath+"_test"] = "{{.OutputDir}}/xtest.a"
+		cmds = append(cmds, g.compileCmd(pkg.ImportPath+"_test", "{{.OutputDir}}/xtest.a", sourcePaths(xtestFiles)))
+	}
+
+	binary := "{{.OutputDir}}/" + path.Base(pkg.ImportPath) + ".test"
+	cmds = append(cmds,
+		build.Cmd{CatTemplate: escapeTemplate(main), CatOutput: "{{.OutputDir}}/_testmain.go"},
+		g.compileCmd("main", "{{.OutputDir}}/testmain.a", []string{"{{.OutputDir}}/_testmain.go"}),
+		build.Cmd{
+			Exec: []string{
+				"go", "tool", "link",
+				"-o", binary,
+				"-importcfg", "{{.OutputDir}}/importcfg",
+				"-buildmode=exe",
+				"{{.OutputDir}}/testmain.a",
+			},
+			Environ: g.environ,
+		},
+		// Tests run in the directory of the package, as with go test.
+		build.Cmd{Exec: []string{binary}, Environ: g.environ, WorkingDirectory: "{{.SourceDir}}/" + dir},
+	)
+
+	_, err = g.addJob(build.Job{
+		Name:   "test " + pkg.ImportPath,
+		Inputs: append(append(files, testFiles...), xtestFiles...),
+		Deps:   deps,
+		Cmds: append([]build.Cmd{
+			{CatTemplate: formatImportcfg(packageFiles), CatOutput: "{{.OutputDir}}/importcfg"},
+		}, cmds...),
+	})
+	return err
+}
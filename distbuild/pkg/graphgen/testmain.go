@@ -0,0 +1,238 @@
+package graphgen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// testMainImports are imported by the generated main package of the test binary.
+var testMainImports = []string{"os", "reflect", "testing", "testing/internal/testdeps"}
+
+// listTestImports adds standard and third party packages imported by the tests to the external packages.
+func (g *generator) listTestImports(ctx context.Context, pkgs []*goPackage) error {
+	seen := map[string]struct{}{}
+	var imports []string
+	add := func(importPath string) {
+		if _, ok := seen[importPath]; ok {
+			return
+		}
+		seen[importPath] = struct{}{}
+
+		if _, ok := g.external[importPath]; !ok && !g.inModule(importPath) {
+			imports = append(imports, importPath)
+		}
+	}
+
+	for _, pkg := range pkgs {
+		if !pkg.hasTests() {
+			continue
+		}
+
+		for _, importPath := range append(append(append([]string{}, testMainImports...), pkg.TestImports...), pkg.XTestImports...) {
+			add(importPath)
+		}
+	}
+
+	if len(imports) == 0 {
+		return nil
+	}
+
+	listed, err := listPackages(ctx, g.config.Dir, imports)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range listed {
+		if !pkg.isMain() {
+			g.external[pkg.ImportPath] = pkg.Standard
+		}
+	}
+	return nil
+}
+
+// testFunc is a test, benchmark, fuzz target or example called by the test main.
+type testFunc struct {
+	Package   string
+	Name      string
+	Output    string
+	Unordered bool
+}
+
+type testMainData struct {
+	ImportPath string
+
+	NeedTest    bool
+	ImportXtest bool
+	NeedXtest   bool
+
+	Tests       []testFunc
+	Benchmarks  []testFunc
+	FuzzTargets []testFunc
+	Examples    []testFunc
+	TestMain    *testFunc
+}
+
+// testMainTemplate follows the main package generated by go test.
+var testMainTemplate = template.Must(template.New("main").Parse(`package main
+
+import (
+	"os"
+{{- if .TestMain}}
+	"reflect"
+{{- end}}
+	"testing"
+	"testing/internal/testdeps"
+
+	{{if .NeedTest}}_test{{else}}_{{end}} {{printf "%q" .ImportPath}}
+{{- if .ImportXtest}}
+	{{if .NeedXtest}}_xtest{{else}}_{{end}} {{printf "%s_test" .ImportPath | printf "%q"}}
+{{- end}}
+)
+
+var tests = []testing.InternalTest{
+{{- range .Tests}}
+	{"{{.Name}}", {{.Package}}.{{.Name}}},
+{{- end}}
+}
+
+var benchmarks = []testing.InternalBenchmark{
+{{- range .Benchmarks}}
+	{"{{.Name}}", {{.Package}}.{{.Name}}},
+{{- end}}
+}
+
+var fuzzTargets = []testing.InternalFuzzTarget{
+{{- range .FuzzTargets}}
+	{"{{.Name}}", {{.Package}}.{{.Name}}},
+{{- end}}
+}
+
+var examples = []testing.InternalExample{
+{{- range .Examples}}
+	{"{{.Name}}", {{.Package}}.{{.Name}}, {{printf "%q" .Output}}, {{.Unordered}}},
+{{- end}}
+}
+
+func init() {
+	testdeps.ImportPath = {{printf "%q" .ImportPath}}
+}
+
+func main() {
+	m := testing.MainStart(testdeps.TestDeps{}, tests, benchmarks, fuzzTargets, examples)
+{{- with .TestMain}}
+	{{.Package}}.{{.Name}}(m)
+	os.Exit(int(reflect.ValueOf(m).Elem().FieldByName("exitCode").Int()))
+{{- else}}
+	os.Exit(m.Run())
+{{- end}}
+}
+`))
+
+// testMain returns source of the main package of the test binary.
+func (g *generator) testMain(pkg *goPackage) (string, error) {
+	data := testMainData{
+		ImportPath:  pkg.ImportPath,
+		ImportXtest: len(pkg.XTestGoFiles) != 0,
+	}
+
+	fset := token.NewFileSet()
+	for _, files := range []struct {
+		pkg   string
+		names []string
+		need  *bool
+	}{
+		{"_test", pkg.TestGoFiles, &data.NeedTest},
+		{"_xtest", pkg.XTestGoFiles, &data.NeedXtest},
+	} {
+		for _, name := range files.names {
+			f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, parser.ParseComments)
+			if err != nil {
+				return "", err
+			}
+
+			found, err := data.addFuncs(f, files.pkg)
+			if err != nil {
+				return "", err
+			}
+			*files.need = *files.need || found
+		}
+	}
+
+	var src bytes.Buffer
+	if err := testMainTemplate.Execute(&src, data); err != nil {
+		return "", err
+	}
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return "", err
+	}
+	return string(formatted), nil
+}
+
+// addFuncs collects tests of the file, as go test does. It reports whether the file has any.
+func (d *testMainData) addFuncs(f *ast.File, pkg string) (bool, error) {
+	found := false
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil {
+			continue
+		}
+
+		name := fn.Name.Name
+		switch {
+		case name == "TestMain":
+			if d.TestMain != nil {
+				return false, fmt.Errorf("multiple definitions of TestMain")
+			}
+			d.TestMain = &testFunc{Package: pkg, Name: name}
+		case isTest(name, "Test"):
+			d.Tests = append(d.Tests, testFunc{Package: pkg, Name: name})
+		case isTest(name, "Benchmark"):
+			d.Benchmarks = append(d.Benchmarks, testFunc{Package: pkg, Name: name})
+		case isTest(name, "Fuzz"):
+			d.FuzzTargets = append(d.FuzzTargets, testFunc{Package: pkg, Name: name})
+		default:
+			continue
+		}
+		found = true
+	}
+
+	// Examples without output comment are only compiled.
+	for _, e := range doc.Examples(f) {
+		if e.Output == "" && !e.EmptyOutput {
+			continue
+		}
+		d.Examples = append(d.Examples, testFunc{Package: pkg, Name: "Example" + e.Name, Output: e.Output, Unordered: e.Unordered})
+		found = true
+	}
+	return found, nil
+}
+
+// isTest reports whether name looks like a test function: prefix is not followed by a lower case letter.
+func isTest(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	if len(name) == len(prefix) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(name[len(prefix):])
+	return !unicode.IsLower(r)
+}
+
+// escapeTemplate quotes text, so that the command template renders it unchanged.
+func escapeTemplate(text string) string {
+	return strings.ReplaceAll(text, "{{", `{{"{{"}}`)
+}
@@ -0,0 +1,67 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/metrics"
+)
+
+func TestRegistry(t *testing.T) {
+	r := metrics.NewRegistry()
+
+	hits := r.NewCounter("test_hits_total", "Number of hits.")
+	hits.Add(2)
+	hits.Inc()
+
+	slots := r.NewGauge("test_free_slots", "Free slots.\nSecond line.")
+	slots.Set(4)
+	slots.Add(-1.5)
+
+	r.NewGaugeFunc("test_pending", "Pending jobs.", func() float64 { return 7 })
+
+	latency := r.NewHistogram("test_latency_seconds", "Latency.", []float64{0.1, 1})
+	latency.Observe(0.05)
+	latency.Observe(0.1)
+	latency.Observe(0.5)
+	latency.Observe(2)
+
+	require.Panics(t, func() { r.NewCounter("test_hits_total", "") })
+
+	mux := http.NewServeMux()
+	r.Register(mux)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	rsp, err := http.Get(server.URL + "/metrics")
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.Equal(t, metrics.ContentType, rsp.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(rsp.Body)
+	require.NoError(t, err)
+	require.Equal(t, `# HELP test_free_slots Free slots.\nSecond line.
+# TYPE test_free_slots gauge
+test_free_slots 2.5
+# HELP test_hits_total Number of hits.
+# TYPE test_hits_total counter
+test_hits_total 3
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{le="0.1"} 2
+test_latency_seconds_bucket{le="1"} 3
+test_latency_seconds_bucket{le="+Inf"} 4
+test_latency_seconds_sum 2.65
+test_latency_seconds_count 4
+# HELP test_pending Pending jobs.
+# TYPE test_pending gauge
+test_pending 7
+`, string(body))
+}
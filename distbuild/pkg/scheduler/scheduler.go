@@ -4,6 +4,9 @@ package scheduler
 
 import (
 	"context"
+	"math/rand"
+	"sort"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -21,31 +24,1108 @@ type PendingJob struct {
 type Config struct {
 	CacheTimeout time.Duration
 	DepsTimeout  time.Duration
+
+	// Weights sets shares of the users. Users missing from Weights have weight 1.
+	Weights map[string]float64
+
+	// SpeculationFactor enables speculative execution. Job running longer than SpeculationFactor times
+	// the historical duration of the jobs with the same name gets a copy on another free worker.
+	// The first copy to finish wins, the other one is cancelled. Zero disables speculative execution.
+	SpeculationFactor float64
+	// SpeculationMinDelay is the least time the job runs before it is copied.
+	SpeculationMinDelay time.Duration
+}
+
+// durationWeight is the weight of the last run in the historical duration of the job.
+const durationWeight = 0.25
+
+// Policy controls the order, in which workers pick jobs, and the workers allowed to pick the job.
+type Policy struct {
+	// User shares the cluster with other users in proportion to its weight.
+	User string
+
+	// Priority orders jobs of all users. Jobs with higher priority are picked first.
+	Priority int
+
+	// CriticalPath is the length of the longest chain of jobs starting from the job.
+	// Among jobs of the same user and priority, jobs with longer chains are picked first.
+	CriticalPath int
+
+	// AvoidWorker is never given the job. Used to repeat the job on a different worker.
+	AvoidWorker api.WorkerID
+}
+
+// Stats describes the current load of the scheduler.
+type Stats struct {
+	// Queued is the number of jobs waiting for a worker.
+	Queued int
+	// Running is the number of jobs picked by the workers and not yet completed.
+	Running int
+	// Workers is the number of registered workers.
+	Workers int
+}
+
+// share tracks the amount of work given to the user.
+type share struct {
+	// vtime is the number of jobs picked for the user divided by the user weight.
+	vtime float64
+	// queued counts jobs of the user waiting for a worker.
+	queued int
+}
+
+// pendingJob tracks scheduler-private state of the job.
+type pendingJob struct {
+	*PendingJob
+
+	// picked is set once some worker took the job.
+	picked bool
+	// depsQueued is set once the job was put into the second local queues.
+	depsQueued bool
+	// workerID is the worker, that picked the job.
+	workerID api.WorkerID
+	// copyWorkerID is the worker running the speculative copy of the job.
+	copyWorkerID api.WorkerID
+	// straggler is set once the current attempt runs longer than expected.
+	straggler bool
+	// refs counts builds waiting for the job.
+	refs int
+	// attempt is the number of the current attempt, starting from 1.
+	attempt int
+	// lost counts attempts lost by the workers. Lost attempts do not use up Job.Retries.
+	lost int
+
+	policy Policy
+	// seq orders jobs with the same policy by the time of scheduling.
+	seq uint64
+	// queued is the time of scheduling, reported in the job trace.
+	queued time.Time
+}
+
+type jobQueue struct {
+	jobs []*pendingJob
+}
+
+func (q *jobQueue) push(job *pendingJob) {
+	q.jobs = append(q.jobs, job)
+}
+
+// find returns index of the job accepted by fits, that goes before other such jobs according to less.
+// Jobs picked through other queues are skipped. Equal jobs are taken in the queue order.
+//
+// Returns -1, if there is no such job.
+func (q *jobQueue) find(fits func(job *pendingJob) bool, less func(a, b *pendingJob) bool) int {
+	for len(q.jobs) != 0 && q.jobs[0].picked {
+		q.jobs[0] = nil
+		q.jobs = q.jobs[1:]
+	}
+
+	best := -1
+	for i, job := range q.jobs {
+		if job.picked || !fits(job) {
+			continue
+		}
+
+		if best == -1 || less(job, q.jobs[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+// remove removes the job with the given index from the queue.
+func (q *jobQueue) remove(i int) *pendingJob {
+	job := q.jobs[i]
+
+	n := len(q.jobs)
+	copy(q.jobs[i:], q.jobs[i+1:])
+	q.jobs[n-1] = nil
+	q.jobs = q.jobs[:n-1]
+	return job
+}
+
+// drop removes all entries of the job from the queue.
+func (q *jobQueue) drop(job *pendingJob) {
+	kept := q.jobs[:0]
+	for _, queued := range q.jobs {
+		if queued != job {
+			kept = append(kept, queued)
+		}
+	}
+
+	clear(q.jobs[len(kept):])
+	q.jobs = kept
+}
+
+type workerQueues struct {
+	// cached contains jobs, which results are stored in the worker cache.
+	cached jobQueue
+	// deps contains jobs, which have at least one dependency stored in the worker cache.
+	deps jobQueue
+
+	// free is the amount of resources available on the worker. Nil means that resources are not limited.
+	free *build.Resources
+	// capacity is the amount of resources of the idle worker. Nil means that resources are not limited.
+	capacity *build.Resources
+	// labels is the set of the worker labels.
+	labels map[string]struct{}
+}
+
+// fits reports whether the job can run on the worker now. Must be called under mu.
+func (w *workerQueues) fits(job *pendingJob) bool {
+	return w.hasLabels(job) && (w.free == nil || job.Job.Resources.Fits(*w.free))
+}
+
+// satisfies reports whether the job can run on the worker, once the worker is idle. Must be called under mu.
+func (w *workerQueues) satisfies(job *pendingJob) bool {
+	return w.hasLabels(job) && (w.capacity == nil || job.Job.Resources.Fits(*w.capacity))
+}
+
+func (w *workerQueues) hasLabels(job *pendingJob) bool {
+	for _, label := range job.Job.Labels {
+		if _, ok := w.labels[label]; !ok {
+			return false
+		}
+	}
+	return true
 }
 
 type Scheduler struct {
+	l         *zap.Logger
+	config    Config
+	timeAfter func(d time.Duration) <-chan time.Time
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	wg       sync.WaitGroup
+
+	mu        sync.Mutex
+	changed   chan struct{}
+	global    jobQueue
+	workers   map[api.WorkerID]*workerQueues
+	artifacts map[build.ID]map[api.WorkerID]struct{}
+	pending   map[build.ID]*pendingJob
+	cancelled map[api.WorkerID][]build.ID
+	// removals are the artifacts, that the worker should delete from its cache.
+	removals map[api.WorkerID][]build.ID
+
+	// durations is the historical duration of the jobs by job name.
+	durations map[string]time.Duration
+	// stragglers are the running jobs waiting for a worker to run their speculative copies.
+	stragglers []*pendingJob
+	// losers are the copies cancelled after the other copy of the job finished. Value is set,
+	// once the worker was told to cancel the copy.
+	losers map[api.WorkerID]map[build.ID]bool
+
+	shares map[string]*share
+	// vclock is the vtime of the user, which job was picked last.
+	vclock float64
+	seq    uint64
 }
 
 func NewScheduler(l *zap.Logger, config Config, timeAfter func(d time.Duration) <-chan time.Time) *Scheduler {
-	panic("implement me")
+	return &Scheduler{
+		l:         l,
+		config:    config,
+		timeAfter: timeAfter,
+		stop:      make(chan struct{}),
+		changed:   make(chan struct{}),
+		workers:   make(map[api.WorkerID]*workerQueues),
+		artifacts: make(map[build.ID]map[api.WorkerID]struct{}),
+		pending:   make(map[build.ID]*pendingJob),
+		cancelled: make(map[api.WorkerID][]build.ID),
+		removals:  make(map[api.WorkerID][]build.ID),
+		durations: make(map[string]time.Duration),
+		losers:    make(map[api.WorkerID]map[build.ID]bool),
+		shares:    make(map[string]*share),
+	}
+}
+
+func (c *Scheduler) share(user string) *share {
+	s, ok := c.shares[user]
+	if !ok {
+		s = &share{}
+		c.shares[user] = s
+	}
+	return s
+}
+
+// enqueued accounts job waiting for a worker. Must be called under mu.
+//
+// User, that had no waiting jobs, does not get credit for the idle time, its vtime catches up with vclock.
+func (c *Scheduler) enqueued(job *pendingJob) {
+	s := c.share(job.policy.User)
+	if s.queued == 0 && s.vtime < c.vclock {
+		s.vtime = c.vclock
+	}
+	s.queued++
+}
+
+// dequeued accounts job leaving the queues. Must be called under mu.
+func (c *Scheduler) dequeued(job *pendingJob, picked bool) {
+	s := c.share(job.policy.User)
+	s.queued--
+
+	if picked {
+		weight := 1.0
+		if w, ok := c.config.Weights[job.policy.User]; ok && w > 0 {
+			weight = w
+		}
+
+		c.vclock = s.vtime
+		s.vtime += 1 / weight
+	}
+}
+
+// less reports whether job a should be picked before job b. Must be called under mu.
+//
+// Jobs are ordered by priority, then by the share of the user, then by the critical path.
+func (c *Scheduler) less(a, b *pendingJob) bool {
+	if a.policy.Priority != b.policy.Priority {
+		return a.policy.Priority > b.policy.Priority
+	}
+
+	if a.policy.User != b.policy.User {
+		if va, vb := c.share(a.policy.User).vtime, c.share(b.policy.User).vtime; va != vb {
+			return va < vb
+		}
+	}
+
+	return a.policy.CriticalPath > b.policy.CriticalPath
+}
+
+// notify wakes up all PickJob calls waiting for new jobs. Must be called under mu.
+func (c *Scheduler) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (c *Scheduler) worker(workerID api.WorkerID) *workerQueues {
+	w, ok := c.workers[workerID]
+	if !ok {
+		w = &workerQueues{}
+		c.workers[workerID] = w
+	}
+	return w
+}
+
+func (c *Scheduler) RegisterWorker(workerID api.WorkerID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.worker(workerID)
+}
+
+// UnregisterWorker forgets the worker and the artifacts stored in its cache.
+//
+// Jobs waiting in the local queues of the worker reach other workers through the global queue, as
+// the timeouts expire. UnregisterWorker returns the artifacts, that were stored in the cache of the worker.
+func (c *Scheduler) UnregisterWorker(workerID api.WorkerID) []build.ID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var removed []build.ID
+	for id, locations := range c.artifacts {
+		if _, ok := locations[workerID]; !ok {
+			continue
+		}
+
+		removed = append(removed, id)
+		delete(locations, workerID)
+		if len(locations) == 0 {
+			delete(c.artifacts, id)
+		}
+	}
+
+	delete(c.workers, workerID)
+	delete(c.cancelled, workerID)
+	delete(c.removals, workerID)
+	delete(c.losers, workerID)
+	return removed
+}
+
+// UpdateWorker records capacity, free resources and labels of the worker, reported in the heartbeat.
+//
+// Jobs picked by the worker later are bin-packed into the free resources. Nil capacity and free mean
+// that resources of the worker are not limited.
+func (c *Scheduler) UpdateWorker(workerID api.WorkerID, capacity, free *build.Resources, labels []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := c.worker(workerID)
+
+	w.free = nil
+	if free != nil {
+		r := *free
+		w.free = &r
+	}
+
+	w.capacity = nil
+	if capacity != nil {
+		r := *capacity
+		w.capacity = &r
+	}
+
+	w.labels = make(map[string]struct{}, len(labels))
+	for _, label := range labels {
+		w.labels[label] = struct{}{}
+	}
+}
+
+// Stats returns the current number of queued and running jobs and registered workers.
+func (c *Scheduler) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := Stats{Workers: len(c.workers)}
+	for _, job := range c.pending {
+		if job.picked {
+			stats.Running++
+		} else {
+			stats.Queued++
+		}
+	}
+	return stats
+}
+
+// Workers returns sorted ids of the registered workers.
+func (c *Scheduler) Workers() []api.WorkerID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	workers := make([]api.WorkerID, 0, len(c.workers))
+	for workerID := range c.workers {
+		workers = append(workers, workerID)
+	}
+
+	sort.Slice(workers, func(i, j int) bool {
+		return workers[i] < workers[j]
+	})
+	return workers
+}
+
+// Unsatisfiable returns the waiting jobs, that none of the registered workers can run: no worker has
+// all labels of the job or enough resources, even when idle. Jobs keep waiting for a suitable worker.
+func (c *Scheduler) Unsatisfiable(ids []build.ID) []build.ID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var unsatisfiable []build.ID
+	for _, id := range ids {
+		job, ok := c.pending[id]
+		if !ok || job.picked {
+			continue
+		}
+
+		satisfied := false
+		for _, w := range c.workers {
+			if w.satisfies(job) {
+				satisfied = true
+				break
+			}
+		}
+
+		if !satisfied {
+			unsatisfiable = append(unsatisfiable, id)
+		}
+	}
+	return unsatisfiable
+}
+
+// LocateArtifacts returns sorted ids of all workers holding the artifact.
+func (c *Scheduler) LocateArtifacts(id build.ID) []api.WorkerID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var workers []api.WorkerID
+	for workerID := range c.artifacts[id] {
+		workers = append(workers, workerID)
+	}
+
+	sort.Slice(workers, func(i, j int) bool {
+		return workers[i] < workers[j]
+	})
+	return workers
 }
 
 func (c *Scheduler) LocateArtifact(id build.ID) (api.WorkerID, bool) {
-	panic("implement me")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for workerID := range c.artifacts[id] {
+		return workerID, true
+	}
+	return "", false
+}
+
+// UniqueArtifacts returns artifacts stored only in the cache of the given worker.
+func (c *Scheduler) UniqueArtifacts(workerID api.WorkerID) []build.ID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var unique []build.ID
+	for id, locations := range c.artifacts {
+		if _, ok := locations[workerID]; ok && len(locations) == 1 {
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// OnArtifactAdded records that artifact appeared in the cache of the worker.
+func (c *Scheduler) OnArtifactAdded(workerID api.WorkerID, id build.ID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.addArtifact(workerID, id)
+}
+
+// OnArtifactRemoved records that artifact was removed from the cache of the worker.
+//
+// Job waiting for the artifact leaves the cached queue of the worker.
+func (c *Scheduler) OnArtifactRemoved(workerID api.WorkerID, id build.ID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	locations := c.artifacts[id]
+	delete(locations, workerID)
+	if len(locations) == 0 {
+		delete(c.artifacts, id)
+	}
+
+	if job, ok := c.pending[id]; ok {
+		if w, ok := c.workers[workerID]; ok {
+			w.cached.drop(job)
+		}
+	}
+}
+
+// RemoveArtifact forgets the artifact stored on the worker and asks the worker to delete it.
+func (c *Scheduler) RemoveArtifact(workerID api.WorkerID, id build.ID) {
+	c.OnArtifactRemoved(workerID, id)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.workers[workerID]; ok {
+		c.removals[workerID] = append(c.removals[workerID], id)
+	}
+}
+
+// TakeRemovedArtifacts returns artifacts, that the worker should delete.
+func (c *Scheduler) TakeRemovedArtifacts(workerID api.WorkerID) []build.ID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ids := c.removals[workerID]
+	delete(c.removals, workerID)
+	return ids
+}
+
+func (c *Scheduler) addArtifact(workerID api.WorkerID, id build.ID) {
+	locations, ok := c.artifacts[id]
+	if !ok {
+		locations = make(map[api.WorkerID]struct{})
+		c.artifacts[id] = locations
+	}
+
+	if _, ok := locations[workerID]; ok {
+		return
+	}
+	locations[workerID] = struct{}{}
+
+	w := c.worker(workerID)
+	if job, ok := c.pending[id]; ok && !job.picked {
+		w.cached.push(job)
+	}
+
+	for _, job := range c.pending {
+		if job.picked || !job.depsQueued {
+			continue
+		}
+
+		for _, dep := range job.Job.Deps {
+			if dep == id {
+				w.deps.push(job)
+				break
+			}
+		}
+	}
+
+	c.notify()
 }
 
 func (c *Scheduler) OnJobComplete(workerID api.WorkerID, jobID build.ID, res *api.JobResult) bool {
-	panic("implement me")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if res.Error == nil && res.ExitCode == 0 {
+		c.addArtifact(workerID, jobID)
+	}
+
+	job, ok := c.pending[jobID]
+	if !ok {
+		return false
+	}
+
+	delete(c.pending, jobID)
+	if !job.picked {
+		c.dequeued(job, false)
+	}
+	job.picked = true
+	job.Result = res
+	close(job.Finished)
+
+	if res.Error == nil && res.ExitCode == 0 {
+		c.recordDuration(job, res)
+	}
+
+	if job.copyWorkerID != "" {
+		loser := job.copyWorkerID
+		if workerID == job.copyWorkerID {
+			loser = job.workerID
+		}
+		c.cancelLoser(loser, jobID)
+
+		c.l.Info("speculative execution finished",
+			zap.String("job_id", jobID.String()),
+			zap.String("winner_worker_id", workerID.String()),
+			zap.String("loser_worker_id", loser.String()))
+	}
+	return true
+}
+
+// recordDuration updates the historical duration of the jobs with the name of the job. Must be called under mu.
+func (c *Scheduler) recordDuration(job *pendingJob, res *api.JobResult) {
+	if res.Trace == nil || res.Trace.Started.IsZero() || res.Trace.Executed.IsZero() {
+		return
+	}
+
+	d := res.Trace.Executed.Sub(res.Trace.Started)
+	if prev, ok := c.durations[job.Job.Name]; ok {
+		d = prev + time.Duration(durationWeight*float64(d-prev))
+	}
+	c.durations[job.Job.Name] = d
+}
+
+// cancelLoser asks the worker to stop the copy of the finished job. Must be called under mu.
+func (c *Scheduler) cancelLoser(workerID api.WorkerID, jobID build.ID) {
+	c.cancelled[workerID] = append(c.cancelled[workerID], jobID)
+
+	losers, ok := c.losers[workerID]
+	if !ok {
+		losers = make(map[build.ID]bool)
+		c.losers[workerID] = losers
+	}
+	losers[jobID] = false
+}
+
+// Superseded reports whether the result comes from the copy of the speculatively executed job, that lost
+// the race, or is the failure of an earlier attempt. Such result is not reported to the builds.
+//
+// Copy loses, when the other copy finishes first. Failed copy also loses, while the other copy is still running.
+// Failure of an earlier attempt arrives again, when the worker resends the heartbeat, which response was lost.
+func (c *Scheduler) Superseded(workerID api.WorkerID, res *api.JobResult) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.losers[workerID][res.ID]; ok {
+		delete(c.losers[workerID], res.ID)
+		return true
+	}
+
+	succeeded := res.Error == nil && res.ExitCode == 0
+	job, ok := c.pending[res.ID]
+	if !ok || succeeded {
+		return false
+	}
+
+	if res.Attempts != 0 && res.Attempts < job.attempt {
+		return true
+	}
+
+	if !job.picked || job.copyWorkerID == "" {
+		return false
+	}
+
+	switch workerID {
+	case job.copyWorkerID:
+	case job.workerID:
+		job.workerID = job.copyWorkerID
+	default:
+		return false
+	}
+	job.copyWorkerID = ""
+
+	c.l.Info("failed copy of the job dropped",
+		zap.String("job_id", res.ID.String()),
+		zap.String("failed_worker_id", workerID.String()),
+		zap.String("worker_id", job.workerID.String()))
+	return true
+}
+
+// retryable reports whether the failed attempt may be repeated according to the job retry policy.
+func retryable(job *pendingJob, res *api.JobResult) bool {
+	if job.attempt-job.lost > job.Job.Retries {
+		return false
+	}
+
+	if res.Error != nil {
+		return true
+	}
+	return res.ExitCode != 0 && job.Job.RetryOnExitCode
+}
+
+// RetryJob puts the job back into the queues, if the failed attempt may be repeated.
+//
+// Workers other than the one reporting the failure get the job first. The failed worker may take
+// the job after DepsTimeout. RetryJob reports whether the job was re-queued, in which case the
+// result should be dropped.
+func (c *Scheduler) RetryJob(workerID api.WorkerID, res *api.JobResult) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	job, ok := c.pending[res.ID]
+	if !ok || !job.picked || !retryable(job, res) {
+		return false
+	}
+
+	c.requeue(job, workerID)
+
+	c.l.Info("job retried",
+		zap.String("job_id", res.ID.String()),
+		zap.String("failed_worker_id", workerID.String()),
+		zap.Int("attempt", job.attempt))
+	return true
 }
 
+// requeue starts the next attempt of the picked job. Workers other than failedWorkerID get the job first.
+// Must be called under mu.
+func (c *Scheduler) requeue(job *pendingJob, failedWorkerID api.WorkerID) {
+	job.attempt++
+	job.picked = false
+	job.straggler = false
+	c.enqueued(job)
+
+	// Entries left by the previous attempt would let the failed worker pick the job right away.
+	c.global.drop(job)
+	for id, w := range c.workers {
+		w.cached.drop(job)
+		w.deps.drop(job)
+		if id != failedWorkerID {
+			w.deps.push(job)
+		}
+	}
+	c.notify()
+
+	c.wg.Add(1)
+	go c.promote(job, job.attempt, false)
+}
+
+// RequeueLost puts back into the queues the jobs picked by the worker, that are missing from running.
+// Such jobs were lost together with the heartbeat response or with the restart of the worker.
+//
+// RequeueLost must be called for every heartbeat after its finished jobs are processed and before the
+// worker picks new jobs. Lost attempts do not use up the retries of the job. RequeueLost returns the
+// requeued jobs.
+func (c *Scheduler) RequeueLost(workerID api.WorkerID, running []build.ID) []build.ID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	isRunning := make(map[build.ID]bool, len(running))
+	for _, id := range running {
+		isRunning[id] = true
+	}
+
+	var lost []build.ID
+	for id, job := range c.pending {
+		if !job.picked || isRunning[id] {
+			continue
+		}
+
+		switch workerID {
+		case job.copyWorkerID:
+			job.copyWorkerID = ""
+		case job.workerID:
+			if job.copyWorkerID != "" {
+				job.workerID, job.copyWorkerID = job.copyWorkerID, ""
+				continue
+			}
+
+			job.lost++
+			c.requeue(job, workerID)
+			lost = append(lost, id)
+		}
+	}
+	return lost
+}
+
+// queueDeps puts job into the second local queues of the workers holding job dependencies. Must be called under mu.
+func (c *Scheduler) queueDeps(job *pendingJob) {
+	job.depsQueued = true
+
+	queued := map[api.WorkerID]struct{}{}
+	for _, dep := range job.Job.Deps {
+		for workerID := range c.artifacts[dep] {
+			if _, ok := queued[workerID]; ok {
+				continue
+			}
+
+			queued[workerID] = struct{}{}
+			c.worker(workerID).deps.push(job)
+		}
+	}
+
+	c.notify()
+}
+
+// ScheduleJob schedules job with the default policy.
 func (c *Scheduler) ScheduleJob(job *api.JobSpec) *PendingJob {
-	panic("implement me")
+	return c.ScheduleJobWithPolicy(job, Policy{})
+}
+
+// ScheduleJobWithPolicy schedules job, ordering it against other jobs according to the policy.
+//
+// When the job is already scheduled by another build, the job keeps its user and gets the
+// highest priority and critical path of the builds.
+func (c *Scheduler) ScheduleJobWithPolicy(job *api.JobSpec, policy Policy) *PendingJob {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if pending, ok := c.pending[job.ID]; ok {
+		pending.refs++
+		pending.policy.Priority = max(pending.policy.Priority, policy.Priority)
+		pending.policy.CriticalPath = max(pending.policy.CriticalPath, policy.CriticalPath)
+		return pending.PendingJob
+	}
+
+	pending := &pendingJob{
+		PendingJob: &PendingJob{
+			Job:      job,
+			Finished: make(chan struct{}),
+		},
+		refs:    1,
+		attempt: 1,
+		policy:  policy,
+		seq:     c.seq,
+		queued:  time.Now(),
+	}
+	c.seq++
+	c.pending[job.ID] = pending
+	c.enqueued(pending)
+
+	for workerID := range c.artifacts[job.ID] {
+		c.worker(workerID).cached.push(pending)
+	}
+
+	cached := len(c.artifacts[job.ID]) != 0
+	if !cached {
+		c.queueDeps(pending)
+	} else {
+		c.notify()
+	}
+
+	c.l.Debug("job scheduled", zap.String("job_id", job.ID.String()), zap.Bool("cached", cached))
+
+	c.wg.Add(1)
+	go c.promote(pending, pending.attempt, cached)
+
+	return pending.PendingJob
+}
+
+// CancelJob notifies scheduler that one of the builds no longer waits for the job.
+//
+// Once no build waits for the job, the job is removed from the queues. If the job is already
+// running, the worker is asked to stop it. Finished channel of the cancelled job is never closed.
+func (c *Scheduler) CancelJob(jobID build.ID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	job, ok := c.pending[jobID]
+	if !ok {
+		return
+	}
+
+	job.refs--
+	if job.refs > 0 {
+		return
+	}
+
+	delete(c.pending, jobID)
+	if job.picked {
+		c.cancelled[job.workerID] = append(c.cancelled[job.workerID], jobID)
+		if job.copyWorkerID != "" {
+			c.cancelled[job.copyWorkerID] = append(c.cancelled[job.copyWorkerID], jobID)
+		}
+		c.l.Debug("running job cancelled",
+			zap.String("job_id", jobID.String()),
+			zap.String("worker_id", job.workerID.String()))
+	} else {
+		job.picked = true
+		c.dequeued(job, false)
+		c.l.Debug("job cancelled", zap.String("job_id", jobID.String()))
+	}
+}
+
+// TakeCancelledJobs returns jobs, that the worker should stop.
+func (c *Scheduler) TakeCancelledJobs(workerID api.WorkerID) []build.ID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Losing copy finished before the worker learned about the cancellation reports its result in the next
+	// heartbeat at the latest. Later the copy is forgotten.
+	for id, told := range c.losers[workerID] {
+		if told {
+			delete(c.losers[workerID], id)
+		} else {
+			c.losers[workerID][id] = true
+		}
+	}
+
+	jobs := c.cancelled[workerID]
+	delete(c.cancelled, workerID)
+	return jobs
+}
+
+// promote moves job to the less local queues as timeouts expire. Promotion stops, once the attempt
+// is picked or superseded by the next attempt.
+func (c *Scheduler) promote(job *pendingJob, attempt int, cached bool) {
+	defer c.wg.Done()
+
+	// waiting must be called under mu.
+	waiting := func() bool {
+		return !job.picked && job.attempt == attempt
+	}
+
+	if cached {
+		select {
+		case <-c.timeAfter(c.config.CacheTimeout):
+		case <-c.stop:
+			return
+		}
+
+		c.mu.Lock()
+		if waiting() {
+			c.queueDeps(job)
+		}
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	ok := waiting()
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	select {
+	case <-c.timeAfter(c.config.DepsTimeout):
+	case <-c.stop:
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if waiting() {
+		c.global.push(job)
+		c.notify()
+	}
+}
+
+// tryPick picks the best job of the worker queues and the global queue. Must be called under mu.
+//
+// Each queue offers its best job, that fits into the free resources of the worker. Offers are compared
+// with less, the queue is chosen at random only among the equal offers.
+func (c *Scheduler) tryPick(workerID api.WorkerID) *pendingJob {
+	w := c.worker(workerID)
+	fits := func(job *pendingJob) bool {
+		return job.policy.AvoidWorker != workerID && w.fits(job)
+	}
+
+	type candidate struct {
+		queue *jobQueue
+		index int
+	}
+
+	var candidates []candidate
+	for _, q := range []*jobQueue{&w.cached, &w.deps, &c.global} {
+		if i := q.find(fits, c.less); i != -1 {
+			candidates = append(candidates, candidate{q, i})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return c.tryPickStraggler(workerID, fits)
+	}
+
+	best := candidates[:1]
+	for _, cand := range candidates[1:] {
+		job, bestJob := cand.queue.jobs[cand.index], best[0].queue.jobs[best[0].index]
+		switch {
+		case c.less(job, bestJob):
+			best = []candidate{cand}
+		case !c.less(bestJob, job):
+			best = append(best, cand)
+		}
+	}
+
+	chosen := best[rand.Intn(len(best))]
+	job := chosen.queue.remove(chosen.index)
+	job.picked = true
+	job.workerID = workerID
+	job.copyWorkerID = ""
+	c.dequeued(job, true)
+	c.reserve(w, job)
+
+	if c.config.SpeculationFactor > 0 {
+		if d, ok := c.durations[job.Job.Name]; ok {
+			c.wg.Add(1)
+			go c.watch(job, job.attempt, workerID, d)
+		}
+	}
+	return job
+}
+
+// reserve prepares the spec of the picked job. Must be called under mu.
+func (c *Scheduler) reserve(w *workerQueues, job *pendingJob) {
+	// Resources of the picked job are reserved until the next heartbeat of the worker.
+	if w.free != nil {
+		*w.free = w.free.Sub(job.Job.Resources)
+	}
+
+	// Spec is copied, since the previous attempt might still be in use.
+	spec := *job.Job
+	spec.Attempt = job.attempt
+	spec.Queued = job.queued
+	spec.Picked = time.Now()
+	job.Job = &spec
+}
+
+// tryPickStraggler gives the worker a speculative copy of the straggling job. Must be called under mu.
+//
+// Copies are run only by the workers, that have nothing else to do.
+func (c *Scheduler) tryPickStraggler(workerID api.WorkerID, fits func(job *pendingJob) bool) *pendingJob {
+	for i := 0; i < len(c.stragglers); i++ {
+		job := c.stragglers[i]
+		if c.pending[job.Job.ID] != job || !job.picked || job.copyWorkerID != "" {
+			c.stragglers = append(c.stragglers[:i], c.stragglers[i+1:]...)
+			i--
+			continue
+		}
+
+		if job.workerID == workerID || !fits(job) {
+			continue
+		}
+
+		c.stragglers = append(c.stragglers[:i], c.stragglers[i+1:]...)
+		job.copyWorkerID = workerID
+		c.reserve(c.worker(workerID), job)
+
+		c.l.Info("speculative copy of the job started",
+			zap.String("job_id", job.Job.ID.String()),
+			zap.String("worker_id", job.workerID.String()),
+			zap.String("copy_worker_id", workerID.String()))
+		return job
+	}
+	return nil
+}
+
+// watch marks the job as a straggler, if the attempt runs longer than SpeculationFactor times
+// the historical duration d.
+func (c *Scheduler) watch(job *pendingJob, attempt int, workerID api.WorkerID, d time.Duration) {
+	defer c.wg.Done()
+
+	delay := max(time.Duration(c.config.SpeculationFactor*float64(d)), c.config.SpeculationMinDelay)
+	select {
+	case <-c.timeAfter(delay):
+	case <-job.Finished:
+		return
+	case <-c.stop:
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pending[job.Job.ID] != job || !job.picked || job.attempt != attempt || job.workerID != workerID || job.straggler {
+		return
+	}
+
+	job.straggler = true
+	c.stragglers = append(c.stragglers, job)
+	c.notify()
+
+	c.l.Info("straggler detected",
+		zap.String("job_id", job.Job.ID.String()),
+		zap.String("name", job.Job.Name),
+		zap.String("worker_id", workerID.String()),
+		zap.Duration("expected", d))
+}
+
+// QueuePositions returns positions of the waiting jobs in the order, in which workers pick jobs.
+//
+// Position is the number of waiting jobs going before the job. Jobs, that are not waiting for a
+// worker, are omitted. Positions are approximate, since workers prefer jobs with local artifacts.
+func (c *Scheduler) QueuePositions(ids []build.ID) map[build.ID]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var waiting []*pendingJob
+	for _, job := range c.pending {
+		if !job.picked {
+			waiting = append(waiting, job)
+		}
+	}
+
+	sort.Slice(waiting, func(i, j int) bool {
+		a, b := waiting[i], waiting[j]
+		if c.less(a, b) || c.less(b, a) {
+			return c.less(a, b)
+		}
+		return a.seq < b.seq
+	})
+
+	positions := make(map[build.ID]int, len(waiting))
+	for i, job := range waiting {
+		positions[job.Job.ID] = i
+	}
+
+	result := map[build.ID]int{}
+	for _, id := range ids {
+		if pos, ok := positions[id]; ok {
+			result[id] = pos
+		}
+	}
+	return result
 }
 
 func (c *Scheduler) PickJob(ctx context.Context, workerID api.WorkerID) *PendingJob {
-	panic("implement me")
+	for {
+		c.mu.Lock()
+		job := c.tryPick(workerID)
+		changed := c.changed
+		c.mu.Unlock()
+
+		if job != nil {
+			c.l.Debug("job picked",
+				zap.String("job_id", job.Job.ID.String()),
+				zap.String("worker_id", workerID.String()))
+			return job.PendingJob
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil
+		case <-c.stop:
+			return nil
+		}
+	}
 }
 
 func (c *Scheduler) Stop() {
-	panic("implement me")
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+	c.wg.Wait()
 }
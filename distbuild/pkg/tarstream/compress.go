@@ -0,0 +1,69 @@
+package tarstream
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Gzip is the only compression supported by the handlers.
+//
+// zstd is better suited for build outputs, but there is no zstd implementation among the dependencies.
+const Gzip = "gzip"
+
+// AcceptEncoding is the value of Accept-Encoding header sent by the clients.
+const AcceptEncoding = Gzip
+
+// NegotiateEncoding picks response encoding given Accept-Encoding header of the request.
+//
+// Empty string means that the response is sent as is.
+func NegotiateEncoding(acceptEncoding string) string {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), Gzip) {
+			continue
+		}
+
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				return ""
+			}
+		}
+		return Gzip
+	}
+	return ""
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// NewWriter compresses data written to w. Close must be called to flush the compressed stream.
+func NewWriter(w io.Writer, encoding string) (io.WriteCloser, error) {
+	switch encoding {
+	case "":
+		return nopWriteCloser{w}, nil
+	case Gzip:
+		return gzip.NewWriterLevel(w, gzip.BestSpeed)
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+}
+
+// NewReader decompresses data read from r.
+func NewReader(r io.Reader, encoding string) (io.ReadCloser, error) {
+	switch encoding {
+	case "":
+		return io.NopCloser(r), nil
+	case Gzip:
+		return gzip.NewReader(r)
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+}
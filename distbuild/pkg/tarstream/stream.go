@@ -2,13 +2,45 @@ package tarstream
 
 import (
 	"archive/tar"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"syscall"
 )
 
 // Send рекурсивно обходит директорию и сериализует её содержимое в поток w.
 func Send(dir string, w io.Writer) error {
+	return SendFrom(dir, w, 0)
+}
+
+// skipWriter drops the first n bytes written to it.
+type skipWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (s *skipWriter) Write(p []byte) (int, error) {
+	if s.n >= int64(len(p)) {
+		s.n -= int64(len(p))
+		return len(p), nil
+	}
+
+	written, err := s.w.Write(p[s.n:])
+	written += int(s.n)
+	s.n = 0
+	return written, err
+}
+
+// SendFrom сериализует директорию так же, как Send, но пропускает первые offset байт потока.
+//
+// Поток детерминирован, поэтому продолжение можно склеить с началом, полученным ранее.
+func SendFrom(dir string, w io.Writer, offset int64) error {
+	if offset > 0 {
+		w = &skipWriter{w: w, n: offset}
+	}
+
 	tw := tar.NewWriter(w)
 
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
@@ -32,6 +64,18 @@ func Send(dir string, w io.Writer) error {
 				Typeflag: tar.TypeDir,
 			})
 
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+
+			return tw.WriteHeader(&tar.Header{
+				Name:     rel,
+				Typeflag: tar.TypeSymlink,
+				Linkname: target,
+			})
+
 		default:
 			h := &tar.Header{
 				Typeflag: tar.TypeReg,
@@ -64,7 +108,41 @@ func Send(dir string, w io.Writer) error {
 
 // Receive читает поток r и материализует содержимое потока внутри dir.
 func Receive(dir string, r io.Reader) error {
-	tr := tar.NewReader(r)
+	return NewReceiver(dir).Receive(r)
+}
+
+// Receiver материализует поток внутри директории и запоминает, какая часть потока уже записана на диск.
+//
+// Если чтение оборвалось, поток можно продолжить с позиции Offset, вызвав Receive ещё раз.
+type Receiver struct {
+	dir    string
+	offset int64
+}
+
+func NewReceiver(dir string) *Receiver {
+	return &Receiver{dir: dir}
+}
+
+// Offset returns the position in the stream right after the last entry written to disk.
+func (r *Receiver) Offset() int64 {
+	return r.offset
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Receive reads the stream starting at Offset.
+func (r *Receiver) Receive(stream io.Reader) error {
+	cr := &countingReader{r: stream, n: r.offset}
+	tr := tar.NewReader(cr)
 
 	for {
 		h, err := tr.Next()
@@ -74,27 +152,95 @@ func Receive(dir string, r io.Reader) error {
 			return err
 		}
 
-		absPath := filepath.Join(dir, h.Name)
+		if !filepath.IsLocal(h.Name) {
+			return fmt.Errorf("tarstream: entry %q is outside of the directory", h.Name)
+		}
+		if err := checkParents(r.dir, h.Name); err != nil {
+			return err
+		}
+		absPath := filepath.Join(r.dir, h.Name)
+
+		switch h.Typeflag {
+		case tar.TypeDir:
+			if err := os.Mkdir(absPath, 0777); err != nil && !os.IsExist(err) {
+				return err
+			}
 
-		if h.Typeflag == tar.TypeDir {
-			if err := os.Mkdir(absPath, 0777); err != nil {
+		case tar.TypeSymlink:
+			if !LocalLink(h.Name, h.Linkname) {
+				return fmt.Errorf("tarstream: symlink %q points outside of the directory", h.Name)
+			}
+
+			// Link left by the interrupted call is replaced.
+			if err := os.Remove(absPath); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if err := os.Symlink(h.Linkname, absPath); err != nil {
 				return err
 			}
-		} else {
+
+		default:
 			writeFile := func() error {
-				f, err := os.OpenFile(absPath, os.O_CREATE|os.O_WRONLY, os.FileMode(h.Mode))
+				// Truncate leftovers of the entry interrupted by the previous call. Symlink in place of the file
+				// is not followed.
+				f, err := os.OpenFile(absPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|syscall.O_NOFOLLOW, os.FileMode(h.Mode))
 				if err != nil {
 					return err
 				}
 				defer f.Close()
 
-				_, err = io.Copy(f, tr)
-				return err
+				if _, err = io.Copy(f, tr); err != nil {
+					return err
+				}
+
+				// Mode is restored exactly, regardless of umask, so that artifact manifests match.
+				return f.Chmod(os.FileMode(h.Mode).Perm())
 			}
 
 			if err := writeFile(); err != nil {
 				return err
 			}
 		}
+
+		// tar.Reader never reads past the entry data, and entries are padded to the block size.
+		r.offset = (cr.n + blockSize - 1) / blockSize * blockSize
 	}
 }
+
+const blockSize = 512
+
+// checkParents verifies, that no parent of the entry is a symlink. Send never puts entries under symlinks,
+// and the link created by the earlier entry could redirect the later entries outside of the directory.
+func checkParents(dir, name string) error {
+	parent := dir
+	for _, elem := range strings.Split(filepath.Dir(name), string(filepath.Separator)) {
+		if elem == "." {
+			break
+		}
+
+		parent = filepath.Join(parent, elem)
+		st, err := os.Lstat(parent)
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if st.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("tarstream: entry %q is located under symlink", name)
+		}
+	}
+	return nil
+}
+
+// LocalLink reports whether symlink at the relative path name, pointing to target, stays inside the directory.
+//
+// Only relative targets are local: absolute paths differ between the workers storing the directory.
+func LocalLink(name, target string) bool {
+	if filepath.IsAbs(target) {
+		return false
+	}
+
+	rel := filepath.Join(filepath.Dir(name), target)
+	return rel != ".." && !strings.HasPrefix(rel, "../")
+}
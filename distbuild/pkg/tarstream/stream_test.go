@@ -1,7 +1,9 @@
 package tarstream_test
 
 import (
+	"archive/tar"
 	"bytes"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
@@ -61,6 +63,128 @@ func TestTarStream(t *testing.T) {
 	checkFile(filepath.Join(to, "b", "c", "y.txt"), []byte("yyy"), 0644)
 }
 
+func TestTarStreamResume(t *testing.T) {
+	from := t.TempDir()
+	to := t.TempDir()
+
+	big := bytes.Repeat([]byte("x"), 64*1024)
+	require.NoError(t, os.WriteFile(filepath.Join(from, "a.bin"), big, 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(from, "b"), 0777))
+	require.NoError(t, os.WriteFile(filepath.Join(from, "b", "c.bin"), big, 0644))
+
+	var buf bytes.Buffer
+	require.NoError(t, tarstream.Send(from, &buf))
+
+	// Connection breaks in the middle of the last file.
+	receiver := tarstream.NewReceiver(to)
+	require.Error(t, receiver.Receive(io.LimitReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()-32*1024))))
+	require.Greater(t, receiver.Offset(), int64(len(big)))
+
+	var rest bytes.Buffer
+	require.NoError(t, tarstream.SendFrom(from, &rest, receiver.Offset()))
+	require.Equal(t, buf.Bytes()[receiver.Offset():], rest.Bytes())
+
+	require.NoError(t, receiver.Receive(&rest))
+
+	for _, name := range []string{"a.bin", "b/c.bin"} {
+		content, err := os.ReadFile(filepath.Join(to, name))
+		require.NoError(t, err)
+		require.Equal(t, big, content)
+	}
+}
+
+func TestTarStreamSymlinks(t *testing.T) {
+	from := t.TempDir()
+	to := t.TempDir()
+
+	require.NoError(t, os.Mkdir(filepath.Join(from, "lib"), 0777))
+	require.NoError(t, os.WriteFile(filepath.Join(from, "lib", "a.so.1"), []byte("aaa"), 0644))
+	require.NoError(t, os.Symlink("a.so.1", filepath.Join(from, "lib", "a.so")))
+	require.NoError(t, os.Symlink("lib", filepath.Join(from, "current")))
+
+	var buf bytes.Buffer
+	require.NoError(t, tarstream.Send(from, &buf))
+	require.NoError(t, tarstream.Receive(to, &buf))
+
+	target, err := os.Readlink(filepath.Join(to, "lib", "a.so"))
+	require.NoError(t, err)
+	require.Equal(t, "a.so.1", target)
+
+	content, err := os.ReadFile(filepath.Join(to, "current", "a.so"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("aaa"), content)
+}
+
+func TestTarStreamRejectsEscapes(t *testing.T) {
+	for _, h := range []*tar.Header{
+		{Name: "../evil", Typeflag: tar.TypeReg},
+		{Name: "/tmp/evil", Typeflag: tar.TypeReg},
+		{Name: "a/evil", Typeflag: tar.TypeSymlink, Linkname: "../.."},
+		{Name: "evil", Typeflag: tar.TypeSymlink, Linkname: "/etc"},
+	} {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		require.NoError(t, tw.WriteHeader(h))
+		require.NoError(t, tw.Close())
+
+		dir := t.TempDir()
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "a"), 0777))
+		require.Error(t, tarstream.Receive(dir, &buf), "%s", h.Name)
+	}
+
+	require.True(t, tarstream.LocalLink("a/b", "../c"))
+	require.False(t, tarstream.LocalLink("a/b", "../../c"))
+}
+
+func TestTarStreamRejectsEntriesUnderSymlinks(t *testing.T) {
+	// Each link is local by itself, but together they lead out of the directory.
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "."}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "a/l", Typeflag: tar.TypeSymlink, Linkname: "../"}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "a/l/pwned", Typeflag: tar.TypeReg, Mode: 0644, Size: 3}))
+	_, err := tw.Write([]byte("bad"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "to")
+	require.NoError(t, os.Mkdir(dir, 0777))
+
+	require.Error(t, tarstream.Receive(dir, &buf))
+
+	_, err = os.Lstat(filepath.Join(root, "pwned"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestTarStreamDoesNotWriteThroughSymlink(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "b"}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "a", Typeflag: tar.TypeReg, Mode: 0644, Size: 3}))
+	_, err := tw.Write([]byte("bad"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	dir := t.TempDir()
+	require.Error(t, tarstream.Receive(dir, &buf))
+
+	_, err = os.Lstat(filepath.Join(dir, "b"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	for header, encoding := range map[string]string{
+		"":                    "",
+		"gzip":                tarstream.Gzip,
+		"deflate, gzip;q=0.5": tarstream.Gzip,
+		"gzip;q=0":            "",
+		"br":                  "",
+	} {
+		require.Equal(t, encoding, tarstream.NegotiateEncoding(header), "%q", header)
+	}
+}
+
 func init() {
 	unix.Umask(0022)
 }
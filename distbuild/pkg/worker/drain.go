@@ -0,0 +1,78 @@
+//go:build !solution
+
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+var _ api.DrainService = (*Worker)(nil)
+
+// Drain takes the worker out of service. The worker stops taking new jobs and asks the coordinator
+// to unregister it. Run returns, once the coordinator reports the worker drained.
+//
+// workerID may be empty. Status reports only the jobs running on the worker, the number of
+// artifacts left to copy is known to the coordinator.
+func (w *Worker) Drain(ctx context.Context, workerID api.WorkerID, req *api.DrainRequest) (*api.DrainStatus, error) {
+	if workerID != "" && workerID != w.id {
+		return nil, fmt.Errorf("drain request for worker %s received by worker %s", workerID, w.id)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.drain == nil {
+		w.l.Info("draining worker", zap.Bool("replicate", req.Replicate))
+		w.drain = &api.DrainRequest{}
+	}
+	w.drain.Replicate = w.drain.Replicate || req.Replicate
+
+	return &api.DrainStatus{Draining: true, RunningJobs: len(w.running)}, nil
+}
+
+// fetchArtifact copies the artifact from the draining worker into the local cache.
+func (w *Worker) fetchArtifact(ctx context.Context, wg *sync.WaitGroup, id build.ID, source api.WorkerID) {
+	w.mu.Lock()
+	if _, ok := w.fetching[id]; ok {
+		w.mu.Unlock()
+		return
+	}
+	w.fetching[id] = struct{}{}
+	w.mu.Unlock()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer func() {
+			w.mu.Lock()
+			delete(w.fetching, id)
+			w.mu.Unlock()
+
+			select {
+			case w.jobDone <- struct{}{}:
+			default:
+			}
+		}()
+
+		err := w.artifactClient.Download(ctx, source.String(), w.artifacts, id)
+		if err != nil && !errors.Is(err, artifact.ErrExists) {
+			w.l.Warn("failed to copy artifact of draining worker",
+				zap.String("id", id.String()),
+				zap.String("source", source.String()),
+				zap.Error(err))
+			return
+		}
+
+		w.l.Debug("artifact copied", zap.String("id", id.String()), zap.String("source", source.String()))
+		w.addArtifact(id)
+	}()
+}
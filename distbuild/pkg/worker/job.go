@@ -0,0 +1,369 @@
+//go:build !solution
+
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+)
+
+// errCached is returned when job output is already present in the local cache.
+var errCached = errors.New("job output is cached")
+
+// errJobCancelled is the cause of the job context cancellation requested by the coordinator.
+var errJobCancelled = errors.New("job cancelled by coordinator")
+
+// errJobTimeout is the cause of the job context cancellation, when the job exceeds its timeout.
+var errJobTimeout = errors.New("job timed out")
+
+// waitDelay bounds the time worker waits for the output of the killed command.
+const waitDelay = time.Second
+
+// jobRun holds resources acquired for a single job execution.
+type jobRun struct {
+	spec *api.JobSpec
+
+	sourceDir string
+	deps      map[build.ID]string
+	unlocks   []func()
+
+	stdout, stderr *outputWriter
+
+	// trace records the time of each execution stage.
+	trace *api.JobTrace
+}
+
+func (r *jobRun) release() {
+	for _, unlock := range r.unlocks {
+		unlock()
+	}
+
+	if r.sourceDir != "" {
+		_ = os.RemoveAll(r.sourceDir)
+	}
+}
+
+func (w *Worker) runJob(ctx context.Context, spec *api.JobSpec, slot int) *api.JobResult {
+	trace := &api.JobTrace{
+		WorkerID: w.id,
+		Slot:     slot,
+		Queued:   spec.Queued,
+		Picked:   spec.Picked,
+		Started:  time.Now(),
+	}
+	res := &api.JobResult{ID: spec.ID, Attempts: spec.Attempt, Trace: trace}
+
+	run := &jobRun{
+		spec:   spec,
+		deps:   map[build.ID]string{},
+		stdout: &outputWriter{w: w, id: spec.ID, attempt: spec.Attempt},
+		stderr: &outputWriter{w: w, id: spec.ID, attempt: spec.Attempt, stderr: true},
+		trace:  trace,
+	}
+	defer run.release()
+
+	exitCode, err := w.execute(ctx, run)
+	if err != nil && errors.Is(context.Cause(ctx), errJobTimeout) {
+		err = fmt.Errorf("job timed out after %v: %w", spec.Timeout, err)
+	}
+
+	switch {
+	case errors.Is(err, errCached):
+		w.l.Debug("job is cached", zap.String("job_id", spec.ID.String()))
+
+		if stored, err := w.artifacts.Result(spec.ID); err == nil {
+			stored.Attempts = spec.Attempt
+			stored.Cached = true
+			stored.Trace = trace
+			return stored
+		}
+		res.Cached = true
+	case err != nil:
+		w.l.Error("job failed", zap.String("job_id", spec.ID.String()), zap.Error(err))
+
+		errStr := err.Error()
+		res.Error = &errStr
+	default:
+		w.l.Debug("job finished", zap.String("job_id", spec.ID.String()), zap.Int("exit_code", exitCode))
+	}
+
+	res.ExitCode = exitCode
+	res.Stdout = run.stdout.Bytes()
+	res.Stderr = run.stderr.Bytes()
+
+	if res.Error == nil && res.ExitCode == 0 && !res.Cached {
+		if err := w.artifacts.WriteResult(res); err != nil {
+			w.l.Warn("failed to store job result", zap.String("job_id", spec.ID.String()), zap.Error(err))
+		}
+	}
+	return res
+}
+
+func (w *Worker) execute(ctx context.Context, run *jobRun) (exitCode int, err error) {
+	if _, unlock, err := w.artifacts.Get(run.spec.ID); err == nil {
+		unlock()
+		return 0, errCached
+	}
+
+	if err := w.prepareSources(ctx, run); err != nil {
+		return 0, fmt.Errorf("prepare sources: %w", err)
+	}
+
+	if err := w.prepareDeps(ctx, run); err != nil {
+		return 0, fmt.Errorf("prepare deps: %w", err)
+	}
+	run.trace.Prepared = time.Now()
+
+	outputDir, commit, abort, err := w.artifacts.Create(run.spec.ID)
+	if errors.Is(err, artifact.ErrExists) {
+		return 0, errCached
+	} else if err != nil {
+		return 0, err
+	}
+
+	jobCtx := build.JobContext{
+		SourceDir: run.sourceDir,
+		OutputDir: outputDir,
+		Deps:      run.deps,
+	}
+
+	for _, cmd := range run.spec.Cmds {
+		exitCode, err = w.runCmd(ctx, run, &cmd, jobCtx)
+		if err != nil || exitCode != 0 {
+			_ = abort()
+			return
+		}
+	}
+	run.trace.Executed = time.Now()
+
+	if err := commit(); err != nil {
+		return 0, err
+	}
+	run.trace.Committed = time.Now()
+	return 0, nil
+}
+
+func (w *Worker) prepareSources(ctx context.Context, run *jobRun) error {
+	dir, err := os.MkdirTemp("", "distbuild-src-")
+	if err != nil {
+		return err
+	}
+	run.sourceDir = dir
+
+	for id, path := range run.spec.SourceFiles {
+		cachePath, unlock, err := w.fileCache.Get(id)
+		if errors.Is(err, filecache.ErrNotFound) {
+			if err = w.files.Download(ctx, w.fileCache, id); err != nil {
+				return err
+			}
+			cachePath, unlock, err = w.fileCache.Get(id)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = copyFile(cachePath, filepath.Join(dir, path))
+		unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *Worker) prepareDeps(ctx context.Context, run *jobRun) error {
+	for _, dep := range run.spec.Deps {
+		path, unlock, err := w.artifacts.Get(dep)
+		if errors.Is(err, artifact.ErrNotFound) {
+			if err = w.downloadDep(ctx, dep, run.spec.Artifacts[dep]); err != nil {
+				return err
+			}
+
+			path, unlock, err = w.artifacts.Get(dep)
+		}
+		if err != nil {
+			return err
+		}
+
+		run.deps[dep] = path
+		run.unlocks = append(run.unlocks, unlock)
+	}
+
+	return nil
+}
+
+// downloadDep downloads the artifact from the first worker, that has it intact.
+func (w *Worker) downloadDep(ctx context.Context, dep build.ID, locations []api.WorkerID) error {
+	if len(locations) == 0 {
+		return fmt.Errorf("artifact %v location is unknown", dep)
+	}
+
+	var lastErr error
+	for _, endpoint := range locations {
+		// Coordinator learns about quarantined artifact only from the next heartbeat.
+		if endpoint == w.id {
+			continue
+		}
+
+		w.l.Debug("downloading artifact", zap.String("id", dep.String()), zap.String("from", endpoint.String()))
+		err := w.artifactClient.Download(ctx, endpoint.String(), w.artifacts, dep)
+		if err == nil || errors.Is(err, artifact.ErrExists) {
+			w.addArtifact(dep)
+			return nil
+		}
+
+		// ErrCorrupted wraps ErrNotFound.
+		if !errors.Is(err, artifact.ErrNotFound) {
+			return err
+		}
+
+		w.l.Warn("artifact is unavailable, trying the next location",
+			zap.String("id", dep.String()),
+			zap.String("from", endpoint.String()),
+			zap.Error(err))
+		lastErr = err
+	}
+
+	if lastErr != nil {
+		return lastErr
+	}
+	return fmt.Errorf("artifact %v is missing from the local cache and no other location is known", dep)
+}
+
+func (w *Worker) runCmd(ctx context.Context, run *jobRun, cmd *build.Cmd, jobCtx build.JobContext) (int, error) {
+	rendered, err := cmd.Render(jobCtx)
+	if err != nil {
+		return 0, err
+	}
+
+	kind, err := cmd.Kind()
+	if err != nil {
+		return 0, err
+	}
+
+	switch kind {
+	case build.CmdExec:
+	case build.CmdCat:
+		// Cat writes from the worker process, so the sandbox limits it by checking the output.
+		if w.config.Sandbox {
+			if err := checkOutput(jobCtx.OutputDir, rendered.CatOutput); err != nil {
+				return 0, fmt.Errorf("cat output: %w", err)
+			}
+		}
+		return 0, os.WriteFile(rendered.CatOutput, []byte(rendered.CatTemplate), 0666)
+	default:
+		return 0, w.runNative(kind, rendered, jobCtx)
+	}
+
+	if len(rendered.Exec) == 0 {
+		return 0, nil
+	}
+
+	c := exec.CommandContext(ctx, rendered.Exec[0], rendered.Exec[1:]...)
+	c.Dir = rendered.WorkingDirectory
+	c.Env = w.commandEnv(rendered.Environ)
+	c.Stdout = run.stdout
+	c.Stderr = run.stderr
+	c.WaitDelay = waitDelay
+	setProcessGroup(c)
+
+	if w.config.Sandbox {
+		err = runSandboxed(c, jobCtx.OutputDir, w.sandboxPaths(jobCtx), w.sandboxMounts())
+	} else {
+		err = c.Run()
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		return exitErr.ExitCode(), nil
+	}
+	return 0, err
+}
+
+// workerEnviron resolves Config.Environ. Names are replaced by the variables of the worker process, unset
+// variables are skipped.
+func workerEnviron(environ []string) []string {
+	var resolved []string
+	for _, entry := range environ {
+		if strings.Contains(entry, "=") {
+			resolved = append(resolved, entry)
+		} else if value, ok := os.LookupEnv(entry); ok {
+			resolved = append(resolved, entry+"="+value)
+		}
+	}
+	return resolved
+}
+
+// commandEnv prepends Config.Environ to the environment of the command. Variables of the command are not
+// duplicated, since the sandbox passes the environment to execve as is.
+func (w *Worker) commandEnv(environ []string) []string {
+	env := make([]string, 0, len(w.environ)+len(environ))
+	for _, entry := range w.environ {
+		name, _, _ := strings.Cut(entry, "=")
+		if !slices.ContainsFunc(environ, func(e string) bool { return strings.HasPrefix(e, name+"=") }) {
+			env = append(env, entry)
+		}
+	}
+	return append(env, environ...)
+}
+
+// sandboxPaths lists inputs of the job, that are mounted read-only inside the sandbox.
+func (w *Worker) sandboxPaths(jobCtx build.JobContext) []string {
+	paths := []string{jobCtx.SourceDir}
+	for _, path := range jobCtx.Deps {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func (w *Worker) sandboxMounts() []string {
+	if w.config.SandboxMounts != nil {
+		return w.config.SandboxMounts
+	}
+	return DefaultSandboxMounts
+}
+
+// withinDir reports whether path is located inside dir.
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+func copyFile(from, to string) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0777); err != nil {
+		return err
+	}
+
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	return dst.Close()
+}
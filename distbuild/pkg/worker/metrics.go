@@ -0,0 +1,56 @@
+//go:build !solution
+
+package worker
+
+import (
+	"gitlab.com/slon/shad-go/distbuild/pkg/metrics"
+)
+
+// workerMetrics are served at /metrics of the worker.
+type workerMetrics struct {
+	*metrics.Registry
+
+	jobDuration       *metrics.Histogram
+	heartbeatDuration *metrics.Histogram
+	jobsCached        *metrics.Counter
+}
+
+func newWorkerMetrics(w *Worker) *workerMetrics {
+	r := metrics.NewRegistry()
+
+	m := &workerMetrics{
+		Registry: r,
+
+		jobDuration: r.NewHistogram("distbuild_worker_job_duration_seconds",
+			"Time spent on executing jobs, including fetching sources and dependencies.", metrics.DurationBuckets),
+		heartbeatDuration: r.NewHistogram("distbuild_worker_heartbeat_duration_seconds",
+			"Round trip time of the heartbeats. Heartbeat of the idle worker includes waiting for a new job.", metrics.DurationBuckets),
+		jobsCached: r.NewCounter("distbuild_worker_jobs_cached_total",
+			"Jobs, which artifacts were already present in the worker cache."),
+	}
+
+	r.NewGaugeFunc("distbuild_worker_free_slots", "Slots available for new jobs.", func() float64 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return float64(w.config.Slots - len(w.running))
+	})
+	r.NewGaugeFunc("distbuild_worker_running_jobs", "Jobs running on the worker.", func() float64 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return float64(len(w.running))
+	})
+
+	r.NewCounterFunc("distbuild_worker_artifact_cache_hits_total", "Artifact lookups found in the worker cache.", func() float64 {
+		return float64(w.artifacts.Stats().Hits)
+	})
+	r.NewCounterFunc("distbuild_worker_artifact_cache_misses_total", "Artifact lookups missing from the worker cache.", func() float64 {
+		return float64(w.artifacts.Stats().Misses)
+	})
+	r.NewCounterFunc("distbuild_worker_file_cache_hits_total", "Source file lookups found in the worker cache.", func() float64 {
+		return float64(w.fileCache.Stats().Hits)
+	})
+	r.NewCounterFunc("distbuild_worker_file_cache_misses_total", "Source file lookups missing from the worker cache.", func() float64 {
+		return float64(w.fileCache.Stats().Misses)
+	})
+	return m
+}
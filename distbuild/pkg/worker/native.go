@@ -0,0 +1,307 @@
+//go:build !solution
+
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/tarstream"
+)
+
+// runNative executes the command kinds implemented by the worker itself.
+//
+// Outputs of the commands must stay inside the output directory, even when the sandbox is disabled:
+// commands run in the worker process. With the sandbox enabled, inputs are limited to the paths visible
+// to the sandboxed commands.
+func (w *Worker) runNative(kind build.CmdKind, cmd *build.Cmd, jobCtx build.JobContext) error {
+	switch kind {
+	case build.CmdCopy:
+		if err := w.checkInput(jobCtx, cmd.CopySource); err != nil {
+			return err
+		}
+		if err := checkOutput(jobCtx.OutputDir, cmd.CopyOutput); err != nil {
+			return err
+		}
+		if withinDir(cmd.CopySource, cmd.CopyOutput) {
+			return fmt.Errorf("copy %s is inside of the copied directory", cmd.CopyOutput)
+		}
+		return copyTree(jobCtx.OutputDir, cmd.CopySource, cmd.CopyOutput)
+
+	case build.CmdSymlink:
+		if !withinDir(jobCtx.OutputDir, cmd.SymlinkOutput) {
+			return fmt.Errorf("%s is outside of the output directory", cmd.SymlinkOutput)
+		}
+		// The link itself is replaced, only its directory must resolve inside the output directory.
+		if err := checkOutput(jobCtx.OutputDir, filepath.Dir(cmd.SymlinkOutput)); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(cmd.SymlinkOutput), 0777); err != nil {
+			return err
+		}
+
+		target := cmd.SymlinkTarget
+		if filepath.IsAbs(target) {
+			rel, err := filepath.Rel(filepath.Dir(filepath.Clean(cmd.SymlinkOutput)), filepath.Clean(target))
+			if err != nil {
+				return err
+			}
+			target = rel
+		}
+		return createSymlink(jobCtx.OutputDir, target, cmd.SymlinkOutput)
+
+	case build.CmdMkdir:
+		if err := checkOutput(jobCtx.OutputDir, cmd.Mkdir); err != nil {
+			return err
+		}
+		return os.MkdirAll(cmd.Mkdir, 0777)
+
+	case build.CmdArchive:
+		if err := w.checkInput(jobCtx, cmd.ArchiveSource); err != nil {
+			return err
+		}
+		if err := checkOutput(jobCtx.OutputDir, cmd.ArchiveOutput); err != nil {
+			return err
+		}
+		if withinDir(cmd.ArchiveSource, cmd.ArchiveOutput) {
+			return fmt.Errorf("archive %s is inside of the archived directory", cmd.ArchiveOutput)
+		}
+		return writeArchive(cmd.ArchiveSource, cmd.ArchiveOutput)
+
+	case build.CmdUnarchive:
+		if err := w.checkInput(jobCtx, cmd.UnarchiveSource); err != nil {
+			return err
+		}
+		if err := checkOutput(jobCtx.OutputDir, cmd.UnarchiveOutput); err != nil {
+			return err
+		}
+		return readArchive(cmd.UnarchiveSource, cmd.UnarchiveOutput)
+
+	default:
+		return fmt.Errorf("unsupported command kind %s", kind)
+	}
+}
+
+// checkInput verifies, that the sandboxed job reads only its sources, dependencies and outputs.
+// Symlinks are resolved first, so that a link planted by the job can not point the command elsewhere.
+func (w *Worker) checkInput(jobCtx build.JobContext, path string) error {
+	if !w.config.Sandbox {
+		return nil
+	}
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("input %s: %w", path, err)
+	}
+
+	for _, dir := range append(w.sandboxPaths(jobCtx), jobCtx.OutputDir) {
+		root, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			continue
+		}
+		if withinDir(root, resolved) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not an input of the job", path)
+}
+
+// checkOutput verifies, that path stays inside the output directory. Symlinks already created by the job
+// are followed, so that a command can not write outside of the output directory through them.
+func checkOutput(outputDir, path string) error {
+	if !withinDir(outputDir, path) {
+		return fmt.Errorf("%s is outside of the output directory", path)
+	}
+
+	root, err := filepath.EvalSymlinks(outputDir)
+	if err != nil {
+		return err
+	}
+
+	for existing := filepath.Clean(path); ; existing = filepath.Dir(existing) {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if errors.Is(err, fs.ErrNotExist) {
+			if _, lerr := os.Lstat(existing); lerr == nil {
+				return fmt.Errorf("%s is a dangling symlink", existing)
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if !withinDir(root, resolved) {
+			return fmt.Errorf("%s is outside of the output directory", path)
+		}
+		return nil
+	}
+}
+
+// createSymlink creates symlink at path, that must point inside the output directory. The target is checked
+// against the real location of the link, as links created earlier by the job may redirect its directory.
+func createSymlink(outputDir, target, path string) error {
+	root, err := filepath.EvalSymlinks(outputDir)
+	if err != nil {
+		return err
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Dir(filepath.Clean(path)))
+	if err != nil {
+		return err
+	}
+	if !withinDir(root, dir) {
+		return fmt.Errorf("%s is outside of the output directory", path)
+	}
+
+	rel, err := filepath.Rel(root, filepath.Join(dir, filepath.Base(path)))
+	if err != nil {
+		return err
+	}
+	if !tarstream.LocalLink(rel, target) {
+		return fmt.Errorf("symlink %s -> %s points outside of the output directory", path, target)
+	}
+
+	if err := removeFile(path); err != nil {
+		return err
+	}
+	return os.Symlink(target, path)
+}
+
+// removeFile removes file or symlink at path, so that it is replaced instead of being written through.
+func removeFile(path string) error {
+	st, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if st.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+	return os.Remove(path)
+}
+
+// copyTree copies file or directory from to the path to. File modes and symlinks are preserved.
+func copyTree(outputDir, from, to string) error {
+	return filepath.Walk(from, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(to, rel)
+
+		switch {
+		case info.IsDir():
+			if st, err := os.Lstat(dst); err == nil && !st.IsDir() {
+				return fmt.Errorf("%s is not a directory", dst)
+			}
+			return os.MkdirAll(dst, 0777)
+
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return createSymlink(outputDir, target, dst)
+
+		default:
+			if err := os.MkdirAll(filepath.Dir(dst), 0777); err != nil {
+				return err
+			}
+			if err := removeFile(dst); err != nil {
+				return err
+			}
+			return copyFileMode(path, dst, info.Mode().Perm())
+		}
+	})
+}
+
+func copyFileMode(from, to string, mode os.FileMode) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(to, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+
+	// Mode is copied exactly, regardless of umask, as tarstream does.
+	if err := dst.Chmod(mode); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// archiveEncoding picks compression of the archive by its extension.
+func archiveEncoding(path string) string {
+	if strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz") {
+		return tarstream.Gzip
+	}
+	return ""
+}
+
+// writeArchive packs the directory. Archive is reproducible: tarstream omits timestamps and owners.
+func writeArchive(dir, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	if err := removeFile(path); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cw, err := tarstream.NewWriter(f, archiveEncoding(path))
+	if err != nil {
+		return err
+	}
+	if err := tarstream.Send(dir, cw); err != nil {
+		return err
+	}
+	if err := cw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// readArchive unpacks the archive. tarstream rejects entries and symlinks escaping the directory.
+func readArchive(path, dir string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := tarstream.NewReader(f, archiveEncoding(path))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	return tarstream.Receive(dir, r)
+}
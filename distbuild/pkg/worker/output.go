@@ -0,0 +1,65 @@
+//go:build !solution
+
+package worker
+
+import (
+	"bytes"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// outputWriter collects the full output of the job and queues it for streaming to the coordinator.
+type outputWriter struct {
+	w       *Worker
+	id      build.ID
+	attempt int
+	stderr  bool
+
+	buf bytes.Buffer
+}
+
+func (o *outputWriter) Write(p []byte) (int, error) {
+	offset := o.buf.Len()
+	o.buf.Write(p)
+	o.w.queueOutput(o.id, o.attempt, o.stderr, offset, p)
+	return len(p), nil
+}
+
+func (o *outputWriter) Bytes() []byte {
+	return o.buf.Bytes()
+}
+
+// queueOutput adds output chunk to the next heartbeat.
+//
+// Chunks are dropped when the queue is full. The coordinator gets the full output with the job result anyway.
+func (w *Worker) queueOutput(id build.ID, attempt int, stderr bool, offset int, data []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.outputSize+len(data) > maxPendingOutput {
+		return
+	}
+	w.outputSize += len(data)
+
+	if n := len(w.output); n != 0 {
+		last := &w.output[n-1]
+		if last.ID == id && last.Attempt == attempt && last.Stderr == stderr && last.Offset+len(last.Data) == offset {
+			last.Data = append(last.Data, data...)
+			return
+		}
+	}
+
+	w.output = append(w.output, api.JobOutput{
+		ID:      id,
+		Stderr:  stderr,
+		Attempt: attempt,
+		Offset:  offset,
+		Data:    append([]byte(nil), data...),
+	})
+
+	select {
+	case w.outputReady <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !solution && !unix
+
+package worker
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup is not supported, cancellation kills only the command process.
+func setProcessGroup(c *exec.Cmd) {}
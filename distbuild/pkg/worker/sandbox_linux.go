@@ -0,0 +1,270 @@
+//go:build !solution && linux
+
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// sandboxEnv passes sandboxSpec to the copy of the worker binary, that sets up the sandbox.
+	sandboxEnv = "DISTBUILD_SANDBOX"
+
+	// sandboxErrorFD is the pipe, that receives the sandbox setup error. The pipe is closed by exec.
+	sandboxErrorFD = 3
+)
+
+// sandboxSpec describes the command and the file system visible to it.
+type sandboxSpec struct {
+	// Root is an empty directory, that becomes the root of the sandbox.
+	Root string
+
+	// ReadOnly and Writable are host paths mounted inside the sandbox at the same locations.
+	ReadOnly []string
+	Writable []string
+
+	// Optional are read-only host paths, that are skipped when missing on the host.
+	Optional []string
+
+	Dir  string
+	Path string
+	Args []string
+	Env  []string
+}
+
+// Sandbox setup runs in the copy of the worker binary started inside new namespaces.
+// It never returns to main, the process is replaced by the command of the job.
+func init() {
+	data, ok := os.LookupEnv(sandboxEnv)
+	if !ok {
+		return
+	}
+
+	err := enterSandbox(data)
+
+	errPipe := os.NewFile(sandboxErrorFD, "sandbox-error")
+	_, _ = fmt.Fprint(errPipe, err)
+	os.Exit(1)
+}
+
+// runSandboxed runs the prepared command inside the sandbox.
+//
+// Exit status of the command is reported the same way as by c.Run.
+func runSandboxed(c *exec.Cmd, outputDir string, readOnly, mounts []string) error {
+	if c.Err != nil {
+		return c.Err
+	}
+
+	root, err := os.MkdirTemp("", "distbuild-sandbox-")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(root) }()
+
+	spec, err := json.Marshal(sandboxSpec{
+		Root:     root,
+		ReadOnly: readOnly,
+		Writable: []string{outputDir},
+		Optional: mounts,
+		Dir:      c.Dir,
+		Path:     c.Path,
+		Args:     c.Args,
+		Env:      c.Env,
+	})
+	if err != nil {
+		return err
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	errR, errW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer errR.Close()
+
+	c.Path = self
+	c.Args = []string{"distbuild-sandbox"}
+	c.Env = []string{sandboxEnv + "=" + string(spec)}
+	c.Dir = ""
+	c.ExtraFiles = []*os.File{errW}
+
+	c.SysProcAttr.Cloneflags = syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+		syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	c.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}}
+	c.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}}
+	c.SysProcAttr.GidMappingsEnableSetgroups = false
+
+	err = c.Start()
+	_ = errW.Close()
+	if err != nil {
+		return fmt.Errorf("start sandbox: %w", err)
+	}
+
+	setupErr, _ := io.ReadAll(errR)
+	err = c.Wait()
+	if len(setupErr) != 0 {
+		return fmt.Errorf("sandbox: %s", setupErr)
+	}
+	return err
+}
+
+func enterSandbox(data string) error {
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(data), &spec); err != nil {
+		return err
+	}
+
+	syscall.CloseOnExec(sandboxErrorFD)
+
+	// Mounts below must not propagate to the host.
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+
+	if err := unix.Mount("tmpfs", spec.Root, "tmpfs", 0, "mode=0755"); err != nil {
+		return fmt.Errorf("mount root: %w", err)
+	}
+
+	tmp := filepath.Join(spec.Root, "tmp")
+	if err := os.Mkdir(tmp, 0777); err != nil {
+		return err
+	}
+	if err := unix.Mount("tmpfs", tmp, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777"); err != nil {
+		return fmt.Errorf("mount /tmp: %w", err)
+	}
+
+	type mount struct {
+		path     string
+		writable bool
+		optional bool
+	}
+
+	var mounts []mount
+	for _, path := range spec.Optional {
+		mounts = append(mounts, mount{path: path, optional: true})
+	}
+	for _, path := range spec.ReadOnly {
+		mounts = append(mounts, mount{path: path})
+	}
+	for _, path := range spec.Writable {
+		mounts = append(mounts, mount{path: path, writable: true})
+	}
+	for _, dev := range []string{"/dev/null", "/dev/zero", "/dev/full", "/dev/random", "/dev/urandom"} {
+		mounts = append(mounts, mount{path: dev, writable: true})
+	}
+
+	// Parent directories are mounted before their children.
+	sort.SliceStable(mounts, func(i, j int) bool {
+		return mounts[i].path < mounts[j].path
+	})
+
+	for _, m := range mounts {
+		err := bindMount(spec.Root, m.path, m.writable)
+		if m.optional && os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("mount %s: %w", m.path, err)
+		}
+	}
+
+	proc := filepath.Join(spec.Root, "proc")
+	if err := os.Mkdir(proc, 0555); err != nil {
+		return err
+	}
+	if err := unix.Mount("proc", proc, "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("mount /proc: %w", err)
+	}
+
+	if err := unix.Sethostname([]byte("sandbox")); err != nil {
+		return fmt.Errorf("set hostname: %w", err)
+	}
+
+	if err := unix.Chroot(spec.Root); err != nil {
+		return fmt.Errorf("chroot: %w", err)
+	}
+
+	dir := spec.Dir
+	if dir == "" {
+		dir = "/"
+	}
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+
+	if err := unix.Exec(spec.Path, spec.Args, spec.Env); err != nil {
+		return fmt.Errorf("exec %s: %w", spec.Path, err)
+	}
+	return nil
+}
+
+// bindMount makes host path visible at the same location inside the root.
+func bindMount(root, path string, writable bool) error {
+	st, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	// Symlinks like /bin -> usr/bin are recreated, their targets are mounted separately.
+	if st.Mode()&os.ModeSymlink != 0 {
+		link, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, target)
+	}
+
+	if st.IsDir() {
+		err = os.MkdirAll(target, 0755)
+	} else {
+		err = os.WriteFile(target, nil, 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := unix.Mount(path, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return err
+	}
+	if writable {
+		return nil
+	}
+
+	// Remount must keep the flags of the host mount, unprivileged user is not allowed to drop them.
+	var fs unix.Statfs_t
+	if err := unix.Statfs(target, &fs); err != nil {
+		return err
+	}
+
+	flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY)
+	for st, ms := range map[int64]uintptr{
+		unix.ST_NOSUID:     unix.MS_NOSUID,
+		unix.ST_NODEV:      unix.MS_NODEV,
+		unix.ST_NOEXEC:     unix.MS_NOEXEC,
+		unix.ST_NOATIME:    unix.MS_NOATIME,
+		unix.ST_NODIRATIME: unix.MS_NODIRATIME,
+		unix.ST_RELATIME:   unix.MS_RELATIME,
+	} {
+		if int64(fs.Flags)&st != 0 {
+			flags |= ms
+		}
+	}
+	return unix.Mount("", target, "", flags, "")
+}
@@ -0,0 +1,12 @@
+//go:build !solution && !linux
+
+package worker
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func runSandboxed(c *exec.Cmd, outputDir string, readOnly, mounts []string) error {
+	return errors.New("sandbox is supported only on linux")
+}
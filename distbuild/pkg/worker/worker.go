@@ -4,16 +4,114 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
 
 	"gitlab.com/slon/shad-go/distbuild/pkg/api"
 	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
 )
 
+const (
+	// defaultSlots is the number of jobs worker runs concurrently by default.
+	defaultSlots = 1
+
+	retryDelay = 100 * time.Millisecond
+
+	// busyHeartbeatInterval is the heartbeat period of the worker running jobs.
+	busyHeartbeatInterval = 200 * time.Millisecond
+
+	// outputFlushDelay is the time worker accumulates job output before sending it to the coordinator.
+	outputFlushDelay = 50 * time.Millisecond
+
+	// maxPendingOutput bounds the size of the job output waiting for the next heartbeat.
+	maxPendingOutput = 1 << 20
+)
+
+// Config configures worker.
+type Config struct {
+	// Slots is the number of jobs worker runs concurrently. Default is 1.
+	Slots int
+
+	// Resources is the capacity of the worker shared by the running jobs.
+	//
+	// Nil means that resources are not limited and only Slots bound the number of jobs.
+	Resources *build.Resources
+
+	// Labels are advertised to the coordinator. Worker runs only jobs, which labels are all present in Labels.
+	Labels []string
+
+	// Sandbox runs commands of the jobs in new user, mount, pid, network, ipc and uts namespaces.
+	// Only SandboxMounts, sources and dependencies of the job are visible to the command read-only,
+	// and the output directory read-write. Environ and Cmd.Environ become the entire environment of the command.
+	//
+	// Sandbox requires Linux with unprivileged user namespaces.
+	Sandbox bool
+
+	// SandboxMounts lists host paths with the tools used by the jobs. Default is DefaultSandboxMounts.
+	// Missing paths are skipped.
+	SandboxMounts []string
+
+	// Environ locates the tools on the worker, e.g. PATH and GOCACHE of the go command. It is prepended to
+	// Cmd.Environ of every command, so that the graph does not depend on the client machine. Entries without
+	// '=' name variables copied from the environment of the worker process. Cmd.Environ takes precedence.
+	Environ []string
+
+	// Auth is presented to the coordinator and to the other workers, and protects the artifact server
+	// of the worker. Coordinator with TLS enabled requires the worker certificate valid for the host of
+	// the worker id.
+	Auth *auth.Config
+}
+
+// DefaultEnviron lists variables of the worker process, that locate the go command and its caches.
+var DefaultEnviron = []string{"PATH", "HOME", "GOPATH", "GOCACHE", "GOMODCACHE", "GOPROXY", "GOFLAGS"}
+
+// DefaultSandboxMounts exposes system binaries and libraries inside the sandbox.
+var DefaultSandboxMounts = []string{"/bin", "/sbin", "/lib", "/lib32", "/lib64", "/usr"}
+
 type Worker struct {
+	id        api.WorkerID
+	l         *zap.Logger
+	config    Config
+	environ   []string
+	fileCache *filecache.Cache
+	artifacts *artifact.Cache
+
+	heartbeat      *api.HeartbeatClient
+	files          *filecache.Client
+	artifactClient *artifact.Client
+	httpClient     *http.Client
+	metrics        *workerMetrics
+	mux            *http.ServeMux
+	handler        http.Handler
+
+	mu       sync.Mutex
+	running  map[build.ID]context.CancelCauseFunc
+	used     build.Resources
+	finished []api.JobResult
+	added    []build.ID
+	removed  []build.ID
+	// jobDone is signalled, when a job or an artifact copy is finished.
+	jobDone chan struct{}
+	// slots marks slots occupied by the running jobs. Slot numbers are reported in the job traces.
+	slots []bool
+
+	output      []api.JobOutput
+	outputSize  int
+	outputReady chan struct{}
+
+	// drain is set, when the worker is taken out of service through its own drain endpoint.
+	drain *api.DrainRequest
+	// draining is set, when the coordinator stopped giving jobs to the worker.
+	draining bool
+	// fetching holds artifacts of the draining workers, that are being copied.
+	fetching map[build.ID]struct{}
 }
 
 func New(
@@ -23,13 +121,296 @@ func New(
 	fileCache *filecache.Cache,
 	artifacts *artifact.Cache,
 ) *Worker {
-	panic("implement me")
+	return NewWithConfig(workerID, coordinatorEndpoint, log, fileCache, artifacts, Config{})
+}
+
+// NewWithConfig creates worker with the given slots, resources and labels.
+func NewWithConfig(
+	workerID api.WorkerID,
+	coordinatorEndpoint string,
+	log *zap.Logger,
+	fileCache *filecache.Cache,
+	artifacts *artifact.Cache,
+	config Config,
+) *Worker {
+	if config.Slots <= 0 {
+		config.Slots = defaultSlots
+	}
+
+	httpClient := config.Auth.HTTPClient()
+	w := &Worker{
+		id:        workerID,
+		l:         log,
+		config:    config,
+		environ:   workerEnviron(config.Environ),
+		fileCache: fileCache,
+		artifacts: artifacts,
+
+		heartbeat:      api.NewHeartbeatClientWithHTTPClient(log, coordinatorEndpoint, httpClient),
+		files:          filecache.NewClientWithHTTPClient(log, coordinatorEndpoint, httpClient),
+		artifactClient: artifact.NewClient(httpClient),
+		httpClient:     httpClient,
+		mux:            http.NewServeMux(),
+
+		running:  make(map[build.ID]context.CancelCauseFunc),
+		jobDone:  make(chan struct{}, 1),
+		fetching: make(map[build.ID]struct{}),
+
+		outputReady: make(chan struct{}, 1),
+	}
+
+	w.metrics = newWorkerMetrics(w)
+
+	artifacts.SetEvictHandler(w.removeArtifact)
+	artifact.NewHandler(log, artifacts).Register(w.mux)
+	api.NewDrainHandler(log, w).Register(w.mux)
+	w.metrics.Register(w.mux)
+	w.handler = config.Auth.Handler(w.mux)
+	return w
 }
 
 func (w *Worker) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
-	panic("implement me")
+	w.handler.ServeHTTP(rw, r)
+}
+
+// nextHeartbeat collects state accumulated since the previous heartbeat.
+func (w *Worker) nextHeartbeat() *api.HeartbeatRequest {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	req := &api.HeartbeatRequest{
+		WorkerID:         w.id,
+		FreeSlots:        w.config.Slots - len(w.running),
+		Labels:           w.config.Labels,
+		JobOutput:        w.output,
+		FinishedJob:      w.finished,
+		AddedArtifacts:   w.added,
+		RemovedArtifacts: w.removed,
+	}
+
+	if w.config.Resources != nil {
+		free := w.config.Resources.Sub(w.used)
+		req.FreeResources = &free
+		req.Resources = w.config.Resources
+	}
+
+	// Draining worker keeps reporting the drain, until the coordinator unregisters it.
+	req.Drain = w.drain
+	if w.drain != nil || w.draining {
+		req.FreeSlots = 0
+	}
+
+	for id := range w.running {
+		req.RunningJobs = append(req.RunningJobs, id)
+	}
+
+	w.output = nil
+	w.outputSize = 0
+	w.finished = nil
+	w.added = nil
+	w.removed = nil
+	return req
 }
 
+// requeue returns reports from the failed heartbeat back to the state.
+func (w *Worker) requeue(req *api.HeartbeatRequest) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.output = append(req.JobOutput, w.output...)
+	for _, out := range req.JobOutput {
+		w.outputSize += len(out.Data)
+	}
+	w.finished = append(req.FinishedJob, w.finished...)
+	w.added = append(req.AddedArtifacts, w.added...)
+	w.removed = append(req.RemovedArtifacts, w.removed...)
+}
+
+func (w *Worker) addArtifact(id build.ID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.added = append(w.added, id)
+}
+
+func (w *Worker) removeArtifact(id build.ID) {
+	w.l.Debug("artifact evicted", zap.String("id", id.String()))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.removed = append(w.removed, id)
+}
+
+func (w *Worker) startJob(ctx context.Context, wg *sync.WaitGroup, spec api.JobSpec) {
+	w.mu.Lock()
+	if _, ok := w.running[spec.ID]; ok {
+		w.mu.Unlock()
+		w.l.Warn("job is already running", zap.String("job_id", spec.ID.String()))
+		return
+	}
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	w.running[spec.ID] = cancel
+	w.used = w.used.Add(spec.Resources)
+	slot := w.takeSlot()
+	w.mu.Unlock()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer cancel(nil)
+
+		runCtx := jobCtx
+		if spec.Timeout > 0 {
+			var stop context.CancelFunc
+			runCtx, stop = context.WithTimeoutCause(jobCtx, spec.Timeout, errJobTimeout)
+			defer stop()
+		}
+
+		start := time.Now()
+		res := w.runJob(runCtx, &spec, slot)
+		cancelled := errors.Is(context.Cause(jobCtx), errJobCancelled)
+
+		if res.Cached {
+			w.metrics.jobsCached.Inc()
+		} else if !cancelled {
+			w.metrics.jobDuration.ObserveSince(start)
+		}
+
+		w.mu.Lock()
+		delete(w.running, spec.ID)
+		w.used = w.used.Sub(spec.Resources)
+		w.slots[slot] = false
+		if !cancelled {
+			w.finished = append(w.finished, *res)
+		}
+		w.mu.Unlock()
+
+		if cancelled {
+			w.l.Info("job cancelled", zap.String("job_id", spec.ID.String()))
+		}
+
+		select {
+		case w.jobDone <- struct{}{}:
+		default:
+		}
+	}()
+}
+
+// takeSlot occupies the first free slot. Must be called under mu.
+func (w *Worker) takeSlot() int {
+	for i, used := range w.slots {
+		if !used {
+			w.slots[i] = true
+			return i
+		}
+	}
+
+	w.slots = append(w.slots, true)
+	return len(w.slots) - 1
+}
+
+// cancelJob stops the running job. Result of the cancelled job is not reported.
+func (w *Worker) cancelJob(id build.ID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if cancel, ok := w.running[id]; ok {
+		cancel(errJobCancelled)
+	}
+}
+
+// Run sends heartbeats to the coordinator and runs the jobs until ctx is cancelled.
+//
+// Run returns nil, when the coordinator unregisters the drained worker.
 func (w *Worker) Run(ctx context.Context) error {
-	panic("implement me")
+	// Connections opened with the worker credentials are not left to the stopped worker.
+	defer w.httpClient.CloseIdleConnections()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	if err := w.artifacts.Evict(); err != nil {
+		w.l.Warn("artifact eviction failed", zap.Error(err))
+	}
+
+	for {
+		req := w.nextHeartbeat()
+
+		start := time.Now()
+		rsp, err := w.heartbeat.Heartbeat(ctx, req)
+		w.metrics.heartbeatDuration.ObserveSince(start)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			w.l.Warn("heartbeat failed", zap.Error(err))
+			w.requeue(req)
+
+			select {
+			case <-time.After(retryDelay):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		for _, id := range rsp.JobsToCancel {
+			w.l.Debug("cancelling job", zap.String("job_id", id.String()))
+			w.cancelJob(id)
+		}
+
+		for _, id := range rsp.ArtifactsToRemove {
+			w.l.Debug("removing artifact", zap.String("id", id.String()))
+			if err := w.artifacts.Remove(id); err != nil && !errors.Is(err, artifact.ErrNotFound) {
+				w.l.Warn("artifact removal failed", zap.String("id", id.String()), zap.Error(err))
+			}
+		}
+
+		for id, source := range rsp.ArtifactsToFetch {
+			w.fetchArtifact(ctx, &wg, id, source)
+		}
+
+		if rsp.Drained {
+			w.l.Info("worker drained")
+			return nil
+		}
+
+		if rsp.Draining {
+			w.mu.Lock()
+			w.draining = true
+			w.mu.Unlock()
+		}
+
+		for _, spec := range rsp.JobsToRun {
+			w.l.Debug("starting job", zap.String("job_id", spec.ID.String()), zap.String("name", spec.Name))
+			w.startJob(ctx, &wg, spec)
+		}
+
+		w.mu.Lock()
+		fetching := len(w.fetching) != 0
+		w.mu.Unlock()
+
+		// Idle worker waits for new jobs inside the heartbeat. Worker with running jobs or artifact copies
+		// polls for them, so that the results are reported without delay.
+		if len(rsp.JobsToRun) != 0 || (req.FreeSlots > 0 && len(req.RunningJobs) == 0 && !fetching) {
+			continue
+		}
+
+		select {
+		case <-w.jobDone:
+		case <-time.After(busyHeartbeatInterval):
+		case <-w.outputReady:
+			// Let the job write more output, so it is sent in a single heartbeat.
+			select {
+			case <-time.After(outputFlushDelay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
@@ -18,6 +18,7 @@ import (
 
 const (
 	workerID0 api.WorkerID = "w0"
+	workerID1 api.WorkerID = "w1"
 )
 
 var (
@@ -34,6 +35,10 @@ type testScheduler struct {
 }
 
 func newTestScheduler(t *testing.T) *testScheduler {
+	return newTestSchedulerWithConfig(t, config)
+}
+
+func newTestSchedulerWithConfig(t *testing.T, config scheduler.Config) *testScheduler {
 	log := zaptest.NewLogger(t)
 
 	fakeClock := clockwork.NewFakeClock()
@@ -124,6 +129,43 @@ func TestScheduler_CacheLocalScheduling(t *testing.T) {
 	assert.Equal(t, pendingUncachedJob, secondPickedJob)
 }
 
+func TestScheduler_ArtifactRemovedFromCachedQueue(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	job := &api.JobSpec{Job: build.Job{ID: build.NewID()}}
+
+	s.RegisterWorker(workerID0)
+	s.OnJobComplete(workerID0, job.ID, &api.JobResult{})
+	s.ScheduleJob(job)
+	s.OnArtifactRemoved(workerID0, job.ID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Job is no longer cached on the worker, so it is not picked from the cached queue.
+	require.Nil(t, s.PickJob(ctx, workerID0))
+}
+
+func TestScheduler_RemoveArtifact(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	id := build.NewID()
+
+	s.RegisterWorker(workerID0)
+	s.RegisterWorker(workerID1)
+	s.OnArtifactAdded(workerID0, id)
+	s.OnArtifactAdded(workerID1, id)
+
+	s.RemoveArtifact(workerID1, id)
+	require.Equal(t, []api.WorkerID{workerID0}, s.LocateArtifacts(id))
+
+	require.Empty(t, s.TakeRemovedArtifacts(workerID0))
+	require.Equal(t, []build.ID{id}, s.TakeRemovedArtifacts(workerID1))
+	require.Empty(t, s.TakeRemovedArtifacts(workerID1))
+}
+
 func TestScheduler_DependencyLocalScheduling(t *testing.T) {
 	s := newTestScheduler(t)
 	defer s.stop(t)
@@ -148,3 +190,419 @@ func TestScheduler_DependencyLocalScheduling(t *testing.T) {
 	secondPickedJob := s.PickJob(context.Background(), workerID0)
 	require.Equal(t, pendingJob2, secondPickedJob)
 }
+
+// scheduleLocal schedules jobs depending on the artifact cached on workerID0, so that the jobs
+// are put into the local queue of the worker right away.
+func scheduleLocal(s *testScheduler, policies ...scheduler.Policy) []*scheduler.PendingJob {
+	dep := build.NewID()
+	s.RegisterWorker(workerID0)
+	s.OnJobComplete(workerID0, dep, &api.JobResult{})
+
+	var pending []*scheduler.PendingJob
+	for _, policy := range policies {
+		job := &api.JobSpec{Job: build.Job{ID: build.NewID(), Deps: []build.ID{dep}}}
+		pending = append(pending, s.ScheduleJobWithPolicy(job, policy))
+	}
+
+	s.BlockUntil(len(policies))
+	return pending
+}
+
+func TestScheduler_Priority(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	pending := scheduleLocal(s,
+		scheduler.Policy{User: "a"},
+		scheduler.Policy{User: "b", Priority: 10},
+		scheduler.Policy{User: "a", Priority: 5})
+
+	for _, i := range []int{1, 2, 0} {
+		require.Equal(t, pending[i], s.PickJob(context.Background(), workerID0))
+	}
+}
+
+func TestScheduler_FairShare(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	pending := scheduleLocal(s,
+		scheduler.Policy{User: "a"},
+		scheduler.Policy{User: "a"},
+		scheduler.Policy{User: "a"},
+		scheduler.Policy{User: "b"},
+		scheduler.Policy{User: "b"})
+
+	// Users take turns, the remaining job of the user a goes last.
+	for _, i := range []int{0, 3, 1, 4, 2} {
+		require.Equal(t, pending[i], s.PickJob(context.Background(), workerID0))
+	}
+}
+
+func TestScheduler_FairShareWeights(t *testing.T) {
+	log := zaptest.NewLogger(t)
+	fakeClock := clockwork.NewFakeClock()
+
+	weighted := config
+	weighted.Weights = map[string]float64{"a": 2}
+
+	s := &testScheduler{
+		FakeClock: fakeClock,
+		Scheduler: scheduler.NewScheduler(log, weighted, fakeClock.After),
+		reset:     make(chan struct{}),
+	}
+	defer s.stop(t)
+
+	pending := scheduleLocal(s,
+		scheduler.Policy{User: "b"},
+		scheduler.Policy{User: "b"},
+		scheduler.Policy{User: "a"},
+		scheduler.Policy{User: "a"},
+		scheduler.Policy{User: "a"},
+		scheduler.Policy{User: "a"})
+
+	// User a gets two jobs for each job of the user b.
+	for _, i := range []int{0, 2, 3, 1, 4, 5} {
+		require.Equal(t, pending[i], s.PickJob(context.Background(), workerID0))
+	}
+}
+
+func TestScheduler_CriticalPath(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	pending := scheduleLocal(s,
+		scheduler.Policy{CriticalPath: 1},
+		scheduler.Policy{CriticalPath: 3},
+		scheduler.Policy{CriticalPath: 2})
+
+	for _, i := range []int{1, 2, 0} {
+		require.Equal(t, pending[i], s.PickJob(context.Background(), workerID0))
+	}
+}
+
+func TestScheduler_QueuePositions(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	pending := scheduleLocal(s,
+		scheduler.Policy{Priority: 0},
+		scheduler.Policy{Priority: 10},
+		scheduler.Policy{Priority: 5})
+
+	ids := []build.ID{pending[0].Job.ID, pending[1].Job.ID, pending[2].Job.ID}
+	require.Equal(t, map[build.ID]int{ids[0]: 2, ids[1]: 0, ids[2]: 1}, s.QueuePositions(ids))
+
+	require.Equal(t, pending[1], s.PickJob(context.Background(), workerID0))
+	require.Equal(t, map[build.ID]int{ids[0]: 1, ids[2]: 0}, s.QueuePositions(ids))
+	require.Equal(t, scheduler.Stats{Queued: 2, Running: 1, Workers: 1}, s.Stats())
+}
+
+func TestScheduler_AvoidWorker(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	s.RegisterWorker(workerID1)
+	s.RegisterWorker(workerID0)
+	require.Equal(t, []api.WorkerID{workerID0, workerID1}, s.Workers())
+
+	job := &api.JobSpec{Job: build.Job{ID: build.NewID()}}
+	pending := s.ScheduleJobWithPolicy(job, scheduler.Policy{AvoidWorker: workerID0})
+
+	s.BlockUntil(1)
+	s.Advance(config.DepsTimeout) // At this point job must be in global queue.
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.Nil(t, s.PickJob(ctx, workerID0))
+
+	require.Equal(t, pending, s.PickJob(context.Background(), workerID1))
+}
+
+func TestScheduler_UnregisterWorker(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	shared := build.NewID()
+	unique := build.NewID()
+
+	s.RegisterWorker(workerID0)
+	s.RegisterWorker(workerID1)
+	s.OnJobComplete(workerID0, shared, &api.JobResult{})
+	s.OnJobComplete(workerID1, shared, &api.JobResult{})
+	s.OnJobComplete(workerID0, unique, &api.JobResult{})
+
+	require.Equal(t, []build.ID{unique}, s.UniqueArtifacts(workerID0))
+	require.Empty(t, s.UniqueArtifacts(workerID1))
+
+	require.ElementsMatch(t, []build.ID{shared, unique}, s.UnregisterWorker(workerID0))
+	require.Equal(t, []api.WorkerID{workerID1}, s.Workers())
+
+	workerID, ok := s.LocateArtifact(shared)
+	require.True(t, ok)
+	require.Equal(t, workerID1, workerID)
+
+	_, ok = s.LocateArtifact(unique)
+	require.False(t, ok)
+}
+
+// runStraggler schedules two jobs with the same name on workerID0. The first job takes a second,
+// the second job is running longer than expected, when runStraggler returns.
+func runStraggler(t *testing.T, s *testScheduler) *scheduler.PendingJob {
+	s.RegisterWorker(workerID0)
+	s.RegisterWorker(workerID1)
+
+	started := time.Now()
+	trace := &api.JobTrace{Started: started, Executed: started.Add(time.Second)}
+
+	for i := 0; i < 2; i++ {
+		job := &api.JobSpec{Job: build.Job{ID: build.NewID(), Name: "compile"}}
+		pending := s.ScheduleJob(job)
+
+		s.BlockUntil(1)
+		s.Advance(config.DepsTimeout) // At this point job must be in global queue.
+
+		require.Equal(t, pending, s.PickJob(context.Background(), workerID0))
+		if i == 1 {
+			s.BlockUntil(1)
+			s.Advance(2 * time.Second)
+			return pending
+		}
+
+		s.OnJobComplete(workerID0, job.ID, &api.JobResult{ID: job.ID, Trace: trace})
+	}
+	return nil
+}
+
+func TestScheduler_SpeculativeExecution(t *testing.T) {
+	s := newTestSchedulerWithConfig(t, scheduler.Config{
+		CacheTimeout:      config.CacheTimeout,
+		DepsTimeout:       config.DepsTimeout,
+		SpeculationFactor: 2,
+	})
+	defer s.stop(t)
+
+	straggler := runStraggler(t, s)
+	id := straggler.Job.ID
+
+	// Copy is not given to the worker running the job.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.Nil(t, s.PickJob(ctx, workerID0))
+
+	require.Equal(t, straggler, s.PickJob(context.Background(), workerID1))
+
+	result := &api.JobResult{ID: id}
+	require.False(t, s.Superseded(workerID1, result))
+	require.True(t, s.OnJobComplete(workerID1, id, result))
+	require.Equal(t, result, straggler.Result)
+
+	// Losing copy is cancelled, its result is dropped until the worker learns about the cancellation.
+	require.Equal(t, []build.ID{id}, s.TakeCancelledJobs(workerID0))
+	require.True(t, s.Superseded(workerID0, &api.JobResult{ID: id}))
+	require.False(t, s.Superseded(workerID0, &api.JobResult{ID: id}))
+}
+
+func TestScheduler_SpeculativeCopyFailure(t *testing.T) {
+	s := newTestSchedulerWithConfig(t, scheduler.Config{
+		CacheTimeout:      config.CacheTimeout,
+		DepsTimeout:       config.DepsTimeout,
+		SpeculationFactor: 2,
+	})
+	defer s.stop(t)
+
+	straggler := runStraggler(t, s)
+	id := straggler.Job.ID
+
+	require.Equal(t, straggler, s.PickJob(context.Background(), workerID1))
+
+	// Failed copy is dropped, while the job is still running on the other worker.
+	errorMsg := "worker failure"
+	require.True(t, s.Superseded(workerID1, &api.JobResult{ID: id, Error: &errorMsg}))
+
+	result := &api.JobResult{ID: id}
+	require.False(t, s.Superseded(workerID0, result))
+	require.True(t, s.OnJobComplete(workerID0, id, result))
+	require.Empty(t, s.TakeCancelledJobs(workerID1))
+}
+
+func TestScheduler_RequeueLost(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	s.RegisterWorker(workerID0)
+	s.RegisterWorker(workerID1)
+
+	running := &api.JobSpec{Job: build.Job{ID: build.NewID()}}
+	lost := &api.JobSpec{Job: build.Job{ID: build.NewID()}}
+	pendingRunning := s.ScheduleJob(running)
+	pendingLost := s.ScheduleJob(lost)
+
+	s.BlockUntil(2)
+	s.Advance(config.DepsTimeout) // At this point jobs must be in global queue.
+
+	require.ElementsMatch(t,
+		[]*scheduler.PendingJob{pendingRunning, pendingLost},
+		[]*scheduler.PendingJob{s.PickJob(context.Background(), workerID0), s.PickJob(context.Background(), workerID0)})
+
+	// Heartbeat response with the jobs was lost, worker runs only one of them.
+	require.Equal(t, []build.ID{lost.ID}, s.RequeueLost(workerID0, []build.ID{running.ID}))
+	require.Equal(t, scheduler.Stats{Queued: 1, Running: 1, Workers: 2}, s.Stats())
+
+	// Other workers get the lost job first.
+	require.Equal(t, pendingLost, s.PickJob(context.Background(), workerID1))
+	require.Equal(t, 2, pendingLost.Job.Attempt)
+
+	// Failure of the lost attempt, resent by the worker, is outdated.
+	errorMsg := "worker restarted"
+	require.True(t, s.Superseded(workerID0, &api.JobResult{ID: lost.ID, Error: &errorMsg, Attempts: 1}))
+	require.False(t, s.Superseded(workerID1, &api.JobResult{ID: lost.ID, Error: &errorMsg, Attempts: 2}))
+}
+
+func TestScheduler_RetryDropsStaleEntries(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	dep := build.NewID()
+	job := &api.JobSpec{Job: build.Job{ID: build.NewID(), Deps: []build.ID{dep}, Retries: 1}}
+
+	s.RegisterWorker(workerID0)
+	s.RegisterWorker(workerID1)
+	s.OnJobComplete(workerID0, dep, &api.JobResult{})
+	s.OnJobComplete(workerID0, job.ID, &api.JobResult{})
+	pending := s.ScheduleJob(job)
+
+	s.BlockUntil(1)
+	s.Advance(config.CacheTimeout)
+	s.BlockUntil(1) // At this point job is both in the cached and in the deps queue of workerID0.
+
+	require.Equal(t, pending, s.PickJob(context.Background(), workerID0))
+
+	errorMsg := "worker failure"
+	require.True(t, s.RetryJob(workerID0, &api.JobResult{ID: job.ID, Error: &errorMsg}))
+
+	// Entry left in the other queue does not give the job back to the failed worker.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.Nil(t, s.PickJob(ctx, workerID0))
+
+	require.Equal(t, pending, s.PickJob(context.Background(), workerID1))
+}
+
+func TestScheduler_RetryStopsPreviousPromotion(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	dep := build.NewID()
+	job := &api.JobSpec{Job: build.Job{ID: build.NewID(), Deps: []build.ID{dep}, Retries: 1}}
+
+	s.RegisterWorker(workerID0)
+	s.OnJobComplete(workerID0, dep, &api.JobResult{})
+	pending := s.ScheduleJob(job)
+
+	s.BlockUntil(1)
+	require.Equal(t, pending, s.PickJob(context.Background(), workerID0))
+
+	s.Advance(config.DepsTimeout / 2)
+
+	errorMsg := "worker failure"
+	require.True(t, s.RetryJob(workerID0, &api.JobResult{ID: job.ID, Error: &errorMsg}))
+
+	// Promotion of the first attempt expires and must not put the retry into global queue.
+	s.BlockUntil(2)
+	s.Advance(config.DepsTimeout / 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.Nil(t, s.PickJob(ctx, workerID0))
+
+	s.Advance(config.DepsTimeout / 2)
+	require.Equal(t, pending, s.PickJob(context.Background(), workerID0))
+	require.Equal(t, 2, pending.Job.Attempt)
+}
+
+// scheduleResources schedules jobs requiring the given CPU, so that the jobs are put into the local queue
+// of workerID0 in order.
+func scheduleResources(s *testScheduler, milliCPU ...int64) []*scheduler.PendingJob {
+	dep := build.NewID()
+	s.RegisterWorker(workerID0)
+	s.OnJobComplete(workerID0, dep, &api.JobResult{})
+
+	var pending []*scheduler.PendingJob
+	for _, cpu := range milliCPU {
+		job := &api.JobSpec{Job: build.Job{
+			ID:        build.NewID(),
+			Deps:      []build.ID{dep},
+			Resources: build.Resources{MilliCPU: cpu},
+		}}
+		pending = append(pending, s.ScheduleJob(job))
+	}
+
+	s.BlockUntil(len(milliCPU))
+	return pending
+}
+
+func TestScheduler_BinPacking(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	capacity := &build.Resources{MilliCPU: 2000}
+	pending := scheduleResources(s, 1500, 1000, 500)
+	s.UpdateWorker(workerID0, capacity, capacity, nil)
+
+	// The second job does not fit into the resources left by the first one, the third one does.
+	require.Equal(t, pending[0], s.PickJob(context.Background(), workerID0))
+	require.Equal(t, pending[2], s.PickJob(context.Background(), workerID0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.Nil(t, s.PickJob(ctx, workerID0))
+
+	// Resources are released with the next heartbeat.
+	s.UpdateWorker(workerID0, capacity, capacity, nil)
+	require.Equal(t, pending[1], s.PickJob(context.Background(), workerID0))
+}
+
+func TestScheduler_Unsatisfiable(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	capacity := &build.Resources{MilliCPU: 1000}
+	pending := scheduleResources(s, 500, 800, 2000)
+	s.UpdateWorker(workerID0, capacity, capacity, []string{"linux"})
+
+	labeled := &api.JobSpec{Job: build.Job{ID: build.NewID(), Labels: []string{"gpu"}}}
+	s.ScheduleJob(labeled)
+
+	ids := []build.ID{pending[0].Job.ID, pending[1].Job.ID, pending[2].Job.ID, labeled.ID}
+	require.Equal(t, []build.ID{pending[2].Job.ID, labeled.ID}, s.Unsatisfiable(ids))
+
+	// Job, that does not fit only into the free resources, waits for the running job.
+	require.Equal(t, pending[0], s.PickJob(context.Background(), workerID0))
+	s.UpdateWorker(workerID0, capacity, &build.Resources{MilliCPU: 500}, []string{"linux"})
+	require.Equal(t, []build.ID{pending[2].Job.ID, labeled.ID}, s.Unsatisfiable(ids))
+
+	s.RegisterWorker(workerID1)
+	s.UpdateWorker(workerID1, nil, nil, []string{"gpu"})
+	require.Empty(t, s.Unsatisfiable(ids))
+}
+
+func TestScheduler_PriorityAcrossQueues(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	dep := build.NewID()
+	cached := &api.JobSpec{Job: build.Job{ID: build.NewID()}}
+	urgent := &api.JobSpec{Job: build.Job{ID: build.NewID(), Deps: []build.ID{dep}}}
+
+	s.RegisterWorker(workerID0)
+	s.OnJobComplete(workerID0, dep, &api.JobResult{})
+	s.OnJobComplete(workerID0, cached.ID, &api.JobResult{})
+
+	pendingCached := s.ScheduleJob(cached)
+	pendingUrgent := s.ScheduleJobWithPolicy(urgent, scheduler.Policy{Priority: 10})
+	s.BlockUntil(2) // cached job is in the cached queue, urgent job is in the deps queue.
+
+	require.Equal(t, pendingUrgent, s.PickJob(context.Background(), workerID0))
+	require.Equal(t, pendingCached, s.PickJob(context.Background(), workerID0))
+}